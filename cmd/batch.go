@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// maxBatchLine is the maximum size of a line of the batch file.
+const maxBatchLine = 1024 * 1024
+
+type batchOptions struct {
+	File    string
+	OnError string
+}
+
+// NewCmdBatch creates a batch command.
+func NewCmdBatch(f *pixelaClientFactory) *cobra.Command {
+	o := &batchOptions{}
+	cmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Run pa operations from a file",
+		Long: "Run the pa operations listed in the file, or stdin, in a single process with the config loaded once.\n" +
+			"A line is an operation written as the arguments of pa, such as\n\n" +
+			"  pixel create --graph-id a --date 20261001 --quantity 3\n\n" +
+			"or NDJSON of the arguments, such as '[\"pixel\", \"increment\", \"--graph-id\", \"a\"]' or\n" +
+			"'{\"args\": [\"pixel\", \"increment\", \"--graph-id\", \"a\"]}'. Empty lines and lines starting with '#' are skipped.\n" +
+			"The global flags given to pa batch apply to every operation, and --concurrency operations run at the same time.\n\n" +
+			"The result of each operation is printed as a line of JSON in the order of the lines.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.OnError != "stop" && o.OnError != "continue" {
+				return fmt.Errorf("on-error must be stop or continue: %s", o.OnError)
+			}
+
+			in := cmd.InOrStdin()
+			if o.File != "" && o.File != "-" {
+				file, err := os.Open(o.File)
+				if err != nil {
+					return fmt.Errorf("open batch file failed: %w", err)
+				}
+				defer func() { _ = file.Close() }()
+				in = file
+			}
+			return runBatch(cmd, f, o, in)
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.File, "file", "f", "", "File of the operations, '-' or empty for stdin")
+	cmd.Flags().StringVar(&o.OnError, "on-error", "stop", "What to do when an operation fails: stop or continue")
+
+	return cmd
+}
+
+// batchOperation is an operation read from a line of the batch file.
+type batchOperation struct {
+	// seq は操作の読んだ順番で、結果を行の順に出力するのに使う
+	seq  int
+	line int
+	args []string
+	// err はその行を読めなかったときのエラー
+	err error
+}
+
+// batchResult is the result of an operation printed as a line of JSON.
+type batchResult struct {
+	Line int      `json:"line"`
+	Args []string `json:"args"`
+	OK   bool     `json:"ok"`
+	// Output is the output of the command, embedded as it is when it is JSON
+	Output json.RawMessage `json:"output,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// runBatch runs the operations read from in on the workers as many as --concurrency, and prints their results
+// in the order of the lines. With --on-error=stop, the operations after the line which fails don't start,
+// and the running ones finish.
+func runBatch(cmd *cobra.Command, f *pixelaClientFactory, o *batchOptions, in io.Reader) error {
+	ctx := f.Context()
+	// dispatch は新しい操作を読むかどうかで、実行中の操作はキャンセルしない
+	dispatch, stop := context.WithCancel(ctx)
+	defer stop()
+	globals := globalArgs(cmd)
+
+	operations := make(chan batchOperation)
+	var readErr error
+	go func() {
+		defer close(operations)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), maxBatchLine)
+		seq := 0
+		for n := 1; scanner.Scan(); n++ {
+			args, err := parseBatchLine(scanner.Text())
+			if args == nil && err == nil {
+				continue
+			}
+			select {
+			case operations <- batchOperation{seq: seq, line: n, args: args, err: err}:
+				seq++
+			case <-dispatch.Done():
+				return
+			}
+		}
+		readErr = scanner.Err()
+	}()
+
+	// 始めなかった操作は結果を nil で送り、出力を待たないようにする
+	type sequenced struct {
+		seq    int
+		result *batchResult
+	}
+	results := make(chan sequenced)
+	// failedSeq は失敗した最初の操作で、それより前の行の操作は後の行が先に失敗しても実行する
+	var mu sync.Mutex
+	failedSeq := -1
+	skip := func(op batchOperation) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return ctx.Err() != nil || failedSeq >= 0 && op.seq > failedSeq
+	}
+	fail := func(op batchOperation) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failedSeq < 0 || op.seq < failedSeq {
+			failedSeq = op.seq
+		}
+		stop()
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < f.Concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for op := range operations {
+				if skip(op) {
+					results <- sequenced{seq: op.seq}
+					continue
+				}
+				r := runBatchOperation(ctx, f, globals, op)
+				if !r.OK && o.OnError == "stop" {
+					fail(op)
+				}
+				results <- sequenced{seq: op.seq, result: r}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// 並行に実行しても先に終わった結果は前の行の結果を待って行の順に出力する
+	total, failed, stoppedAt := 0, 0, 0
+	pending := map[int]*batchResult{}
+	next := 0
+	var marshalErr error
+	for s := range results {
+		pending[s.seq] = s.result
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if r == nil || marshalErr != nil {
+				continue
+			}
+			total++
+			if !r.OK {
+				failed++
+				if o.OnError == "stop" && stoppedAt == 0 {
+					stoppedAt = r.Line
+				}
+			}
+			b, err := json.Marshal(r)
+			if err != nil {
+				marshalErr = fmt.Errorf("marshal batch result failed: %w", err)
+				continue
+			}
+			cmd.Printf("%s\n", b)
+		}
+	}
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	switch {
+	case readErr != nil:
+		return fmt.Errorf("read batch failed: %w", readErr)
+	case ctx.Err() != nil:
+		return fmt.Errorf("batch cancelled: %w", ctx.Err())
+	case stoppedAt != 0:
+		return fmt.Errorf("batch stopped: the operation at line %d failed", stoppedAt)
+	case failed > 0:
+		return fmt.Errorf("batch failed: %d of %d operations failed", failed, total)
+	}
+	return nil
+}
+
+// parseBatchLine returns the arguments of the operation on the line, or nil for an empty line and a comment.
+func parseBatchLine(line string) ([]string, error) {
+	line = strings.TrimSpace(line)
+	var args []string
+	switch {
+	case line == "" || strings.HasPrefix(line, "#"):
+		return nil, nil
+	case strings.HasPrefix(line, "["):
+		if err := json.Unmarshal([]byte(line), &args); err != nil {
+			return nil, fmt.Errorf("invalid operation: %w", err)
+		}
+	case strings.HasPrefix(line, "{"):
+		var op struct {
+			Args []string `json:"args"`
+		}
+		if err := json.Unmarshal([]byte(line), &op); err != nil {
+			return nil, fmt.Errorf("invalid operation: %w", err)
+		}
+		args = op.Args
+	default:
+		var err error
+		if args, err = splitShellLine(line); err != nil {
+			return nil, fmt.Errorf("invalid operation: %w", err)
+		}
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("invalid operation: no arguments")
+	}
+	return args, nil
+}
+
+// runBatchOperation runs the operation on a new root command which shares the configuration with f.
+func runBatchOperation(ctx context.Context, f *pixelaClientFactory, globals []string, op batchOperation) *batchResult {
+	r := &batchResult{Line: op.line, Args: op.args}
+	if op.err != nil {
+		r.Error = op.err.Error()
+		return r
+	}
+
+	child := f.child()
+	defer child.release()
+	root := newCmdRoot(child)
+	if c, _, err := root.Find(op.args); err == nil {
+		switch c.Name() {
+		case "batch", "shell", "tui":
+			r.Error = fmt.Sprintf("%s can't be run in batch", c.Name())
+			return r
+		}
+	}
+	out := bytes.NewBuffer([]byte{})
+	root.SetIn(strings.NewReader(""))
+	root.SetOut(out)
+	root.SetErr(io.Discard)
+	root.SetArgs(append(append([]string{}, globals...), op.args...))
+
+	err := root.ExecuteContext(ctx)
+	r.Output = batchOutput(out.Bytes())
+	switch {
+	case err == nil:
+		r.OK = true
+	case errors.Is(err, ErrNeglect) && out.Len() > 0:
+		r.Error = resultMessage(out.String())
+	default:
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// batchOutput returns the output as JSON, which is a string unless the output is JSON.
+func batchOutput(b []byte) json.RawMessage {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil
+	}
+	compact := bytes.NewBuffer([]byte{})
+	if json.Compact(compact, b) == nil {
+		return compact.Bytes()
+	}
+	s, _ := json.Marshal(string(b))
+	return s
+}
@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func executeBatch(f *pixelaClientFactory, input string, args ...string) (string, error) {
+	cmd := newCmdRoot(f)
+	buffer := bytes.NewBuffer([]byte{})
+	cmd.SetIn(strings.NewReader(input))
+	cmd.SetOut(buffer)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(append([]string{"batch"}, args...))
+
+	err := cmd.Execute()
+	return buffer.String(), err
+}
+
+func TestBatch(t *testing.T) {
+	fake := newPixelaFake()
+	fake.addGraph(pixela.GraphDefinition{ID: "graph-id"})
+	f := fake.factory()
+
+	out, err := executeBatch(f, strings.Join([]string{
+		"# nightly sync",
+		"pixel create --graph-id graph-id --date 20261001 --quantity 3",
+		"",
+		`["pixel", "increment", "--graph-id", "graph-id"]`,
+		`{"args": ["pixel", "get", "--graph-id", "graph-id", "--date", "20261001"]}`,
+	}, "\n"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		`{"line":2,"args":["pixel","create","--graph-id","graph-id","--date","20261001","--quantity","3"],"ok":true,"output":{"message":"Success.","isSuccess":true,"isRejected":false,"statusCode":200}}`,
+		`{"line":4,"args":["pixel","increment","--graph-id","graph-id"],"ok":true,"output":{"message":"Success.","isSuccess":true,"isRejected":false,"statusCode":200}}`,
+		`{"line":5,"args":["pixel","get","--graph-id","graph-id","--date","20261001"],"ok":true,"output":{"quantity":"3","optionalData":""}}`,
+	}, "\n")+"\n", out)
+	assert.Equal(t, []string{"graph-id:increment"}, fake.added)
+}
+
+func TestBatchOnError(t *testing.T) {
+	input := strings.Join([]string{
+		"pixel create --graph-id graph-id --date 20261001 --quantity 3",
+		"pixel create --graph-id unknown --date 20261001 --quantity 3",
+		"shell",
+		"pixel create --graph-id graph-id --date 20261002 --quantity 3",
+	}, "\n")
+
+	tests := []struct {
+		name    string
+		args    []string
+		lines   int
+		errors  []string
+		wantErr string
+		pixels  int
+	}{
+		{
+			name:    "stop",
+			lines:   2,
+			errors:  []string{"Specified graphID not exist."},
+			wantErr: "batch stopped: the operation at line 2 failed",
+			pixels:  1,
+		},
+		{
+			name:    "continue",
+			args:    []string{"--on-error=continue"},
+			lines:   4,
+			errors:  []string{"Specified graphID not exist.", "shell can't be run in batch"},
+			wantErr: "batch failed: 2 of 4 operations failed",
+			pixels:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newPixelaFake()
+			fake.addGraph(pixela.GraphDefinition{ID: "graph-id"})
+
+			out, err := executeBatch(fake.factory(), input, tt.args...)
+
+			assert.EqualError(t, err, tt.wantErr)
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			assert.Len(t, lines, tt.lines)
+			var failures []string
+			for _, line := range lines {
+				var r batchResult
+				assert.NoError(t, json.Unmarshal([]byte(line), &r))
+				if !r.OK {
+					failures = append(failures, r.Error)
+				}
+			}
+			assert.Equal(t, tt.errors, failures)
+			assert.Len(t, fake.pixels["graph-id"], tt.pixels)
+		})
+	}
+}
+
+func TestBatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ops.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("pixel increment --graph-id graph-id\n"), 0600))
+	fake := newPixelaFake()
+
+	_, err := executeBatch(fake.factory(), "", "-f", path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"graph-id:increment"}, fake.added)
+
+	_, err = executeBatch(fake.factory(), "", "--concurrency=0")
+	assert.EqualError(t, err, "concurrency must be positive: 0")
+	_, err = executeBatch(fake.factory(), "", "--on-error=retry")
+	assert.EqualError(t, err, "on-error must be stop or continue: retry")
+}
+
+// syncPixel serializes the calls of the fake for the concurrent operations.
+type syncPixel struct {
+	pixelaPixel
+	mu sync.Mutex
+}
+
+func (p *syncPixel) IncrementWithContext(ctx context.Context, input *pixela.PixelIncrementInput) (*pixela.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pixelaPixel.IncrementWithContext(ctx, input)
+}
+
+func (p *syncPixel) UpdateWithContext(ctx context.Context, input *pixela.PixelUpdateInput) (*pixela.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pixelaPixel.UpdateWithContext(ctx, input)
+}
+
+func TestBatchConcurrency(t *testing.T) {
+	fake := newPixelaFake()
+	f := fake.factory()
+	f.pixel = &syncPixel{pixelaPixel: f.pixel}
+	input := strings.Repeat("pixel increment --graph-id graph-id\n", 20)
+
+	out, err := executeBatch(f, input, "--concurrency=4")
+
+	assert.NoError(t, err)
+	assert.Len(t, fake.added, 20)
+	assert.Equal(t, 20, strings.Count(out, `"ok":true`))
+}
+
+// rejectingPixel rejects the first increment of each graph.
+type rejectingPixel struct {
+	syncPixel
+	mu       sync.Mutex
+	rejected map[string]bool
+}
+
+func (p *rejectingPixel) IncrementWithContext(ctx context.Context, input *pixela.PixelIncrementInput) (*pixela.Result, error) {
+	p.mu.Lock()
+	rejected := p.rejected[*input.GraphID]
+	p.rejected[*input.GraphID] = true
+	p.mu.Unlock()
+	if !rejected {
+		return nil, pixela.ErrAPICallRejected
+	}
+	return p.syncPixel.IncrementWithContext(ctx, input)
+}
+
+func TestBatchRetry(t *testing.T) {
+	fake := newPixelaFake()
+	f := fake.factory()
+	f.pixel = &rejectingPixel{syncPixel: syncPixel{pixelaPixel: f.pixel}, rejected: map[string]bool{}}
+	// 行ごとの --retry は、並行して実行される他の行のリトライ回数を変えない
+	input := strings.Join([]string{
+		"pixel increment --graph-id retried --retry=1",
+		"pixel increment --graph-id rejected",
+		"pixel increment --graph-id retried-again --retry=2",
+	}, "\n")
+
+	out, err := executeBatch(f, input, "--concurrency=3", "--on-error=continue")
+
+	assert.EqualError(t, err, "batch failed: 1 of 3 operations failed")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"ok":true`)
+	assert.Contains(t, lines[1], `"ok":false`)
+	assert.Contains(t, lines[2], `"ok":true`)
+	assert.ElementsMatch(t, []string{"retried:increment", "retried-again:increment"}, fake.added)
+}
+
+// slowPixel fails the increments of the unknown graphs, and finishes the ones of the slow graphs
+// after the unknown-fast graph fails, so that the later lines finish first.
+type slowPixel struct {
+	syncPixel
+	fastFailed chan struct{}
+}
+
+func (p *slowPixel) IncrementWithContext(ctx context.Context, input *pixela.PixelIncrementInput) (*pixela.Result, error) {
+	id := *input.GraphID
+	if strings.HasSuffix(id, "slow") {
+		<-p.fastFailed
+	}
+	if strings.HasPrefix(id, "unknown") {
+		if id == "unknown-fast" {
+			defer close(p.fastFailed)
+		}
+		return notFoundResult("Specified graphID not exist."), nil
+	}
+	return p.syncPixel.IncrementWithContext(ctx, input)
+}
+
+func TestBatchOrder(t *testing.T) {
+	input := strings.Join([]string{
+		"pixel increment --graph-id slow",
+		"pixel increment --graph-id unknown-slow",
+		"pixel increment --graph-id unknown-fast",
+		"pixel increment --graph-id fast",
+	}, "\n")
+
+	tests := []struct {
+		name    string
+		args    []string
+		lines   []int
+		wantErr string
+	}{
+		{
+			// 行 4 は行 3 が失敗した後なので始まらないことがある
+			name:    "stop",
+			args:    []string{"--concurrency=4"},
+			lines:   []int{1, 2, 3},
+			wantErr: "batch stopped: the operation at line 2 failed",
+		},
+		{
+			name:    "continue",
+			args:    []string{"--concurrency=4", "--on-error=continue"},
+			lines:   []int{1, 2, 3, 4},
+			wantErr: "batch failed: 2 of 4 operations failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newPixelaFake()
+			fake.addGraph(pixela.GraphDefinition{ID: "slow"})
+			fake.addGraph(pixela.GraphDefinition{ID: "fast"})
+			f := fake.factory()
+			f.pixel = &slowPixel{syncPixel: syncPixel{pixelaPixel: f.pixel}, fastFailed: make(chan struct{})}
+
+			out, err := executeBatch(f, input, tt.args...)
+
+			// 前の行ほど遅く終わっても、結果は行の順に出力して最初に失敗した行で止まる
+			assert.EqualError(t, err, tt.wantErr)
+			var lines []int
+			for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+				var r batchResult
+				assert.NoError(t, json.Unmarshal([]byte(line), &r))
+				lines = append(lines, r.Line)
+			}
+			assert.True(t, sort.IntsAreSorted(lines), lines)
+			if assert.True(t, len(lines) >= len(tt.lines), lines) {
+				assert.Equal(t, tt.lines, lines[:len(tt.lines)])
+			}
+		})
+	}
+}
+
+func TestParseBatchLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr string
+	}{
+		{line: "  ", want: nil},
+		{line: "# comment", want: nil},
+		{line: `pixel update --graph-id a --optional-data '{"b": 1}'`, want: []string{"pixel", "update", "--graph-id", "a", "--optional-data", `{"b": 1}`}},
+		{line: `["graph", "list"]`, want: []string{"graph", "list"}},
+		{line: `{"args": ["graph", "list"]}`, want: []string{"graph", "list"}},
+		{line: `[]`, wantErr: "invalid operation: no arguments"},
+		{line: `{"command": "graph list"}`, wantErr: "invalid operation: no arguments"},
+		{line: `["graph", "list"`, wantErr: "invalid operation: unexpected end of JSON input"},
+		{line: `graph list 'open`, wantErr: "invalid operation: unterminated quote or escape"},
+	}
+	for _, tt := range tests {
+		got, err := parseBatchLine(tt.line)
+		if tt.wantErr != "" {
+			assert.EqualError(t, err, tt.wantErr, tt.line)
+			continue
+		}
+		assert.NoError(t, err, tt.line)
+		assert.Equal(t, tt.want, got, tt.line)
+	}
+}
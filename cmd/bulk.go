@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebc-2in2crc/pa/pkg/pa"
+)
+
+// ratePeriods are the units of --rate.
+var ratePeriods = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// initBulk sets up the pool of the bulk operations by --concurrency and the rate limit of the requests by --rate.
+// The commands run by pa itself, such as the lines of the shell and the batch, use the ones of the parent,
+// so the limits apply to all of them together.
+func initBulk(f *pixelaClientFactory) error {
+	concurrency := f.Concurrency()
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be positive: %d", concurrency)
+	}
+	n, per, err := parseRate(f.Rate())
+	if err != nil {
+		return err
+	}
+	// 子のコマンドは親のプールとリミッターを共有している
+	if f.configInherited {
+		return nil
+	}
+
+	f.pool = nil
+	if concurrency > 1 {
+		f.pool = pa.NewPool(concurrency)
+	}
+
+	// リミッターはファクトリーが作る API に渡すので、プロセス全体の設定は変えない
+	f.limiter = nil
+	if n > 0 {
+		f.limiter = pa.NewLimiter(n, per)
+	}
+	return nil
+}
+
+// parseRate parses the rate such as 5/s, 100/m and 1000/h, or a number of the requests per second.
+// It returns 0 requests for the empty rate, which means no limit.
+func parseRate(rate string) (int, time.Duration, error) {
+	if rate == "" {
+		return 0, 0, nil
+	}
+	count, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		unit = "s"
+	}
+	per, ok := ratePeriods[unit]
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n < 1 {
+		return 0, 0, fmt.Errorf("rate must be a positive number per s, m or h such as 5/s: %s", rate)
+	}
+	return n, per, nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate    string
+		n       int
+		per     time.Duration
+		wantErr bool
+	}{
+		{rate: "", n: 0, per: 0},
+		{rate: "5/s", n: 5, per: time.Second},
+		{rate: "100/m", n: 100, per: time.Minute},
+		{rate: "1000/h", n: 1000, per: time.Hour},
+		{rate: "3", n: 3, per: time.Second},
+		{rate: "0/s", wantErr: true},
+		{rate: "5/d", wantErr: true},
+		{rate: "fast", wantErr: true},
+	}
+	for _, tt := range tests {
+		n, per, err := parseRate(tt.rate)
+		if tt.wantErr {
+			assert.EqualError(t, err, "rate must be a positive number per s, m or h such as 5/s: "+tt.rate)
+			continue
+		}
+		assert.NoError(t, err, tt.rate)
+		assert.Equal(t, tt.n, n, tt.rate)
+		assert.Equal(t, tt.per, per, tt.rate)
+	}
+}
+
+func TestInitBulk(t *testing.T) {
+	// リミッターはファクトリーが作る API に渡される
+	send := func(f *pixelaClientFactory) time.Duration {
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := f.Graph().GetAllWithContext(f.Context())
+			assert.NoError(t, err)
+		}
+		return time.Since(start)
+	}
+	f := newPixelaFake().factory()
+	f.config.Set("concurrency", 4)
+	f.config.Set("rate", "5/s")
+	other := newPixelaFake().factory()
+	other.config.Set("concurrency", 1)
+
+	assert.NoError(t, initBulk(f))
+	assert.NoError(t, initBulk(other))
+
+	assert.NotNil(t, f.pool)
+	assert.Same(t, f.pool, f.Client().Pool)
+	assert.Same(t, f.pool, f.child().pool)
+	assert.Same(t, f.limiter, f.child().limiter)
+	assert.True(t, send(f) >= 400*time.Millisecond)
+	// --rate を指定していないコマンドのリクエストは制限しない
+	assert.Nil(t, other.limiter)
+	assert.True(t, send(other) < 200*time.Millisecond)
+
+	f.config.Set("concurrency", 0)
+	assert.EqualError(t, initBulk(f), "concurrency must be positive: 0")
+}
+
+func TestCmdRootRate(t *testing.T) {
+	f := newPixelaFake().factory()
+	cmd := newCmdRoot(f)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"--rate=100/m", "config", "path"})
+
+	assert.NoError(t, cmd.Execute())
+	assert.NotNil(t, f.limiter)
+}
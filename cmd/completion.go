@@ -20,22 +20,22 @@ func NewCmdCompletion() *cobra.Command {
 			sh := args[0]
 			switch sh {
 			case "bash":
-				err := rootCmd.GenBashCompletion(cmd.OutOrStdout())
+				err := cmd.Root().GenBashCompletion(cmd.OutOrStdout())
 				if err != nil {
 					return errors.Wrapf(err, "failed to bash completion")
 				}
 			case "zsh":
-				err := rootCmd.GenZshCompletion(cmd.OutOrStdout())
+				err := cmd.Root().GenZshCompletion(cmd.OutOrStdout())
 				if err != nil {
 					return errors.Wrapf(err, "failed to zsh completion")
 				}
 			case "fish":
-				err := rootCmd.GenFishCompletion(cmd.OutOrStdout(), true)
+				err := cmd.Root().GenFishCompletion(cmd.OutOrStdout(), true)
 				if err != nil {
 					return errors.Wrapf(err, "failed to fish completion")
 				}
 			case "powershell":
-				err := rootCmd.GenPowerShellCompletion(cmd.OutOrStdout())
+				err := cmd.Root().GenPowerShellCompletion(cmd.OutOrStdout())
 				if err != nil {
 					return errors.Wrapf(err, "failed to PowerShell completion")
 				}
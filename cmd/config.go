@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	appName          = "pa"
+	legacyConfigName = ".pa"
+)
+
+var configExtensions = []string{"toml", "yaml", "yml", "json"}
+
+type configOptions struct {
+	Format       string
+	Force        bool
+	RemoveLegacy bool
+}
+
+// NewCmdConfig creates a config command.
+func NewCmdConfig(f *pixelaClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Config",
+		Args:  cobra.NoArgs,
+		RunE:  showHelp,
+	}
+
+	cmd.AddCommand(NewCmdConfigPath(f))
+	cmd.AddCommand(NewCmdConfigMigrate(f, &configOptions{}))
+
+	return cmd
+}
+
+// NewCmdConfigPath creates a config path command.
+func NewCmdConfigPath(f *pixelaClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "path",
+		Short: "Show the config file and the data directories in use",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configDir, err := getConfigDir()
+			if err != nil {
+				return fmt.Errorf("get config dir failed: %w", err)
+			}
+			cacheDir, err := getCacheDir()
+			if err != nil {
+				return fmt.Errorf("get cache dir failed: %w", err)
+			}
+			stateDir, err := getStateDir()
+			if err != nil {
+				return fmt.Errorf("get state dir failed: %w", err)
+			}
+
+			b, err := json.Marshal(&configPaths{
+				ConfigFile: f.config.ConfigFileUsed(),
+				ConfigDir:  configDir,
+				CacheDir:   cacheDir,
+				StateDir:   stateDir,
+			})
+			if err != nil {
+				return fmt.Errorf("marshal config path failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+type configPaths struct {
+	ConfigFile string `json:"configFile"`
+	ConfigDir  string `json:"configDir"`
+	CacheDir   string `json:"cacheDir"`
+	StateDir   string `json:"stateDir"`
+}
+
+// NewCmdConfigMigrate creates a config migrate command.
+func NewCmdConfigMigrate(f *pixelaClientFactory, o *configOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the legacy $HOME/.pa to $XDG_CONFIG_HOME/pa",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home, err := homedir.Dir()
+			if err != nil {
+				return fmt.Errorf("get home dir failed: %w", err)
+			}
+			configDir, err := getConfigDir()
+			if err != nil {
+				return fmt.Errorf("get config dir failed: %w", err)
+			}
+
+			switch o.Format {
+			case "toml", "yaml", "json":
+			default:
+				return fmt.Errorf("unsupported config format: %s", o.Format)
+			}
+
+			src := filepath.Join(home, legacyConfigName)
+			dst := filepath.Join(configDir, "config."+o.Format)
+			if err := migrateConfig(f, src, dst, o.Force); err != nil {
+				return fmt.Errorf("config migrate failed: %w", err)
+			}
+			// ドライランでは書き出すファイルと削除するファイルだけを出力する
+			if f.dryRun != nil {
+				if o.RemoveLegacy {
+					printFileChange(f.dryRun, "REMOVE", src)
+				}
+				return nil
+			}
+			cmd.Printf("Migrated %s to %s\n", src, dst)
+
+			if o.RemoveLegacy {
+				if err := os.Remove(src); err != nil {
+					return fmt.Errorf("remove legacy config failed: %w", err)
+				}
+				cmd.Printf("Removed %s\n", src)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Format, "format", "toml", "The format of the migrated config file: toml, yaml or json")
+	cmd.Flags().BoolVar(&o.Force, "force", false, "Overwrite the config file if it already exists")
+	cmd.Flags().BoolVar(&o.RemoveLegacy, "remove-legacy", false, "Remove the legacy config file after the migration")
+
+	return cmd
+}
+
+// migrateConfig writes the legacy config in src to dst, or prints dst in the dry run after reading src.
+func migrateConfig(f *pixelaClientFactory, src, dst string, force bool) error {
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("legacy config not found: %w", err)
+	}
+	if _, err := os.Stat(dst); err == nil && !force {
+		return fmt.Errorf("%s already exists, specify the '--force' flag to overwrite it", dst)
+	}
+
+	// 環境変数やフラグの値が書き出されないように専用の viper で読み込む
+	v := viper.New()
+	v.SetConfigFile(src)
+	v.SetConfigType(configTypeOf(src))
+	v.SetConfigPermissions(0600)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("read legacy config failed: %w", err)
+	}
+	if f.dryRun != nil {
+		printFileChange(f.dryRun, "WRITE", dst)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return fmt.Errorf("create config dir failed: %w", err)
+	}
+	if err := v.WriteConfigAs(dst); err != nil {
+		return fmt.Errorf("write config failed: %w", err)
+	}
+	return nil
+}
+
+// findConfigFile returns the config file with the highest precedence.
+// It returns an empty string if no config file exists.
+func findConfigFile() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	candidates := []string{filepath.Join(wd, legacyConfigName)}
+	for _, ext := range configExtensions {
+		candidates = append(candidates, filepath.Join(configDir, "config."+ext))
+	}
+	candidates = append(candidates, filepath.Join(home, legacyConfigName))
+
+	for _, c := range candidates {
+		info, err := os.Stat(c)
+		if err == nil && !info.IsDir() {
+			return c, nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// configTypeOf detects the config type from the file extension.
+// The legacy config file has no extension and is written in TOML.
+func configTypeOf(path string) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	switch ext {
+	case "yaml", "yml":
+		return "yaml"
+	case "json":
+		return "json"
+	default:
+		return "toml"
+	}
+}
+
+func getConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+func getCacheDir() (string, error) {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+func getStateDir() (string, error) {
+	return xdgDir("XDG_STATE_HOME", ".local", "state")
+}
+
+// stateFile returns the path of the file under the state directory, creating the parent directory.
+func stateFile(elem ...string) (string, error) {
+	dir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(append([]string{dir}, elem...)...)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// readStateJSON unmarshals the state file into v. It leaves v as it is when the file does not exist.
+func readStateJSON(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// writeStateJSON writes v to the state file atomically so that an interrupted write never breaks it.
+func writeStateJSON(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// xdgDir returns the pa directory under the XDG base directory.
+// A relative path in the environment variable is ignored as the specification says.
+func xdgDir(env string, fallback ...string) (string, error) {
+	base := os.Getenv(env)
+	if base == "" || !filepath.IsAbs(base) {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(append([]string{home}, fallback...)...)
+	}
+	return filepath.Join(base, appName), nil
+}
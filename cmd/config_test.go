@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupConfigHome(t *testing.T) (home string, wd string) {
+	t.Helper()
+	home = t.TempDir()
+	wd = t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "relative/state")
+	prev, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(wd))
+	t.Cleanup(func() { _ = os.Chdir(prev) })
+	homedir.Reset()
+	t.Cleanup(homedir.Reset)
+	return home, wd
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func TestConfigTypeOf(t *testing.T) {
+	params := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/home/pa/.pa", expected: "toml"},
+		{path: "config.toml", expected: "toml"},
+		{path: "config.yaml", expected: "yaml"},
+		{path: "config.YML", expected: "yaml"},
+		{path: "config.json", expected: "json"},
+	}
+
+	for _, p := range params {
+		assert.Equal(t, p.expected, configTypeOf(p.path), p.path)
+	}
+}
+
+func TestXDGDir(t *testing.T) {
+	home, _ := setupConfigHome(t)
+
+	configDir, err := getConfigDir()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "xdg-config", "pa"), configDir)
+
+	cacheDir, err := getCacheDir()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".cache", "pa"), cacheDir)
+
+	// 相対パスは無視する
+	stateDir, err := getStateDir()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".local", "state", "pa"), stateDir)
+}
+
+func TestFindConfigFile(t *testing.T) {
+	home, wd := setupConfigHome(t)
+
+	path, err := findConfigFile()
+	assert.NoError(t, err)
+	assert.Equal(t, "", path)
+
+	writeFile(t, filepath.Join(home, ".pa"), `username = "home"`)
+	path, err = findConfigFile()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".pa"), path)
+
+	writeFile(t, filepath.Join(home, "xdg-config", "pa", "config.json"), `{"username": "json"}`)
+	path, err = findConfigFile()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "xdg-config", "pa", "config.json"), path)
+
+	writeFile(t, filepath.Join(home, "xdg-config", "pa", "config.yaml"), `username: yaml`)
+	path, err = findConfigFile()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "xdg-config", "pa", "config.yaml"), path)
+
+	writeFile(t, filepath.Join(wd, ".pa"), `username = "wd"`)
+	path, err = findConfigFile()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(wd, ".pa"), path)
+}
+
+func TestConfigMigrate(t *testing.T) {
+	home, _ := setupConfigHome(t)
+	writeFile(t, filepath.Join(home, ".pa"), "username = \"pa-user\"\ntoken = \"pa-token\"\n")
+
+	params := []struct {
+		commandline []string
+		dst         string
+		occur       bool
+	}{
+		{
+			commandline: []string{"config", "migrate", "--format=yaml"},
+			dst:         filepath.Join(home, "xdg-config", "pa", "config.yaml"),
+			occur:       false,
+		},
+		{
+			commandline: []string{"config", "migrate", "--format=yaml"},
+			dst:         filepath.Join(home, "xdg-config", "pa", "config.yaml"),
+			occur:       true,
+		},
+		{
+			commandline: []string{"config", "migrate", "--format=yaml", "--force"},
+			dst:         filepath.Join(home, "xdg-config", "pa", "config.yaml"),
+			occur:       false,
+		},
+		{
+			commandline: []string{"config", "migrate", "--format=ini"},
+			occur:       true,
+		},
+	}
+
+	for _, p := range params {
+		cmd := newCmdRoot(newPixelaClientFactory())
+		buffer := bytes.NewBuffer([]byte{})
+		cmd.SetOut(buffer)
+		cmd.SetArgs(p.commandline)
+
+		err := cmd.Execute()
+
+		if p.occur {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), p.dst)
+
+		v := viper.New()
+		v.SetConfigFile(p.dst)
+		assert.NoError(t, v.ReadInConfig())
+		assert.Equal(t, "pa-user", v.GetString("username"))
+		assert.Equal(t, "pa-token", v.GetString("token"))
+
+		info, err := os.Stat(p.dst)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronNext(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	// 2026-01-01 は木曜日
+	base := time.Date(2026, 1, 1, 10, 30, 15, 0, tokyo)
+
+	params := []struct {
+		expr     string
+		expected []time.Time
+	}{
+		{expr: "* * * * *", expected: []time.Time{
+			time.Date(2026, 1, 1, 10, 31, 0, 0, tokyo),
+			time.Date(2026, 1, 1, 10, 32, 0, 0, tokyo),
+		}},
+		{expr: "*/20 * * * *", expected: []time.Time{
+			time.Date(2026, 1, 1, 10, 40, 0, 0, tokyo),
+			time.Date(2026, 1, 1, 11, 0, 0, 0, tokyo),
+		}},
+		{expr: "0 9-18/3 * * 1-5", expected: []time.Time{
+			time.Date(2026, 1, 1, 12, 0, 0, 0, tokyo),
+			time.Date(2026, 1, 1, 15, 0, 0, 0, tokyo),
+			time.Date(2026, 1, 1, 18, 0, 0, 0, tokyo),
+			time.Date(2026, 1, 2, 9, 0, 0, 0, tokyo),
+			time.Date(2026, 1, 2, 12, 0, 0, 0, tokyo),
+		}},
+		{expr: "30 10 * * 7", expected: []time.Time{
+			time.Date(2026, 1, 4, 10, 30, 0, 0, tokyo),
+		}},
+		{expr: "0 0 13 * 5", expected: []time.Time{
+			time.Date(2026, 1, 2, 0, 0, 0, 0, tokyo),
+			time.Date(2026, 1, 9, 0, 0, 0, 0, tokyo),
+			time.Date(2026, 1, 13, 0, 0, 0, 0, tokyo),
+		}},
+		// "*/2" は制限なしとして扱い、奇数日かつ月曜日に一致する
+		{expr: "0 9 */2 * 1", expected: []time.Time{
+			time.Date(2026, 1, 5, 9, 0, 0, 0, tokyo),
+			time.Date(2026, 1, 19, 9, 0, 0, 0, tokyo),
+			time.Date(2026, 2, 9, 9, 0, 0, 0, tokyo),
+		}},
+		{expr: "0 12 29 2 *", expected: []time.Time{
+			time.Date(2028, 2, 29, 12, 0, 0, 0, tokyo),
+		}},
+		{expr: "@monthly", expected: []time.Time{
+			time.Date(2026, 2, 1, 0, 0, 0, 0, tokyo),
+			time.Date(2026, 3, 1, 0, 0, 0, 0, tokyo),
+		}},
+		{expr: "0 0 30 2 *", expected: []time.Time{{}}},
+	}
+
+	for _, p := range params {
+		s, err := parseCron(p.expr)
+		assert.NoError(t, err, p.expr)
+		next := base
+		for _, e := range p.expected {
+			next = s.next(next)
+			assert.True(t, e.Equal(next), "%s: expected %s, got %s", p.expr, e, next)
+		}
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+	s, err := parseCron("30 2 * * *")
+	assert.NoError(t, err)
+
+	// 2026-03-08 02:30 は夏時間への切り替えで存在しない
+	next := s.next(time.Date(2026, 3, 7, 12, 0, 0, 0, ny))
+	assert.Equal(t, time.Date(2026, 3, 9, 2, 30, 0, 0, ny), next)
+}
+
+func TestParseCronError(t *testing.T) {
+	params := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: "* * * *", expected: `invalid cron expression "* * * *", it must have 5 fields`},
+		{expr: "60 * * * *", expected: `invalid minute of "60 * * * *": "60" is out of range 0-59`},
+		{expr: "* 5-1 * * *", expected: `invalid hour of "* 5-1 * * *": "5-1" is out of range 0-23`},
+		{expr: "* * 0 * *", expected: `invalid day of month of "* * 0 * *": "0" is out of range 1-31`},
+		{expr: "* * * jan *", expected: `invalid month of "* * * jan *": invalid value "jan"`},
+		{expr: "* * * * */0", expected: `invalid day of week of "* * * * */0": invalid step "*/0"`},
+	}
+
+	for _, p := range params {
+		_, err := parseCron(p.expr)
+		assert.EqualError(t, err, p.expected)
+	}
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ebc-2in2crc/pa/pkg/pa"
+	pixela "github.com/ebc-2in2crc/pixela4go"
+)
+
+// redacted replaces the tokens in the printed request bodies.
+const redacted = "********"
+
+// dryRunWebhookHash is the hash of the webhook created in the dry run.
+const dryRunWebhookHash = "dry-run-webhook-hash"
+
+// dryRunner prints the requests which the mutating API calls would send instead of sending them.
+type dryRunner struct {
+	out      io.Writer
+	username string
+}
+
+// print prints the method, the path and the JSON body of the request, and returns a successful result.
+// body is omitted when it is nil.
+func (d *dryRunner) print(method, path string, body interface{}) (*pixela.Result, error) {
+	line := method + " " + strings.TrimPrefix(path, pixela.APIBaseURL)
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request body failed: %w", err)
+		}
+		line += " " + string(b)
+	}
+	fmt.Fprintln(d.out, line)
+
+	return &pixela.Result{Message: "Dry run.", IsSuccess: true}, nil
+}
+
+// printFileChange prints the change of the local file, such as WRITE and REMOVE, which is not made in the dry run.
+func printFileChange(out io.Writer, change, path string) {
+	fmt.Fprintln(out, change+" "+path)
+}
+
+func (d *dryRunner) userURL() string {
+	return pixela.APIBaseURLForV1 + "/users/" + d.username
+}
+
+func (d *dryRunner) graphURL(id *string) string {
+	return d.userURL() + "/graphs/" + pixela.StringValue(id)
+}
+
+type dryRunUser struct {
+	pixelaUser
+	runner *dryRunner
+}
+
+func (u *dryRunUser) CreateWithContext(ctx context.Context, input *pixela.UserCreateInput) (*pixela.Result, error) {
+	return u.runner.print(http.MethodPost, pixela.APIBaseURLForV1+"/users", &struct {
+		Token               string `json:"token"`
+		UserName            string `json:"username"`
+		AgreeTermsOfService string `json:"AgreeTermsOfService"`
+		NotMinor            string `json:"NotMinor"`
+		ThanksCode          string `json:"thanksCode,omitempty"`
+	}{
+		Token:               redacted,
+		UserName:            u.runner.username,
+		AgreeTermsOfService: yesNo(pixela.BoolValue(input.AgreeTermsOfService)),
+		NotMinor:            yesNo(pixela.BoolValue(input.NotMinor)),
+		ThanksCode:          pixela.StringValue(input.ThanksCode),
+	})
+}
+
+func (u *dryRunUser) UpdateWithContext(ctx context.Context, input *pixela.UserUpdateInput) (*pixela.Result, error) {
+	return u.runner.print(http.MethodPut, u.runner.userURL(), &struct {
+		NewToken   string `json:"newToken"`
+		ThanksCode string `json:"thanksCode,omitempty"`
+	}{
+		NewToken:   redacted,
+		ThanksCode: pixela.StringValue(input.ThanksCode),
+	})
+}
+
+func (u *dryRunUser) DeleteWithContext(ctx context.Context) (*pixela.Result, error) {
+	return u.runner.print(http.MethodDelete, u.runner.userURL(), nil)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+type dryRunUserProfile struct {
+	pixelaUserProfile
+	runner *dryRunner
+}
+
+func (p *dryRunUserProfile) UpdateWithContext(ctx context.Context, input *pixela.UserProfileUpdateInput) (*pixela.Result, error) {
+	return p.runner.print(http.MethodPut, pixela.APIBaseURL+"/@"+p.runner.username, input)
+}
+
+type dryRunGraph struct {
+	pixelaGraph
+	runner *dryRunner
+}
+
+func (g *dryRunGraph) CreateWithContext(ctx context.Context, input *pixela.GraphCreateInput) (*pixela.Result, error) {
+	return g.runner.print(http.MethodPost, g.runner.userURL()+"/graphs", input)
+}
+
+func (g *dryRunGraph) UpdateWithContext(ctx context.Context, input *pixela.GraphUpdateInput) (*pixela.Result, error) {
+	return g.runner.print(http.MethodPut, g.runner.graphURL(input.ID), input)
+}
+
+func (g *dryRunGraph) DeleteWithContext(ctx context.Context, input *pixela.GraphDeleteInput) (*pixela.Result, error) {
+	return g.runner.print(http.MethodDelete, g.runner.graphURL(input.ID), nil)
+}
+
+func (g *dryRunGraph) StopwatchWithContext(ctx context.Context, input *pixela.GraphStopwatchInput) (*pixela.Result, error) {
+	return g.runner.print(http.MethodPost, g.runner.graphURL(input.ID)+"/stopwatch", nil)
+}
+
+func (g *dryRunGraph) AddWithContext(ctx context.Context, input *pixela.GraphAddInput) (*pixela.Result, error) {
+	return g.runner.print(http.MethodPut, g.runner.graphURL(input.ID)+"/add", input)
+}
+
+func (g *dryRunGraph) SubtractWithContext(ctx context.Context, input *pixela.GraphSubtractInput) (*pixela.Result, error) {
+	return g.runner.print(http.MethodPut, g.runner.graphURL(input.ID)+"/subtract", input)
+}
+
+func (g *dryRunGraph) ClearPurgeCacheURLsWithContext(ctx context.Context, input *pa.GraphClearPurgeCacheURLsInput) (*pixela.Result, error) {
+	return g.runner.print(http.MethodPut, g.runner.graphURL(input.ID), map[string][]string{"purgeCacheURLs": {}})
+}
+
+type dryRunPixel struct {
+	pixelaPixel
+	runner *dryRunner
+}
+
+func (p *dryRunPixel) CreateWithContext(ctx context.Context, input *pixela.PixelCreateInput) (*pixela.Result, error) {
+	return p.runner.print(http.MethodPost, p.runner.graphURL(input.GraphID), input)
+}
+
+func (p *dryRunPixel) IncrementWithContext(ctx context.Context, input *pixela.PixelIncrementInput) (*pixela.Result, error) {
+	return p.runner.print(http.MethodPut, p.runner.graphURL(input.GraphID)+"/increment", nil)
+}
+
+func (p *dryRunPixel) DecrementWithContext(ctx context.Context, input *pixela.PixelDecrementInput) (*pixela.Result, error) {
+	return p.runner.print(http.MethodPut, p.runner.graphURL(input.GraphID)+"/decrement", nil)
+}
+
+func (p *dryRunPixel) UpdateWithContext(ctx context.Context, input *pixela.PixelUpdateInput) (*pixela.Result, error) {
+	return p.runner.print(http.MethodPut, p.runner.graphURL(input.GraphID)+"/"+pixela.StringValue(input.Date), input)
+}
+
+func (p *dryRunPixel) DeleteWithContext(ctx context.Context, input *pixela.PixelDeleteInput) (*pixela.Result, error) {
+	return p.runner.print(http.MethodDelete, p.runner.graphURL(input.GraphID)+"/"+pixela.StringValue(input.Date), nil)
+}
+
+type dryRunWebhook struct {
+	pixelaWebhook
+	runner *dryRunner
+}
+
+func (w *dryRunWebhook) CreateWithContext(ctx context.Context, input *pixela.WebhookCreateInput) (*pixela.WebhookCreateResult, error) {
+	result, err := w.runner.print(http.MethodPost, w.runner.userURL()+"/webhooks", input)
+	if err != nil {
+		return nil, err
+	}
+	// 作成した webhook を続けて使うコマンドのために仮のハッシュを返す
+	return &pixela.WebhookCreateResult{Result: *result, WebhookHash: dryRunWebhookHash}, nil
+}
+
+func (w *dryRunWebhook) InvokeWithContext(ctx context.Context, input *pixela.WebhookInvokeInput) (*pixela.Result, error) {
+	return w.runner.print(http.MethodPost, w.runner.userURL()+"/webhooks/"+pixela.StringValue(input.WebhookHash), nil)
+}
+
+func (w *dryRunWebhook) DeleteWithContext(ctx context.Context, input *pixela.WebhookDeleteInput) (*pixela.Result, error) {
+	return w.runner.print(http.MethodDelete, w.runner.userURL()+"/webhooks/"+pixela.StringValue(input.WebhookHash), nil)
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee", Name: "coffee"}, pixela.PixelWithBody{Date: "20260101", Quantity: "3"})
+
+	params := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{"graph", "update", "--id", "coffee", "--name", "tea"},
+			expected: `PUT /v1/users/alice/graphs/coffee {"name":"tea"}`,
+		},
+		{
+			args:     []string{"graph", "delete", "--id", "coffee", "--delete-me"},
+			expected: `DELETE /v1/users/alice/graphs/coffee`,
+		},
+		{
+			args:     []string{"pixel", "update", "--graph-id", "coffee", "--date", "20260101", "--quantity", "5"},
+			expected: `PUT /v1/users/alice/graphs/coffee/20260101 {"quantity":"5"}`,
+		},
+		{
+			args:     []string{"pixel", "increment", "--graph-id", "coffee"},
+			expected: `PUT /v1/users/alice/graphs/coffee/increment`,
+		},
+		{
+			args:     []string{"user", "update", "--new-token", "new-secret"},
+			expected: `PUT /v1/users/alice {"newToken":"********"}`,
+		},
+		{
+			args:     []string{"user", "delete", "--delete-me"},
+			expected: `DELETE /v1/users/alice`,
+		},
+	}
+
+	for _, p := range params {
+		cmd := newCmdRoot(f)
+		out := bytes.NewBuffer([]byte{})
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"--dry-run", "--username", "alice", "--token", "secret"}, p.args...))
+
+		err := cmd.Execute()
+
+		assert.NoError(t, err, p.args)
+		assert.Equal(t, p.expected+"\n"+`{"message":"Dry run.","isSuccess":true,"isRejected":false,"statusCode":0}`+"\n", out.String(), p.args)
+		assert.NotContains(t, out.String(), "secret")
+	}
+	assert.Empty(t, fake.added)
+	assert.Equal(t, "coffee", fake.definitions["coffee"].Name)
+	assert.Equal(t, "3", fake.pixels["coffee"]["20260101"].Quantity)
+}
+
+func TestDryRunKeepsState(t *testing.T) {
+	home, _ := setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+
+	cmd := newCmdRoot(f)
+	cmd.SetOut(bytes.NewBuffer([]byte{}))
+	cmd.SetArgs([]string{"--dry-run", "webhook", "alias", "set", "coffee", "coffee-hash"})
+
+	assert.NoError(t, cmd.Execute())
+	assert.NoFileExists(t, filepath.Join(home, ".local", "state", "pa", "webhook_aliases.json"))
+}
+
+func TestDryRunCreateIfMissing(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee"})
+
+	out, err := executeWebhook(t, f, "invoke", "--dry-run", "--username", "alice", "--graph-id=coffee", "--type=increment", "--create-if-missing")
+
+	assert.NoError(t, err)
+	assert.Equal(t, `POST /v1/users/alice/webhooks {"graphID":"coffee","type":"increment"}`+"\n"+
+		"POST /v1/users/alice/webhooks/"+dryRunWebhookHash+"\n"+
+		`{"message":"Dry run.","isSuccess":true,"isRejected":false,"statusCode":0}`+"\n", out)
+	assert.Empty(t, fake.webhooks)
+	assert.Empty(t, fake.added)
+}
+
+func TestDryRunLocalFiles(t *testing.T) {
+	repo := setupGitRepo(t)
+	hook := filepath.Join(repo, ".git", "hooks", "post-commit")
+
+	out, err := executeGit(t, newPixelaClientFactory(), "--dry-run", "hooks", "install", "--repo", repo, "--graph", "commits")
+	assert.NoError(t, err)
+	assert.Equal(t, "WRITE "+hook+"\n"+hook+"\n", out)
+	assert.NoFileExists(t, hook)
+
+	home, _ := setupConfigHome(t)
+	legacy := filepath.Join(home, ".pa")
+	writeFile(t, legacy, "username = \"pa-user\"\ntoken = \"pa-token\"\n")
+	dst := filepath.Join(home, "xdg-config", "pa", "config.toml")
+
+	out, err = executeGit(t, newPixelaClientFactory(), "--dry-run", "config", "migrate", "--remove-legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, "WRITE "+dst+"\nREMOVE "+legacy+"\n", out)
+	assert.NoFileExists(t, dst)
+	assert.FileExists(t, legacy)
+}
+
+func TestDryRunSchedule(t *testing.T) {
+	f, runs, _ := setupSchedule(t, time.Date(2026, 1, 1, 3, 0, 20, 0, time.UTC), noScheduleFailure)
+
+	_, _, err := executeSchedule(t, f, "run", "--once", "--dry-run")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--dry-run=true pixel increment --graph-id water", "--dry-run=true graph add --id review --quantity 1"}, *runs)
+}
+
+func TestDryRunGraphRename(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	fake.addGraph(pixela.GraphDefinition{ID: "old", Name: "name"}, pixela.PixelWithBody{Date: "20260101", Quantity: "3"})
+	fake.webhooks = []pixela.WebhookDefinition{{WebhookHash: "old-hash", GraphID: "old", Type: "increment"}}
+	f := fake.factory()
+
+	cmd := newCmdRoot(f)
+	out := bytes.NewBuffer([]byte{})
+	cmd.SetOut(out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--dry-run", "--username", "alice", "graph", "rename", "--id=old", "--new-id=new"})
+
+	// 新しいグラフは作られないので、コピーした Pixel は確認しない
+	assert.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), `POST /v1/users/alice/graphs/new {"date":"20260101","quantity":"3"}`)
+	assert.Contains(t, out.String(), `{"id":"new","oldId":"old","pixels":1,"webhooks":[{"type":"increment","oldHash":"old-hash","newHash":"`+dryRunWebhookHash+`"}],"deleted":false}`)
+	_, ok := fake.definitions["new"]
+	assert.False(t, ok)
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+type execOptions struct {
+	ID             string
+	Unit           string
+	CountOnSuccess bool
+}
+
+// durationUnits maps the unit of the graph to the unit of the duration.
+var durationUnits = map[string]time.Duration{
+	"s":       time.Second,
+	"sec":     time.Second,
+	"secs":    time.Second,
+	"second":  time.Second,
+	"seconds": time.Second,
+	"秒":       time.Second,
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"mins":    time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"分":       time.Minute,
+	"h":       time.Hour,
+	"hr":      time.Hour,
+	"hrs":     time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"時間":      time.Hour,
+}
+
+// NewCmdExec creates an exec command.
+func NewCmdExec(f *pixelaClientFactory) *cobra.Command {
+	o := &execOptions{}
+	cmd := &cobra.Command{
+		Use:   "exec -- command [args...]",
+		Short: "Run a command and record its duration or success",
+		Long: "Run a command and add its wall time to the Graph in the unit of the Graph (seconds, minutes or hours).\n" +
+			"With '--count-on-success', increment the Pixel of today only when the command succeeds.\n" +
+			"Exit with the exit status of the command.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var unit time.Duration
+			var isInt bool
+			if !o.CountOnSuccess {
+				var err error
+				unit, isInt, err = graphDurationUnit(f, o.ID, o.Unit)
+				if err != nil {
+					return reportError(cmd, err, "exec failed")
+				}
+			}
+
+			elapsed, runErr := runCommand(cmd, f, args)
+			var exitErr *exitError
+			if runErr != nil && !errors.As(runErr, &exitErr) {
+				return runErr
+			}
+
+			var result *pixela.Result
+			var err error
+			switch {
+			case o.CountOnSuccess && runErr != nil:
+				return runErr
+			case o.CountOnSuccess:
+				result, err = f.Pixel().IncrementWithContext(f.Context(), &pixela.PixelIncrementInput{GraphID: pixela.String(o.ID)})
+			default:
+				q := durationQuantity(elapsed, unit, isInt)
+				if q == "0" {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s is too short to record\n", elapsed)
+					return runErr
+				}
+				result, err = f.Graph().AddWithContext(f.Context(), &pixela.GraphAddInput{ID: pixela.String(o.ID), Quantity: pixela.String(q)})
+			}
+			if err != nil {
+				return fmt.Errorf("exec record failed: %w", err)
+			}
+
+			// 標準出力はコマンドの出力なので記録の結果は標準エラー出力に出す
+			s, err := marshalResult(result)
+			if err != nil {
+				return fmt.Errorf("marshal exec result failed: %w", err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", s)
+
+			if runErr != nil {
+				return runErr
+			}
+			if !result.IsSuccess {
+				return ErrNeglect
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVar(&o.Unit, "unit", "", "The unit of the duration: seconds, minutes or hours (default the unit of the graph)")
+	cmd.Flags().BoolVar(&o.CountOnSuccess, "count-on-success", false, "Increment the Pixel of today when the command succeeds instead of adding the duration")
+
+	return cmd
+}
+
+// graphDurationUnit returns the unit of the duration and whether the graph is an int graph.
+// The unit of the graph is used unless the unit is specified.
+func graphDurationUnit(f *pixelaClientFactory, id, unit string) (time.Duration, bool, error) {
+	def, err := f.Graph().GetWithContext(f.Context(), &pixela.GraphGetInput{ID: pixela.String(id)})
+	if err != nil {
+		return 0, false, fmt.Errorf("graph get failed: %w", err)
+	}
+	if !def.IsSuccess {
+		return 0, false, &resultError{Result: &def.Result}
+	}
+
+	if unit == "" {
+		unit = def.Unit
+	}
+	d, ok := durationUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, false, fmt.Errorf("unsupported unit for the duration: %s, specify '--unit' or '--count-on-success'", unit)
+	}
+	return d, def.Type == "int", nil
+}
+
+// runCommand runs the command with the standard streams of cmd and returns its wall time.
+// It returns an exitError when the command exits with non-zero status.
+func runCommand(cmd *cobra.Command, f *pixelaClientFactory, args []string) (time.Duration, error) {
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdin = cmd.InOrStdin()
+	c.Stdout = cmd.OutOrStdout()
+	c.Stderr = cmd.ErrOrStderr()
+
+	start := f.now()
+	err := c.Run()
+	elapsed := f.now().Sub(start)
+
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return elapsed, &exitError{code: ee.ExitCode()}
+	}
+	if err != nil {
+		return elapsed, fmt.Errorf("run command failed: %w", err)
+	}
+	return elapsed, nil
+}
+
+// durationQuantity converts the duration to the quantity. It rounds to an integer for an int graph
+// and to 2 decimal places for a float graph.
+func durationQuantity(d, unit time.Duration, isInt bool) string {
+	v := float64(d) / float64(unit)
+	if isInt {
+		return strconv.Itoa(int(math.Round(v)))
+	}
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func executeExec(t *testing.T, f *pixelaClientFactory, elapsed time.Duration, args ...string) (string, string, error) {
+	t.Helper()
+	now := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+	f.now = func() time.Time {
+		current := now
+		now = now.Add(elapsed)
+		return current
+	}
+
+	cmd := newCmdRoot(f)
+	out := bytes.NewBuffer([]byte{})
+	errOut := bytes.NewBuffer([]byte{})
+	cmd.SetOut(out)
+	cmd.SetErr(errOut)
+	cmd.SetArgs(append([]string{"exec"}, args...))
+	err := cmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+func TestExec(t *testing.T) {
+	params := []struct {
+		unit     string
+		typ      string
+		args     []string
+		elapsed  time.Duration
+		expected []string
+	}{
+		{unit: "minutes", typ: "int", args: []string{"--id=graph-id", "--", "true"}, elapsed: 150 * time.Second, expected: []string{"graph-id:3"}},
+		{unit: "min", typ: "float", args: []string{"--id=graph-id", "true"}, elapsed: 100 * time.Second, expected: []string{"graph-id:1.67"}},
+		{unit: "分", typ: "int", args: []string{"--id=graph-id", "--", "true"}, elapsed: 20 * time.Second, expected: nil},
+		{unit: "builds", typ: "int", args: []string{"--id=graph-id", "--unit=seconds", "--", "true"}, elapsed: 42 * time.Second, expected: []string{"graph-id:42"}},
+		{unit: "hours", typ: "float", args: []string{"--id=graph-id", "--", "true"}, elapsed: 90 * time.Minute, expected: []string{"graph-id:1.5"}},
+		{unit: "builds", typ: "int", args: []string{"--id=graph-id", "--count-on-success", "--", "true"}, expected: []string{"graph-id:increment"}},
+	}
+
+	for _, p := range params {
+		fake := newPixelaFake()
+		f := fake.factory()
+		fake.addGraph(pixela.GraphDefinition{ID: "graph-id", Unit: p.unit, Type: p.typ})
+
+		_, _, err := executeExec(t, f, p.elapsed, p.args...)
+		assert.NoError(t, err, p.unit)
+		assert.Equal(t, p.expected, fake.added, p.unit)
+	}
+}
+
+func TestExecPassesThroughExitCode(t *testing.T) {
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "graph-id", Unit: "seconds", Type: "int"})
+
+	out, errOut, err := executeExec(t, f, 5*time.Second, "--id=graph-id", "--", "sh", "-c", "echo out; echo err >&2; exit 3")
+	var exitErr *exitError
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 3, exitErr.code)
+	assert.Equal(t, "out\n", out)
+	assert.Contains(t, errOut, "err\n")
+	assert.Contains(t, errOut, `"isSuccess":true`)
+	// 失敗したコマンドの時間も記録する
+	assert.Equal(t, []string{"graph-id:5"}, fake.added)
+
+	fake.added = nil
+	_, _, err = executeExec(t, f, 5*time.Second, "--id=graph-id", "--count-on-success", "--", "sh", "-c", "exit 2")
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 2, exitErr.code)
+	assert.Empty(t, fake.added)
+}
+
+func TestExecError(t *testing.T) {
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "graph-id", Unit: "commits", Type: "int"})
+
+	_, _, err := executeExec(t, f, time.Second, "--id=graph-id", "--", "true")
+	assert.EqualError(t, err, "exec failed: unsupported unit for the duration: commits, specify '--unit' or '--count-on-success'")
+
+	out, _, err := executeExec(t, f, time.Second, "--id=unknown-id", "--", "true")
+	assert.True(t, errors.Is(err, ErrNeglect))
+	assert.Contains(t, out, "Specified graphID not exist.")
+
+	_, _, err = executeExec(t, f, time.Second, "--id=graph-id", "--count-on-success", "--", "pa-no-such-command")
+	assert.Contains(t, err.Error(), "run command failed:")
+}
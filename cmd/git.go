@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+type gitOptions struct {
+	Repo   string
+	Graph  string
+	Metric string
+	Since  string
+	Until  string
+	Force  bool
+}
+
+var gitMetrics = []string{"commits", "lines-added", "files"}
+
+// gitCommitMarker is the prefix of the line which starts a commit in the output of git log.
+const gitCommitMarker = "@@pa "
+
+// NewCmdGit creates a git command.
+func NewCmdGit(f *pixelaClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "git",
+		Short: "Record the git activity",
+		Args:  cobra.NoArgs,
+		RunE:  showHelp,
+	}
+
+	cmd.AddCommand(NewCmdGitRecord(f, &gitOptions{}))
+	cmd.AddCommand(NewCmdGitBackfill(f, &gitOptions{}))
+
+	return cmd
+}
+
+// NewCmdGitRecord creates a record git command.
+func NewCmdGitRecord(f *pixelaClientFactory, o *gitOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "record",
+		Short: "Record the last commit to the Graph",
+		Long:  "Record the last commit to the Graph. This is invoked by the post-commit hook installed by 'pa hooks install'.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateGitMetric(o.Metric); err != nil {
+				return err
+			}
+
+			stats, err := gitLogStats(o.Repo, "-1", "HEAD")
+			if err != nil {
+				return err
+			}
+			q := 0
+			for _, s := range stats {
+				q += s.metric(o.Metric)
+			}
+
+			var result *pixela.Result
+			switch {
+			case o.Metric == "commits":
+				result, err = f.Pixel().IncrementWithContext(f.Context(), &pixela.PixelIncrementInput{GraphID: pixela.String(o.Graph)})
+			case q > 0:
+				result, err = f.Graph().AddWithContext(f.Context(), &pixela.GraphAddInput{ID: pixela.String(o.Graph), Quantity: pixela.String(strconv.Itoa(q))})
+			default:
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("git record failed: %w", err)
+			}
+			s, err := marshalResult(result)
+			if err != nil {
+				return fmt.Errorf("marshal git record result failed: %w", err)
+			}
+			cmd.Printf("%s\n", s)
+
+			if !result.IsSuccess {
+				return ErrNeglect
+			}
+			return nil
+		},
+	}
+
+	addGitFlags(cmd, o)
+
+	return cmd
+}
+
+// NewCmdGitBackfill creates a backfill git command.
+func NewCmdGitBackfill(f *pixelaClientFactory, o *gitOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backfill",
+		Short: "Write the Pixels per day from the local git history",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateGitMetric(o.Metric); err != nil {
+				return err
+			}
+			if err := checkProtectedGraph(f, o.Graph, "overwrite", o.Force); err != nil {
+				return err
+			}
+
+			result, backfillErr := backfillGit(cmd, f, o)
+			if result == nil {
+				return backfillErr
+			}
+
+			b, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("marshal git backfill result failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+
+			// キャンセルされたときはそれまでの結果を出力して終了する
+			if backfillErr != nil {
+				return fmt.Errorf("git backfill cancelled: %w", backfillErr)
+			}
+
+			if len(result.Failed) > 0 {
+				return ErrNeglect
+			}
+			return nil
+		},
+	}
+
+	addGitFlags(cmd, o)
+	cmd.Flags().StringVar(&o.Since, "since", "", "Walk the commits more recent than the date (e.g. 2026-01-01)")
+	cmd.Flags().StringVar(&o.Until, "until", "", "Walk the commits older than the date")
+	cmd.Flags().BoolVar(&o.Force, "force", false, "Overwrite the Pixels even if the graph is protected by protected_graphs")
+
+	return cmd
+}
+
+func addGitFlags(cmd *cobra.Command, o *gitOptions) {
+	cmd.Flags().StringVar(&o.Repo, "repo", ".", "Path of the git repository")
+	cmd.Flags().StringVar(&o.Graph, "graph", "", "ID for identifying the pixelation graph")
+	_ = cmd.MarkFlagRequired("graph")
+	cmd.Flags().StringVar(&o.Metric, "metric", "commits", "What to record: "+strings.Join(gitMetrics, ", "))
+}
+
+func validateGitMetric(metric string) error {
+	for _, m := range gitMetrics {
+		if m == metric {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported metric: %s", metric)
+}
+
+type gitBackfillResult struct {
+	Graph   string   `json:"graph"`
+	Dates   int      `json:"dates"`
+	Updated int      `json:"updated"`
+	Failed  []string `json:"failed"`
+}
+
+// backfillGit writes the Pixels per day of the git history.
+// When the command is cancelled, it returns the result so far with the error.
+func backfillGit(cmd *cobra.Command, f *pixelaClientFactory, o *gitOptions) (*gitBackfillResult, error) {
+	ctx := f.Context()
+	args := []string{"--no-merges"}
+	if o.Since != "" {
+		args = append(args, "--since="+o.Since)
+	}
+	if o.Until != "" {
+		args = append(args, "--until="+o.Until)
+	}
+	stats, err := gitLogStats(o.Repo, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]string, 0, len(stats))
+	for d := range stats {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+
+	r := &gitBackfillResult{Graph: o.Graph, Dates: len(dates), Failed: []string{}}
+	results := make([]*pixela.Result, len(dates))
+	update := func(ctx context.Context, i int) error {
+		var err error
+		results[i], err = f.Pixel().UpdateWithContext(ctx, &pixela.PixelUpdateInput{
+			GraphID:  pixela.String(o.Graph),
+			Date:     pixela.String(dates[i]),
+			Quantity: pixela.String(strconv.Itoa(stats[dates[i]].metric(o.Metric))),
+		})
+		return err
+	}
+	report := func(i int, err error) {
+		if err != nil {
+			return
+		}
+		if !results[i].IsSuccess {
+			r.Failed = append(r.Failed, dates[i])
+			fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] %s failed: %s\n", i+1, len(dates), dates[i], results[i].Message)
+			return
+		}
+		r.Updated++
+		fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] %s updated: %d\n", i+1, len(dates), dates[i], stats[dates[i]].metric(o.Metric))
+	}
+	if err := f.pool.Do(ctx, len(dates), update, report); err != nil {
+		if ctx.Err() != nil {
+			return r, ctx.Err()
+		}
+		return nil, fmt.Errorf("pixel update failed: %w", err)
+	}
+	return r, nil
+}
+
+// gitStats is the activity of a day.
+type gitStats struct {
+	Commits    int
+	LinesAdded int
+	Files      int
+}
+
+func (s *gitStats) metric(name string) int {
+	switch name {
+	case "lines-added":
+		return s.LinesAdded
+	case "files":
+		return s.Files
+	}
+	return s.Commits
+}
+
+// gitLogStats runs git log with the args and aggregates the commits per author date.
+func gitLogStats(repo string, args ...string) (map[string]*gitStats, error) {
+	args = append([]string{"-C", repo, "log", "--numstat", "--date=format:%Y%m%d", "--format=" + gitCommitMarker + "%ad"}, args...)
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			return nil, fmt.Errorf("git log failed: %s", strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+	return parseGitLog(string(out))
+}
+
+// parseGitLog parses the output of git log --numstat whose commits start with gitCommitMarker and the date.
+func parseGitLog(out string) (map[string]*gitStats, error) {
+	stats := map[string]*gitStats{}
+	var current *gitStats
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, gitCommitMarker) {
+			date := strings.TrimPrefix(line, gitCommitMarker)
+			if _, ok := stats[date]; !ok {
+				stats[date] = &gitStats{}
+			}
+			current = stats[date]
+			current.Commits++
+			continue
+		}
+		if line == "" || current == nil {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git log line: %s", line)
+		}
+		current.Files++
+		// バイナリファイルの行数は "-" になる
+		if added, err := strconv.Atoi(fields[0]); err == nil {
+			current.LinesAdded += added
+		}
+	}
+	return stats, scanner.Err()
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupGitRepo creates a git repository in a temporary directory.
+func setupGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	setupConfigHome(t)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "pa")
+	t.Setenv("GIT_AUTHOR_EMAIL", "pa@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "pa")
+	t.Setenv("GIT_COMMITTER_EMAIL", "pa@example.com")
+
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q")
+	return repo
+}
+
+// gitCommit commits the files with the author date.
+func gitCommit(t *testing.T, repo, date string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		writeFile(t, filepath.Join(repo, name), content)
+	}
+	runGit(t, repo, "add", "-A")
+	t.Setenv("GIT_AUTHOR_DATE", date)
+	t.Setenv("GIT_COMMITTER_DATE", date)
+	runGit(t, repo, "commit", "-q", "-m", "commit at "+date)
+}
+
+func runGit(t *testing.T, repo string, args ...string) {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func executeGit(t *testing.T, f *pixelaClientFactory, args ...string) (string, error) {
+	t.Helper()
+	cmd := newCmdRoot(f)
+	buffer := bytes.NewBuffer([]byte{})
+	cmd.SetOut(buffer)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return buffer.String(), err
+}
+
+func TestParseGitLog(t *testing.T) {
+	out := "@@pa 20260101\n" +
+		"\n" +
+		"3\t1\tmain.go\n" +
+		"-\t-\timage.png\n" +
+		"@@pa 20260102\n" +
+		"\n" +
+		"10\t0\tREADME.md\n" +
+		"@@pa 20260101\n" +
+		"@@pa 20260101\n" +
+		"\n" +
+		"2\t2\tmain.go\n"
+
+	stats, err := parseGitLog(out)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*gitStats{
+		"20260101": {Commits: 3, LinesAdded: 5, Files: 3},
+		"20260102": {Commits: 1, LinesAdded: 10, Files: 1},
+	}, stats)
+
+	_, err = parseGitLog("@@pa 20260101\n\nunexpected\n")
+	assert.EqualError(t, err, "unexpected git log line: unexpected")
+}
+
+func TestGitBackfill(t *testing.T) {
+	repo := setupGitRepo(t)
+	gitCommit(t, repo, "2025-12-31T10:00:00+09:00", map[string]string{"a.txt": "1\n"})
+	gitCommit(t, repo, "2026-01-01T10:00:00+09:00", map[string]string{"a.txt": "1\n2\n", "b.txt": "1\n2\n3\n"})
+	gitCommit(t, repo, "2026-01-01T18:00:00+09:00", map[string]string{"c.txt": "1\n"})
+	gitCommit(t, repo, "2026-01-03T10:00:00+09:00", map[string]string{"a.txt": "1\n"})
+
+	params := []struct {
+		metric   string
+		expected map[string]string
+	}{
+		{metric: "commits", expected: map[string]string{"20260101": "2", "20260103": "1"}},
+		{metric: "lines-added", expected: map[string]string{"20260101": "5", "20260103": "0"}},
+		{metric: "files", expected: map[string]string{"20260101": "3", "20260103": "1"}},
+	}
+
+	for _, p := range params {
+		fake := newPixelaFake()
+		f := fake.factory()
+		fake.addGraph(pixela.GraphDefinition{ID: "commits"})
+
+		out, err := executeGit(t, f, "git", "backfill", "--repo", repo, "--graph", "commits", "--metric", p.metric, "--since", "2026-01-01T00:00:00+09:00")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"graph":"commits","dates":2,"updated":2,"failed":[]}`+"\n", out)
+
+		actual := map[string]string{}
+		for _, px := range fake.sortedPixels("commits") {
+			actual[px.Date] = px.Quantity
+		}
+		assert.Equal(t, p.expected, actual, p.metric)
+	}
+}
+
+func TestGitRecord(t *testing.T) {
+	repo := setupGitRepo(t)
+	gitCommit(t, repo, "2026-01-01T10:00:00+09:00", map[string]string{"a.txt": "1\n"})
+	gitCommit(t, repo, "2026-01-02T10:00:00+09:00", map[string]string{"a.txt": "1\n2\n3\n", "b.txt": "1\n"})
+
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "commits"})
+
+	for _, metric := range []string{"commits", "lines-added", "files"} {
+		_, err := executeGit(t, f, "git", "record", "--repo", repo, "--graph", "commits", "--metric", metric)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"commits:increment", "commits:3", "commits:2"}, fake.added)
+
+	_, err := executeGit(t, f, "git", "record", "--repo", repo, "--graph", "commits", "--metric", "words")
+	assert.EqualError(t, err, "unsupported metric: words")
+
+	_, err = executeGit(t, f, "git", "record", "--repo", t.TempDir(), "--graph", "commits")
+	assert.True(t, strings.HasPrefix(err.Error(), "git log failed: "))
+}
@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/ebc-2in2crc/pa/pkg/pa"
 	pixela "github.com/ebc-2in2crc/pixela4go"
 	"github.com/spf13/cobra"
 )
 
-var graphOptions = &struct {
+type graphOptions struct {
 	ID                  string
 	Name                string
 	Unit                string
@@ -31,10 +33,26 @@ var graphOptions = &struct {
 	WithBody            bool
 	Quantity            string
 	StartOnMonday       bool
-}{}
+	NewID               string
+	WithPixels          bool
+	ToProfile           string
+	Against             string
+	AgainstFile         string
+	Sources             []string
+	Target              string
+	Func                string
+	Incremental         bool
+	Where               []string
+	Sort                string
+	Limit               int
+	Interval            time.Duration
+	NDJSON              bool
+	Count               int
+	Force               bool
+}
 
 // NewCmdGraph creates a graph command.
-func NewCmdGraph() *cobra.Command {
+func NewCmdGraph(f *pixelaClientFactory) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "graph",
 		Short: "Graph",
@@ -42,32 +60,38 @@ func NewCmdGraph() *cobra.Command {
 		RunE:  showHelp,
 	}
 
-	cmd.AddCommand(NewCmdGraphCreate())
-	cmd.AddCommand(NewCmdGraphGetAll())
-	cmd.AddCommand(NewCmdGraphGet())
-	cmd.AddCommand(NewCmdGraphGetSVG())
-	cmd.AddCommand(NewCmdGraphURL())
-	cmd.AddCommand(NewCmdGraphStats())
-	cmd.AddCommand(NewCmdGraphUpdate())
-	cmd.AddCommand(NewCmdGraphDelete())
-	cmd.AddCommand(NewCmdGraphGetPixelDates())
-	cmd.AddCommand(NewCmdGraphStopwatch())
-	cmd.AddCommand(NewCmdGraphAdd())
-	cmd.AddCommand(NewCmdGraphSubtract())
-	cmd.AddCommand(NewCmdGraphGetLatestPixel())
+	cmd.AddCommand(NewCmdGraphCreate(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphGetAll(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphGet(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphGetSVG(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphURL(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphStats(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphUpdate(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphDelete(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphGetPixelDates(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphStopwatch(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphAdd(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphSubtract(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphGetLatestPixel(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphClone(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphRename(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphExport(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphDiff(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphMerge(f, &graphOptions{}))
+	cmd.AddCommand(NewCmdGraphWatch(f, &graphOptions{}))
 
 	return cmd
 }
 
 // NewCmdGraphCreate creates a create graph command.
-func NewCmdGraphCreate() *cobra.Command {
+func NewCmdGraphCreate(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create Graph",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphCreateInput()
-			result, err := pixelaClient.Graph().Create(input)
+			input := createGraphCreateInput(o)
+			result, err := f.Graph().CreateWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("graph create failed: %w", err)
 			}
@@ -84,43 +108,43 @@ func NewCmdGraphCreate() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
-	cmd.Flags().StringVar(&graphOptions.Name, "name", "", "The name of the pixelation graph")
-	cmd.Flags().StringVar(&graphOptions.Unit, "unit", "", "A Unit of the quantity recorded in the pixelation graph")
-	cmd.Flags().StringVar(&graphOptions.Type, "type", "", "The type of quantity to be handled in the graph")
-	cmd.Flags().StringVar(&graphOptions.Color, "color", "", "Defines the display color of the pixel in the pixelation graph")
-	cmd.Flags().StringVar(&graphOptions.TimeZone, "timezone", "", "The timezone for handling this graph")
-	cmd.Flags().StringVar(&graphOptions.SelfSufficient, "self-sufficient", "", "See: https://docs.pixe.la/entry/post-graph")
-	cmd.Flags().BoolVar(&graphOptions.IsSecret, "secret", false, "The Graph not displayed on the graph list page")
-	cmd.Flags().BoolVar(&graphOptions.PublishOptionalData, "publish-optional-data", false, "Each pixel's optionalData will be added to the generated SVG data")
-	cmd.Flags().BoolVar(&graphOptions.StartOnMonday, "start-on-monday", false, "The week starts on Monday")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.Name, "name", "", "The name of the pixelation graph")
+	cmd.Flags().StringVar(&o.Unit, "unit", "", "A Unit of the quantity recorded in the pixelation graph")
+	cmd.Flags().StringVar(&o.Type, "type", "", "The type of quantity to be handled in the graph")
+	cmd.Flags().StringVar(&o.Color, "color", "", "Defines the display color of the pixel in the pixelation graph")
+	cmd.Flags().StringVar(&o.TimeZone, "timezone", "", "The timezone for handling this graph")
+	cmd.Flags().StringVar(&o.SelfSufficient, "self-sufficient", "", "See: https://docs.pixe.la/entry/post-graph")
+	cmd.Flags().BoolVar(&o.IsSecret, "secret", false, "The Graph not displayed on the graph list page")
+	cmd.Flags().BoolVar(&o.PublishOptionalData, "publish-optional-data", false, "Each pixel's optionalData will be added to the generated SVG data")
+	cmd.Flags().BoolVar(&o.StartOnMonday, "start-on-monday", false, "The week starts on Monday")
 
 	return cmd
 }
 
-func createGraphCreateInput() *pixela.GraphCreateInput {
+func createGraphCreateInput(o *graphOptions) *pixela.GraphCreateInput {
 	return &pixela.GraphCreateInput{
-		ID:                  getStringPtr(graphOptions.ID),
-		Name:                getStringPtr(graphOptions.Name),
-		Unit:                getStringPtr(graphOptions.Unit),
-		Type:                getStringPtr(graphOptions.Type),
-		Color:               getStringPtr(graphOptions.Color),
-		TimeZone:            getStringPtr(graphOptions.TimeZone),
-		SelfSufficient:      getStringPtr(graphOptions.SelfSufficient),
-		IsSecret:            getBoolPtr(graphOptions.IsSecret),
-		PublishOptionalData: getBoolPtr(graphOptions.PublishOptionalData),
-		StartOnMonday:       getBoolPtr(graphOptions.StartOnMonday),
+		ID:                  getStringPtr(o.ID),
+		Name:                getStringPtr(o.Name),
+		Unit:                getStringPtr(o.Unit),
+		Type:                getStringPtr(o.Type),
+		Color:               getStringPtr(o.Color),
+		TimeZone:            getStringPtr(o.TimeZone),
+		SelfSufficient:      getStringPtr(o.SelfSufficient),
+		IsSecret:            getBoolPtr(o.IsSecret),
+		PublishOptionalData: getBoolPtr(o.PublishOptionalData),
+		StartOnMonday:       getBoolPtr(o.StartOnMonday),
 	}
 }
 
 // NewCmdGraphGetAll creates a get all graph command.
-func NewCmdGraphGetAll() *cobra.Command {
+func NewCmdGraphGetAll(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get-all",
 		Short: "Get all Graph definitions",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			definitions, err := pixelaClient.Graph().GetAll()
+			definitions, err := f.Graph().GetAllWithContext(f.Context())
 			if err != nil {
 				return fmt.Errorf("graph get all failed: %w", err)
 			}
@@ -133,9 +157,9 @@ func NewCmdGraphGetAll() *cobra.Command {
 				return ErrNeglect
 			}
 
-			defs := make([]graphDefinition, len(definitions.Graphs))
+			defs := make([]pa.GraphDefinition, len(definitions.Graphs))
 			for i, v := range definitions.Graphs {
-				defs[i] = gToG(&v)
+				defs[i] = pa.NewGraphDefinition(&v)
 			}
 
 			b, err := json.Marshal(&graphDefinitions{Graphs: defs})
@@ -152,46 +176,18 @@ func NewCmdGraphGetAll() *cobra.Command {
 }
 
 type graphDefinitions struct {
-	Graphs []graphDefinition `json:"graphs"`
-}
-
-type graphDefinition struct {
-	ID                  string   `json:"id"`
-	Name                string   `json:"name"`
-	Unit                string   `json:"unit"`
-	Type                string   `json:"type"`
-	Color               string   `json:"color"`
-	TimeZone            string   `json:"timezone"`
-	PurgeCacheURLs      []string `json:"purgeCacheURLs"`
-	SelfSufficient      string   `json:"selfSufficient"`
-	IsSecret            bool     `json:"isSecret"`
-	PublishOptionalData bool     `json:"publishOptionalData"`
-}
-
-func gToG(g *pixela.GraphDefinition) graphDefinition {
-	return graphDefinition{
-		ID:                  g.ID,
-		Name:                g.Name,
-		Unit:                g.Unit,
-		Type:                g.Type,
-		Color:               g.Color,
-		TimeZone:            g.TimeZone,
-		PurgeCacheURLs:      g.PurgeCacheURLs,
-		SelfSufficient:      g.SelfSufficient,
-		IsSecret:            g.IsSecret,
-		PublishOptionalData: g.PublishOptionalData,
-	}
+	Graphs []pa.GraphDefinition `json:"graphs"`
 }
 
 // NewCmdGraphGet creates a get graph command.
-func NewCmdGraphGet() *cobra.Command {
+func NewCmdGraphGet(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get",
 		Short: "Get Graph definition",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphGetInput()
-			result, err := pixelaClient.Graph().Get(input)
+			input := createGraphGetInput(o)
+			result, err := f.Graph().GetWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("graph get failed: %w", err)
 			}
@@ -204,7 +200,7 @@ func NewCmdGraphGet() *cobra.Command {
 				return ErrNeglect
 			}
 
-			g := gToG(result)
+			g := pa.NewGraphDefinition(result)
 			b, err := json.Marshal(g)
 			if err != nil {
 				return fmt.Errorf("marshal graph get definition failed: %w", err)
@@ -215,27 +211,27 @@ func NewCmdGraphGet() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("id")
 
 	return cmd
 }
 
-func createGraphGetInput() *pixela.GraphGetInput {
+func createGraphGetInput(o *graphOptions) *pixela.GraphGetInput {
 	return &pixela.GraphGetInput{
-		ID: getStringPtr(graphOptions.ID),
+		ID: getStringPtr(o.ID),
 	}
 }
 
 // NewCmdGraphGetSVG creates a get graph svg command.
-func NewCmdGraphGetSVG() *cobra.Command {
+func NewCmdGraphGetSVG(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "svg",
 		Short: "Get the Graph in SVG format diagram",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphGetSVGInput()
-			result, err := pixelaClient.Graph().GetSVG(input)
+			input := createGraphGetSVGInput(o)
+			result, err := f.Graph().GetSVGWithContext(f.Context(), input)
 			if err != nil {
 				e := err.Error()
 				s := e[strings.LastIndex(e, `{"message"`):]
@@ -248,62 +244,62 @@ func NewCmdGraphGetSVG() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("id")
-	cmd.Flags().StringVar(&graphOptions.Date, "date", "", "Create a pixelation graph dating back to the past with that day as the start date")
-	cmd.Flags().StringVar(&graphOptions.Mode, "mode", "", "The Graph display mode")
-	cmd.Flags().StringVar(&graphOptions.Appearance, "appearance", "", "The graph appearance mode")
+	cmd.Flags().StringVar(&o.Date, "date", "", "Create a pixelation graph dating back to the past with that day as the start date")
+	cmd.Flags().StringVar(&o.Mode, "mode", "", "The Graph display mode")
+	cmd.Flags().StringVar(&o.Appearance, "appearance", "", "The graph appearance mode")
 
 	return cmd
 }
 
-func createGraphGetSVGInput() *pixela.GraphGetSVGInput {
+func createGraphGetSVGInput(o *graphOptions) *pixela.GraphGetSVGInput {
 	return &pixela.GraphGetSVGInput{
-		ID:         getStringPtr(graphOptions.ID),
-		Date:       getStringPtr(graphOptions.Date),
-		Mode:       getStringPtr(graphOptions.Mode),
-		Appearance: getStringPtr(graphOptions.Appearance),
+		ID:         getStringPtr(o.ID),
+		Date:       getStringPtr(o.Date),
+		Mode:       getStringPtr(o.Mode),
+		Appearance: getStringPtr(o.Appearance),
 	}
 }
 
 // NewCmdGraphURL creates a graph URL command.
-func NewCmdGraphURL() *cobra.Command {
+func NewCmdGraphURL(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "detail",
 		Short: "Get Graph detail URL",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphURLInput()
-			url := pixelaClient.Graph().URL(input)
+			input := createGraphURLInput(o)
+			url := f.Graph().URL(input)
 			cmd.Printf("%s\n", url)
 
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("id")
-	cmd.Flags().StringVar(&graphOptions.Mode, "mode", "", "The graph html page mode")
+	cmd.Flags().StringVar(&o.Mode, "mode", "", "The graph html page mode")
 
 	return cmd
 }
 
-func createGraphURLInput() *pixela.GraphURLInput {
+func createGraphURLInput(o *graphOptions) *pixela.GraphURLInput {
 	return &pixela.GraphURLInput{
-		ID:   getStringPtr(graphOptions.ID),
-		Mode: getStringPtr(graphOptions.Mode),
+		ID:   getStringPtr(o.ID),
+		Mode: getStringPtr(o.Mode),
 	}
 }
 
 // NewCmdGraphStats creates a graphs stats command.
-func NewCmdGraphStats() *cobra.Command {
+func NewCmdGraphStats(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "stats",
 		Short: "Get various statistics",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphStatsInput()
-			stats, err := pixelaClient.Graph().Stats(input)
+			input := createGraphStatsInput(o)
+			stats, err := f.Graph().StatsWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("graph stats failed: %w", err)
 			}
@@ -336,15 +332,15 @@ func NewCmdGraphStats() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("id")
 
 	return cmd
 }
 
-func createGraphStatsInput() *pixela.GraphStatsInput {
+func createGraphStatsInput(o *graphOptions) *pixela.GraphStatsInput {
 	return &pixela.GraphStatsInput{
-		ID: getStringPtr(graphOptions.ID),
+		ID: getStringPtr(o.ID),
 	}
 }
 
@@ -361,14 +357,18 @@ type graphStats struct {
 }
 
 // NewCmdGraphUpdate creates a update graphs command.
-func NewCmdGraphUpdate() *cobra.Command {
+func NewCmdGraphUpdate(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "update",
 		Short: "Update Graph",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphUpdateInput()
-			result, err := pixelaClient.Graph().Update(input)
+			input := createGraphUpdateInput(o)
+			before, err := graphBefore(f, o.ID)
+			if err != nil {
+				return reportError(cmd, err, "graph update failed")
+			}
+			result, err := f.Graph().UpdateWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("graph update failed: %w", err)
 			}
@@ -381,56 +381,56 @@ func NewCmdGraphUpdate() *cobra.Command {
 			if !result.IsSuccess {
 				return ErrNeglect
 			}
-			return nil
+			return recordJournal(f, &journalEntry{Operation: "graph update", GraphID: o.ID, Graph: before})
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("id")
-	cmd.Flags().StringVar(&graphOptions.Name, "name", "", "The name of the pixelation graph")
-	cmd.Flags().StringVar(&graphOptions.Unit, "unit", "", "A Unit of the quantity recorded in the pixelation graph")
-	cmd.Flags().StringVar(&graphOptions.Color, "color", "", "Defines the display color of the pixel in the pixelation graph")
-	cmd.Flags().StringVar(&graphOptions.TimeZone, "timezone", "", "The timezone for handling this graph")
-	cmd.Flags().StringSliceVar(&graphOptions.PurgeCacheURLs, "purge-cache-urls", []string{}, "URL to send the purge request to purge the cache when the graph is updated")
-	cmd.Flags().StringVar(&graphOptions.SelfSufficient, "self-sufficient", "", "See: https://docs.pixe.la/entry/put-graph")
-	cmd.Flags().BoolVar(&graphOptions.IsSecret, "secret", false, "The Graph not displayed on the graph list page")
-	cmd.Flags().BoolVar(&graphOptions.IsPublish, "publish", false, "The Graph displayed on the graph list page")
-	cmd.Flags().BoolVar(&graphOptions.PublishOptionalData, "publish-optional-data", false, "Each pixel's optionalData will be added to the generated SVG data")
-	cmd.Flags().BoolVar(&graphOptions.HideOptionalData, "hide-optional-data", false, "Each pixel's optionalData will not be added to the generated SVG data")
-	cmd.Flags().BoolVar(&graphOptions.StartOnMonday, "start-on-monday", false, "The week starts on Monday")
+	cmd.Flags().StringVar(&o.Name, "name", "", "The name of the pixelation graph")
+	cmd.Flags().StringVar(&o.Unit, "unit", "", "A Unit of the quantity recorded in the pixelation graph")
+	cmd.Flags().StringVar(&o.Color, "color", "", "Defines the display color of the pixel in the pixelation graph")
+	cmd.Flags().StringVar(&o.TimeZone, "timezone", "", "The timezone for handling this graph")
+	cmd.Flags().StringSliceVar(&o.PurgeCacheURLs, "purge-cache-urls", []string{}, "URL to send the purge request to purge the cache when the graph is updated")
+	cmd.Flags().StringVar(&o.SelfSufficient, "self-sufficient", "", "See: https://docs.pixe.la/entry/put-graph")
+	cmd.Flags().BoolVar(&o.IsSecret, "secret", false, "The Graph not displayed on the graph list page")
+	cmd.Flags().BoolVar(&o.IsPublish, "publish", false, "The Graph displayed on the graph list page")
+	cmd.Flags().BoolVar(&o.PublishOptionalData, "publish-optional-data", false, "Each pixel's optionalData will be added to the generated SVG data")
+	cmd.Flags().BoolVar(&o.HideOptionalData, "hide-optional-data", false, "Each pixel's optionalData will not be added to the generated SVG data")
+	cmd.Flags().BoolVar(&o.StartOnMonday, "start-on-monday", false, "The week starts on Monday")
 
 	return cmd
 }
 
-func createGraphUpdateInput() *pixela.GraphUpdateInput {
+func createGraphUpdateInput(o *graphOptions) *pixela.GraphUpdateInput {
 	var secret *bool
-	if graphOptions.IsPublish {
+	if o.IsPublish {
 		secret = pixela.Bool(false)
 	}
-	if graphOptions.IsSecret {
+	if o.IsSecret {
 		secret = pixela.Bool(true)
 	}
 
 	var publishOptionalData *bool
-	if graphOptions.PublishOptionalData {
+	if o.PublishOptionalData {
 		publishOptionalData = pixela.Bool(true)
 	}
-	if graphOptions.HideOptionalData {
+	if o.HideOptionalData {
 		publishOptionalData = pixela.Bool(false)
 	}
 	var startOnMonday *bool
-	if graphOptions.StartOnMonday {
+	if o.StartOnMonday {
 		startOnMonday = pixela.Bool(true)
 	}
 
 	return &pixela.GraphUpdateInput{
-		ID:                  getStringPtr(graphOptions.ID),
-		Name:                getStringPtr(graphOptions.Name),
-		Unit:                getStringPtr(graphOptions.Unit),
-		Color:               getStringPtr(graphOptions.Color),
-		TimeZone:            getStringPtr(graphOptions.TimeZone),
-		PurgeCacheURLs:      graphOptions.PurgeCacheURLs,
-		SelfSufficient:      getStringPtr(graphOptions.SelfSufficient),
+		ID:                  getStringPtr(o.ID),
+		Name:                getStringPtr(o.Name),
+		Unit:                getStringPtr(o.Unit),
+		Color:               getStringPtr(o.Color),
+		TimeZone:            getStringPtr(o.TimeZone),
+		PurgeCacheURLs:      o.PurgeCacheURLs,
+		SelfSufficient:      getStringPtr(o.SelfSufficient),
 		IsSecret:            secret,
 		PublishOptionalData: publishOptionalData,
 		StartOnMonday:       startOnMonday,
@@ -438,19 +438,29 @@ func createGraphUpdateInput() *pixela.GraphUpdateInput {
 }
 
 // NewCmdGraphDelete creates a delete graphs command.
-func NewCmdGraphDelete() *cobra.Command {
+func NewCmdGraphDelete(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "delete",
 		Short: "Delete a Graph",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !graphOptions.DeleteMe {
+			if !o.DeleteMe {
 				cmd.Println("Specify the '--delete-me' flag to confirm the deletion.")
 				return nil
 			}
+			if err := checkProtectedGraph(f, o.ID, "delete", o.Force); err != nil {
+				return err
+			}
+			if err := confirmByTyping(cmd, f, "graph ID", o.ID); err != nil {
+				return err
+			}
+			// 削除する前に Pixel を退避しておく
+			if err := archiveGraph(cmd, f, o.ID); err != nil {
+				return reportError(cmd, err, "graph delete failed")
+			}
 
-			input := createGraphDeleteInput()
-			result, err := pixelaClient.Graph().Delete(input)
+			input := createGraphDeleteInput(o)
+			result, err := f.Graph().DeleteWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("graph delete failed: %w", err)
 			}
@@ -467,30 +477,40 @@ func NewCmdGraphDelete() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("id")
 
 	// グラフの削除は非常に危険なので `--delete-me` フラグが指定したときだけ削除する
-	cmd.Flags().BoolVarP(&graphOptions.DeleteMe, "delete-me", "", false, "Delete your Graph")
+	cmd.Flags().BoolVarP(&o.DeleteMe, "delete-me", "", false, "Delete your Graph")
+	cmd.Flags().BoolVar(&o.Force, "force", false, "Delete the Graph even if it is protected by protected_graphs")
 
 	return cmd
 }
 
-func createGraphDeleteInput() *pixela.GraphDeleteInput {
+func createGraphDeleteInput(o *graphOptions) *pixela.GraphDeleteInput {
 	return &pixela.GraphDeleteInput{
-		ID: getStringPtr(graphOptions.ID),
+		ID: getStringPtr(o.ID),
 	}
 }
 
 // NewCmdGraphGetPixelDates creates a get pixel dates command.
-func NewCmdGraphGetPixelDates() *cobra.Command {
+func NewCmdGraphGetPixelDates(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "pixels",
 		Short: "Get a Date list of Pixel registered",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphGetPixelDatesInput()
-			dates, err := pixelaClient.Graph().GetPixelDates(input)
+			query, err := newPixelQuery(o.Where, o.Sort, o.Limit)
+			if err != nil {
+				return fmt.Errorf("invalid query: %w", err)
+			}
+
+			input := createGraphGetPixelDatesInput(o)
+			if query != nil {
+				// 絞り込みには quantity や optionalData が必要なので常に body を取得する
+				input.WithBody = pixela.Bool(true)
+			}
+			dates, err := f.Graph().GetPixelDatesWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("graph get pixel dates failed: %w", err)
 			}
@@ -504,7 +524,20 @@ func NewCmdGraphGetPixelDates() *cobra.Command {
 				return ErrNeglect
 			}
 
-			b, err := marshalPixels(dates.Pixels, graphOptions.WithBody)
+			datePixels := dates.Pixels
+			if query != nil {
+				p, ok := dates.Pixels.([]pixela.PixelWithBody)
+				if !ok {
+					return fmt.Errorf("type assertion failed: %T", dates.Pixels)
+				}
+				p = query.apply(p)
+				datePixels = p
+				if !o.WithBody {
+					datePixels = pixelDates(p)
+				}
+			}
+
+			b, err := marshalPixels(datePixels, o.WithBody)
 			if err != nil {
 				return fmt.Errorf("marshal graph get pixel dates failed: %w", err)
 			}
@@ -514,21 +547,24 @@ func NewCmdGraphGetPixelDates() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("id")
-	cmd.Flags().StringVar(&graphOptions.From, "from", "", "The start position of the period")
-	cmd.Flags().StringVar(&graphOptions.To, "to", "", "The end position of the period")
-	cmd.Flags().BoolVar(&graphOptions.WithBody, "with-body", false, "Get all the information the Pixel has")
+	cmd.Flags().StringVar(&o.From, "from", "", "The start position of the period")
+	cmd.Flags().StringVar(&o.To, "to", "", "The end position of the period")
+	cmd.Flags().BoolVar(&o.WithBody, "with-body", false, "Get all the information the Pixel has")
+	cmd.Flags().StringArrayVar(&o.Where, "where", []string{}, "Filter the Pixels by quantity, date or optionalData key (e.g. 'tag == \"deep-work\"', 'quantity > 30')")
+	cmd.Flags().StringVar(&o.Sort, "sort", "", "Sort the Pixels by the field (e.g. 'quantity desc')")
+	cmd.Flags().IntVar(&o.Limit, "limit", 0, "Maximum number of the Pixels to output")
 
 	return cmd
 }
 
-func createGraphGetPixelDatesInput() *pixela.GraphGetPixelDatesInput {
+func createGraphGetPixelDatesInput(o *graphOptions) *pixela.GraphGetPixelDatesInput {
 	return &pixela.GraphGetPixelDatesInput{
-		ID:       getStringPtr(graphOptions.ID),
-		From:     getStringPtr(graphOptions.From),
-		To:       getStringPtr(graphOptions.To),
-		WithBody: getBoolPtr(graphOptions.WithBody),
+		ID:       getStringPtr(o.ID),
+		From:     getStringPtr(o.From),
+		To:       getStringPtr(o.To),
+		WithBody: getBoolPtr(o.WithBody),
 	}
 }
 
@@ -565,14 +601,14 @@ type pixels struct {
 }
 
 // NewCmdGraphStopwatch creates a graph stopwatch command.
-func NewCmdGraphStopwatch() *cobra.Command {
+func NewCmdGraphStopwatch(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "stopwatch",
 		Short: "Start and end the measurement of the time",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphStopwatchInput()
-			result, err := pixelaClient.Graph().Stopwatch(input)
+			input := createGraphStopwatchInput(o)
+			result, err := f.Graph().StopwatchWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("graph stopwatch failed: %w", err)
 			}
@@ -589,27 +625,27 @@ func NewCmdGraphStopwatch() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("id")
 
 	return cmd
 }
 
-func createGraphStopwatchInput() *pixela.GraphStopwatchInput {
+func createGraphStopwatchInput(o *graphOptions) *pixela.GraphStopwatchInput {
 	return &pixela.GraphStopwatchInput{
-		ID: getStringPtr(graphOptions.ID),
+		ID: getStringPtr(o.ID),
 	}
 }
 
 // NewCmdGraphAdd creates a add graph command.
-func NewCmdGraphAdd() *cobra.Command {
+func NewCmdGraphAdd(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "add",
 		Short: "Add quantity to the Pixel of the day",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphAddInput()
-			result, err := pixelaClient.Graph().Add(input)
+			input := createGraphAddInput(o)
+			result, err := f.Graph().AddWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("graph add failed: %w", err)
 			}
@@ -626,28 +662,28 @@ func NewCmdGraphAdd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
-	cmd.Flags().StringVar(&graphOptions.Quantity, "quantity", "", "The quantity to be added to the pixel of the day")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.Quantity, "quantity", "", "The quantity to be added to the pixel of the day")
 
 	return cmd
 }
 
-func createGraphAddInput() *pixela.GraphAddInput {
+func createGraphAddInput(o *graphOptions) *pixela.GraphAddInput {
 	return &pixela.GraphAddInput{
-		ID:       getStringPtr(graphOptions.ID),
-		Quantity: getStringPtr(graphOptions.Quantity),
+		ID:       getStringPtr(o.ID),
+		Quantity: getStringPtr(o.Quantity),
 	}
 }
 
 // NewCmdGraphSubtract creates a add graph command.
-func NewCmdGraphSubtract() *cobra.Command {
+func NewCmdGraphSubtract(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "subtract",
 		Short: "Subtract quantity from the Pixel of the day",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphSubtractInput()
-			result, err := pixelaClient.Graph().Subtract(input)
+			input := createGraphSubtractInput(o)
+			result, err := f.Graph().SubtractWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("graph subtract failed: %w", err)
 			}
@@ -664,28 +700,28 @@ func NewCmdGraphSubtract() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
-	cmd.Flags().StringVar(&graphOptions.Quantity, "quantity", "", "The quantity to be subtracted from the pixel of the day")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.Quantity, "quantity", "", "The quantity to be subtracted from the pixel of the day")
 
 	return cmd
 }
 
-func createGraphSubtractInput() *pixela.GraphSubtractInput {
+func createGraphSubtractInput(o *graphOptions) *pixela.GraphSubtractInput {
 	return &pixela.GraphSubtractInput{
-		ID:       getStringPtr(graphOptions.ID),
-		Quantity: getStringPtr(graphOptions.Quantity),
+		ID:       getStringPtr(o.ID),
+		Quantity: getStringPtr(o.Quantity),
 	}
 }
 
 // NewCmdGraphGetLatestPixel creates a get latest pixel command.
-func NewCmdGraphGetLatestPixel() *cobra.Command {
+func NewCmdGraphGetLatestPixel(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get-latest-pixel",
 		Short: "Get the latest Pixel",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createGraphGetLatestPixelInput()
-			pixel, err := pixelaClient.Graph().GetLatestPixel(input)
+			input := createGraphGetLatestPixelInput(o)
+			pixel, err := f.Graph().GetLatestPixelWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("graph get latest pixel failed: %w", err)
 			}
@@ -712,15 +748,15 @@ func NewCmdGraphGetLatestPixel() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&graphOptions.ID, "id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("id")
 
 	return cmd
 }
 
-func createGraphGetLatestPixelInput() *pixela.GraphGetLatestPixelInput {
+func createGraphGetLatestPixelInput(o *graphOptions) *pixela.GraphGetLatestPixelInput {
 	return &pixela.GraphGetLatestPixelInput{
-		ID: getStringPtr(graphOptions.ID),
+		ID: getStringPtr(o.ID),
 	}
 }
 
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ebc-2in2crc/pa/pkg/pa"
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdGraphClone creates a clone graph command.
+func NewCmdGraphClone(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clone",
+		Short: "Clone a Graph definition and its Pixels",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dst := f
+			if o.ToProfile != "" {
+				p, err := f.Profile(o.ToProfile)
+				if err != nil {
+					return fmt.Errorf("graph clone failed: %w", err)
+				}
+				dst = p
+			}
+
+			result, cloneErr := f.Client().CloneGraph(f.Context(), dst.Client(), o.ID, o.NewID, cloneOptions(cmd, o, o.WithPixels))
+			if result == nil {
+				return reportError(cmd, cloneErr, "graph clone failed")
+			}
+
+			b, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("marshal graph clone result failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+
+			// キャンセルされたときや Pixel のコピーに失敗したときはそれまでの結果を出力して終了する
+			if cloneErr != nil {
+				if f.Context().Err() != nil {
+					return fmt.Errorf("graph clone cancelled: %w", cloneErr)
+				}
+				return fmt.Errorf("graph clone failed: %w", cloneErr)
+			}
+
+			if len(result.Failed) > 0 {
+				return ErrNeglect
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the source pixelation graph")
+	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVar(&o.NewID, "new-id", "", "ID for identifying the new pixelation graph")
+	_ = cmd.MarkFlagRequired("new-id")
+	cmd.Flags().BoolVar(&o.WithPixels, "with-pixels", false, "Copy the Pixels of the source graph")
+	cmd.Flags().StringVar(&o.ToProfile, "to-profile", "", "Create the new graph on the account of the profile in the config file")
+	cmd.Flags().StringVar(&o.From, "from", "", "The start position of the period of the Pixels to copy (default 20000101)")
+	cmd.Flags().StringVar(&o.To, "to", "", "The end position of the period of the Pixels to copy (default today)")
+	cmd.Flags().BoolVar(&o.StartOnMonday, "start-on-monday", false, "The week of the new graph starts on Monday, which is not copied from the source graph")
+
+	return cmd
+}
+
+// cloneOptions returns the options to clone the Pixels between --from and --to with the progress on stderr.
+func cloneOptions(cmd *cobra.Command, o *graphOptions, withPixels bool) pa.CloneOptions {
+	return pa.CloneOptions{
+		WithPixels:    withPixels,
+		From:          o.From,
+		To:            o.To,
+		StartOnMonday: o.StartOnMonday,
+		Progress: func(i, n int, date string, result *pixela.Result) {
+			if !result.IsSuccess {
+				fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] %s failed: %s\n", i, n, date, result.Message)
+				return
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] %s copied\n", i, n, date)
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphClone(t *testing.T) {
+	params := []struct {
+		commandline    string
+		failDates      []string
+		expected       string
+		expectedError  error
+		expectedPixels []pixela.PixelWithBody
+	}{
+		{
+			commandline:    "graph clone --id=src --new-id=dst",
+			expected:       `{"id":"dst","pixels":0,"copied":0,"failed":[]}` + "\n",
+			expectedPixels: []pixela.PixelWithBody{},
+		},
+		{
+			commandline: "graph clone --id=src --new-id=dst --with-pixels --from=20200101 --to=20211231",
+			expected:    `{"id":"dst","pixels":2,"copied":2,"failed":[]}` + "\n",
+			expectedPixels: []pixela.PixelWithBody{
+				{Date: "20200101", Quantity: "1", OptionalData: `{"key":"value"}`},
+				{Date: "20210101", Quantity: "2"},
+			},
+		},
+		{
+			commandline:   "graph clone --id=src --new-id=dst --with-pixels --from=20200101 --to=20211231",
+			failDates:     []string{"20210101"},
+			expected:      `{"id":"dst","pixels":2,"copied":1,"failed":["20210101"]}` + "\n",
+			expectedError: ErrNeglect,
+			expectedPixels: []pixela.PixelWithBody{
+				{Date: "20200101", Quantity: "1", OptionalData: `{"key":"value"}`},
+			},
+		},
+		{
+			commandline:   "graph clone --id=not-exist --new-id=dst",
+			expected:      `{"message":"Specified graphID not exist.","isSuccess":false,"isRejected":false,"statusCode":404}` + "\n",
+			expectedError: ErrNeglect,
+		},
+	}
+
+	for _, p := range params {
+		fake := newPixelaFake()
+		fake.addGraph(
+			pixela.GraphDefinition{
+				ID: "src", Name: "name", Unit: "commit", Type: "int", Color: "shibafu",
+				TimeZone: "Asia/Tokyo", SelfSufficient: "increment", IsSecret: true,
+				PurgeCacheURLs: []string{"https://example.com"},
+			},
+			pixela.PixelWithBody{Date: "20200101", Quantity: "1", OptionalData: `{"key":"value"}`},
+			pixela.PixelWithBody{Date: "20210101", Quantity: "2"},
+			pixela.PixelWithBody{Date: "20220101", Quantity: "3"},
+		)
+		for _, d := range p.failDates {
+			fake.failDates[d] = true
+		}
+		f := fake.factory()
+
+		cmd := newCmdRoot(f)
+		buffer := bytes.NewBuffer([]byte{})
+		cmd.SetOut(buffer)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(strings.Split(p.commandline, " "))
+
+		err := cmd.Execute()
+
+		assert.Equal(t, p.expectedError, err)
+		assert.Equal(t, p.expected, buffer.String())
+		if p.expectedPixels == nil {
+			continue
+		}
+
+		dst := fake.definitions["dst"]
+		assert.Equal(t, "name", dst.Name)
+		assert.Equal(t, "commit", dst.Unit)
+		assert.Equal(t, "int", dst.Type)
+		assert.Equal(t, "shibafu", dst.Color)
+		assert.Equal(t, "Asia/Tokyo", dst.TimeZone)
+		assert.Equal(t, "increment", dst.SelfSufficient)
+		assert.Equal(t, true, dst.IsSecret)
+		assert.Equal(t, []string{"https://example.com"}, dst.PurgeCacheURLs)
+		assert.Equal(t, p.expectedPixels, fake.sortedPixels("dst"))
+	}
+}
+
+func TestGraphCloneToUnknownProfile(t *testing.T) {
+	f := newPixelaFake().factory()
+
+	cmd := newCmdRoot(f)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs(strings.Split("graph clone --id=src --new-id=dst --to-profile=unknown", " "))
+
+	err := cmd.Execute()
+
+	assert.EqualError(t, err, "graph clone failed: profile not found: unknown")
+}
+
+func TestGraphCloneStartOnMonday(t *testing.T) {
+	fake := newPixelaFake()
+	fake.addGraph(pixela.GraphDefinition{ID: "src", Name: "name", Unit: "commit", Type: "int", Color: "shibafu"})
+	f := fake.factory()
+
+	cmd := newCmdRoot(f)
+	out := bytes.NewBuffer([]byte{})
+	cmd.SetOut(out)
+	cmd.SetArgs(strings.Split("--dry-run --username=alice graph clone --id=src --new-id=dst --start-on-monday", " "))
+
+	assert.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), `POST /v1/users/alice/graphs {"id":"dst","name":"name","unit":"commit","type":"int","color":"shibafu","startOnMonday":true}`+"\n")
+}
+
+// cancelingPixel cancels the command after each Pixel is created.
+type cancelingPixel struct {
+	pixelaPixel
+	cancel context.CancelFunc
+}
+
+func (p *cancelingPixel) CreateWithContext(ctx context.Context, input *pixela.PixelCreateInput) (*pixela.Result, error) {
+	defer p.cancel()
+	return p.pixelaPixel.CreateWithContext(ctx, input)
+}
+
+func TestGraphCloneCancelled(t *testing.T) {
+	fake := newPixelaFake()
+	fake.addGraph(
+		pixela.GraphDefinition{ID: "src"},
+		pixela.PixelWithBody{Date: "20200101", Quantity: "1"},
+		pixela.PixelWithBody{Date: "20200102", Quantity: "2"},
+	)
+	f := fake.factory()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.pixel = &cancelingPixel{pixelaPixel: f.pixel, cancel: cancel}
+
+	cmd := newCmdRoot(f)
+	buffer := bytes.NewBuffer([]byte{})
+	cmd.SetOut(buffer)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(strings.Split("graph clone --id=src --new-id=dst --with-pixels --from=20200101 --to=20200131", " "))
+
+	err := cmd.ExecuteContext(ctx)
+
+	assert.EqualError(t, err, "graph clone cancelled: context canceled")
+	assert.Equal(t, `{"id":"dst","pixels":2,"copied":1,"failed":[]}`+"\n", buffer.String())
+	assert.Len(t, fake.pixels["dst"], 1)
+}
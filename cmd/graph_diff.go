@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/ebc-2in2crc/pa/pkg/pa"
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdGraphDiff creates a diff graph command.
+func NewCmdGraphDiff(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare a Graph with another Graph or a local export",
+		Long: "Compare a Graph with another Graph or a local export created by 'pa graph export'.\n" +
+			"Exit with status 1 when there are differences.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (o.Against == "") == (o.AgainstFile == "") {
+				return errors.New("specify either '--against' or '--against-file'")
+			}
+			a, err := f.Client().ExportGraph(f.Context(), o.ID, o.From, o.To)
+			if err != nil {
+				return reportError(cmd, err, "graph diff failed")
+			}
+			b, err := loadDiffTarget(f.Context(), f, o)
+			if err != nil {
+				return reportError(cmd, err, "graph diff failed")
+			}
+
+			d := diffGraphs(a, b)
+			s, err := json.Marshal(d)
+			if err != nil {
+				return fmt.Errorf("marshal graph diff failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(s))
+
+			if !d.isEmpty() {
+				return ErrNeglect
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVar(&o.Against, "against", "", "ID of the pixelation graph to compare with")
+	cmd.Flags().StringVar(&o.AgainstFile, "against-file", "", "Path of the export file to compare with instead of '--against'")
+	cmd.Flags().StringVar(&o.From, "from", "", "The start position of the period of the Pixels to compare (default 20000101)")
+	cmd.Flags().StringVar(&o.To, "to", "", "The end position of the period of the Pixels to compare (default today)")
+
+	return cmd
+}
+
+// loadDiffTarget loads the export file of --against-file, otherwise it exports the graph of --against.
+func loadDiffTarget(ctx context.Context, f *pixelaClientFactory, o *graphOptions) (*pa.GraphExport, error) {
+	if o.AgainstFile == "" {
+		return f.Client().ExportGraph(ctx, o.Against, o.From, o.To)
+	}
+
+	export, err := readGraphExport(o.AgainstFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pixels := []pixela.PixelWithBody{}
+	for _, p := range export.Pixels {
+		if inPeriod(p.Date, o.From, o.To) {
+			pixels = append(pixels, p)
+		}
+	}
+	export.Pixels = pixels
+	return export, nil
+}
+
+type graphDiff struct {
+	Definition []fieldDiff            `json:"definition"`
+	Added      []pixela.PixelWithBody `json:"added"`
+	Removed    []pixela.PixelWithBody `json:"removed"`
+	Changed    []pixelDiff            `json:"changed"`
+}
+
+type fieldDiff struct {
+	Field string      `json:"field"`
+	A     interface{} `json:"a"`
+	B     interface{} `json:"b"`
+}
+
+type pixelDiff struct {
+	Date string   `json:"date"`
+	A    quantity `json:"a"`
+	B    quantity `json:"b"`
+}
+
+func (d *graphDiff) isEmpty() bool {
+	return len(d.Definition) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffGraphs compares b with a. Added are the pixels only in b and removed are the pixels only in a.
+func diffGraphs(a, b *pa.GraphExport) *graphDiff {
+	d := &graphDiff{
+		Definition: diffDefinitions(a.Definition, b.Definition),
+		Added:      []pixela.PixelWithBody{},
+		Removed:    []pixela.PixelWithBody{},
+		Changed:    []pixelDiff{},
+	}
+
+	bPixels := map[string]pixela.PixelWithBody{}
+	for _, p := range b.Pixels {
+		bPixels[p.Date] = p
+	}
+	aDates := map[string]bool{}
+	for _, pixelA := range a.Pixels {
+		aDates[pixelA.Date] = true
+		pixelB, ok := bPixels[pixelA.Date]
+		if !ok {
+			d.Removed = append(d.Removed, pixelA)
+			continue
+		}
+		if !sameQuantity(pixelA.Quantity, pixelB.Quantity) || !sameOptionalData(pixelA.OptionalData, pixelB.OptionalData) {
+			d.Changed = append(d.Changed, pixelDiff{
+				Date: pixelA.Date,
+				A:    quantity{Quantity: pixelA.Quantity, OptionalData: pixelA.OptionalData},
+				B:    quantity{Quantity: pixelB.Quantity, OptionalData: pixelB.OptionalData},
+			})
+		}
+	}
+	for _, pixelB := range b.Pixels {
+		if !aDates[pixelB.Date] {
+			d.Added = append(d.Added, pixelB)
+		}
+	}
+
+	return d
+}
+
+// diffDefinitions compares the fields of the definitions except the ID.
+func diffDefinitions(a, b pa.GraphDefinition) []fieldDiff {
+	result := []fieldDiff{}
+	va := reflect.ValueOf(a)
+	vb := reflect.ValueOf(b)
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("json")
+		if name == "id" {
+			continue
+		}
+		fa := va.Field(i).Interface()
+		fb := vb.Field(i).Interface()
+		if isEmptyURLs(fa) && isEmptyURLs(fb) {
+			continue
+		}
+		if !reflect.DeepEqual(fa, fb) {
+			result = append(result, fieldDiff{Field: name, A: fa, B: fb})
+		}
+	}
+	return result
+}
+
+// isEmptyURLs reports whether v is a nil or empty slice. API returns both for no purgeCacheURLs.
+func isEmptyURLs(v interface{}) bool {
+	urls, ok := v.([]string)
+	return ok && len(urls) == 0
+}
+
+func sameQuantity(a, b string) bool {
+	if a == b {
+		return true
+	}
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	return errA == nil && errB == nil && fa == fb
+}
+
+func sameOptionalData(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var ja, jb interface{}
+	if json.Unmarshal([]byte(a), &ja) != nil || json.Unmarshal([]byte(b), &jb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(ja, jb)
+}
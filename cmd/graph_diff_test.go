@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphDiff(t *testing.T) {
+	exportPath := filepath.Join(t.TempDir(), "export.json")
+	export := `{"definition":{"id":"a","name":"name","unit":"commit","type":"int","color":"shibafu","purgeCacheURLs":[]},` +
+		`"pixels":[{"date":"20200101","quantity":"1","optionalData":"{\"a\":1,\"b\":2}"},{"date":"20200103","quantity":"3"}]}`
+	assert.NoError(t, os.WriteFile(exportPath, []byte(export), 0600))
+
+	params := []struct {
+		commandline   string
+		expected      string
+		expectedError error
+	}{
+		{
+			commandline: "graph diff --id=a --against=b",
+			expected: `{"definition":[{"field":"color","a":"shibafu","b":"momiji"}],` +
+				`"added":[{"date":"20200104","quantity":"4","optionalData":""}],` +
+				`"removed":[{"date":"20200103","quantity":"3","optionalData":""}],` +
+				`"changed":[{"date":"20200102","a":{"quantity":"2","optionalData":""},"b":{"quantity":"5","optionalData":""}}]}` + "\n",
+			expectedError: ErrNeglect,
+		},
+		{
+			commandline: "graph diff --id=a --against=b --from=20200101 --to=20200101",
+			expected: `{"definition":[{"field":"color","a":"shibafu","b":"momiji"}],` +
+				`"added":[],"removed":[],"changed":[]}` + "\n",
+			expectedError: ErrNeglect,
+		},
+		{
+			commandline: "graph diff --id=a --against-file=" + exportPath + " --to=20200101",
+			expected:    `{"definition":[],"added":[],"removed":[],"changed":[]}` + "\n",
+		},
+		{
+			// --against はファイルが存在してもグラフ ID として扱う
+			commandline:   "graph diff --id=a --against=" + exportPath,
+			expected:      `{"message":"Specified graphID not exist.","isSuccess":false,"isRejected":false,"statusCode":404}` + "\n",
+			expectedError: ErrNeglect,
+		},
+		{
+			commandline:   "graph diff --id=a --against=b --against-file=" + exportPath,
+			expectedError: errors.New("specify either '--against' or '--against-file'"),
+		},
+		{
+			commandline:   "graph diff --id=a",
+			expectedError: errors.New("specify either '--against' or '--against-file'"),
+		},
+		{
+			commandline:   "graph diff --id=a --against=not-exist",
+			expected:      `{"message":"Specified graphID not exist.","isSuccess":false,"isRejected":false,"statusCode":404}` + "\n",
+			expectedError: ErrNeglect,
+		},
+	}
+
+	for _, p := range params {
+		fake := newPixelaFake()
+		fake.addGraph(
+			pixela.GraphDefinition{ID: "a", Name: "name", Unit: "commit", Type: "int", Color: "shibafu"},
+			pixela.PixelWithBody{Date: "20200101", Quantity: "1", OptionalData: `{"b":2,"a":1}`},
+			pixela.PixelWithBody{Date: "20200102", Quantity: "2"},
+			pixela.PixelWithBody{Date: "20200103", Quantity: "3"},
+		)
+		fake.addGraph(
+			pixela.GraphDefinition{ID: "b", Name: "name", Unit: "commit", Type: "int", Color: "momiji"},
+			pixela.PixelWithBody{Date: "20200101", Quantity: "1.0", OptionalData: `{"a":1,"b":2}`},
+			pixela.PixelWithBody{Date: "20200102", Quantity: "5"},
+			pixela.PixelWithBody{Date: "20200104", Quantity: "4"},
+		)
+		f := fake.factory()
+
+		cmd := newCmdRoot(f)
+		buffer := bytes.NewBuffer([]byte{})
+		cmd.SetOut(buffer)
+		cmd.SetArgs(strings.Split(p.commandline, " "))
+
+		err := cmd.Execute()
+
+		assert.Equal(t, p.expectedError, err)
+		assert.Equal(t, p.expected, buffer.String())
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ebc-2in2crc/pa/pkg/pa"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdGraphExport creates a export graph command.
+func NewCmdGraphExport(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a Graph definition and its Pixels as JSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			export, err := f.Client().ExportGraph(f.Context(), o.ID, o.From, o.To)
+			if err != nil {
+				return reportError(cmd, err, "graph export failed")
+			}
+
+			b, err := json.Marshal(export)
+			if err != nil {
+				return fmt.Errorf("marshal graph export failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVar(&o.From, "from", "", "The start position of the period of the Pixels to export (default 20000101)")
+	cmd.Flags().StringVar(&o.To, "to", "", "The end position of the period of the Pixels to export (default today)")
+
+	return cmd
+}
+
+func readGraphExport(path string) (*pa.GraphExport, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read graph export failed: %w", err)
+	}
+
+	var export pa.GraphExport
+	if err := json.Unmarshal(b, &export); err != nil {
+		return nil, fmt.Errorf("unmarshal graph export failed: %w", err)
+	}
+	return &export, nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphExport(t *testing.T) {
+	params := []struct {
+		commandline   string
+		expected      string
+		expectedError error
+	}{
+		{
+			commandline: "graph export --id=graph-id --from=20200101 --to=20201231",
+			expected: `{"definition":{"id":"graph-id","name":"name","unit":"commit","type":"int","color":"shibafu","timezone":"","purgeCacheURLs":null,"selfSufficient":"","isSecret":false,"publishOptionalData":false},` +
+				`"pixels":[{"date":"20200101","quantity":"1","optionalData":"{\"key\":\"value\"}"}]}` + "\n",
+		},
+		{
+			commandline:   "graph export --id=not-exist",
+			expected:      `{"message":"Specified graphID not exist.","isSuccess":false,"isRejected":false,"statusCode":404}` + "\n",
+			expectedError: ErrNeglect,
+		},
+	}
+
+	for _, p := range params {
+		fake := newPixelaFake()
+		fake.addGraph(
+			pixela.GraphDefinition{ID: "graph-id", Name: "name", Unit: "commit", Type: "int", Color: "shibafu"},
+			pixela.PixelWithBody{Date: "20200101", Quantity: "1", OptionalData: `{"key":"value"}`},
+			pixela.PixelWithBody{Date: "20210101", Quantity: "2"},
+		)
+		f := fake.factory()
+
+		cmd := newCmdRoot(f)
+		buffer := bytes.NewBuffer([]byte{})
+		cmd.SetOut(buffer)
+		cmd.SetArgs(strings.Split(p.commandline, " "))
+
+		err := cmd.Execute()
+
+		assert.Equal(t, p.expectedError, err)
+		assert.Equal(t, p.expected, buffer.String())
+	}
+}
+
+func TestReadGraphExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.json")
+	content := `{"definition":{"id":"graph-id","name":"name"},"pixels":[{"date":"20200101","quantity":"1","optionalData":""}]}`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	export, err := readGraphExport(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "graph-id", export.Definition.ID)
+	assert.Equal(t, "name", export.Definition.Name)
+	assert.Equal(t, []pixela.PixelWithBody{{Date: "20200101", Quantity: "1"}}, export.Pixels)
+
+	_, err = readGraphExport(filepath.Join(t.TempDir(), "not-exist.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+var mergeFuncs = map[string]func(a, b float64) float64{
+	"sum": func(a, b float64) float64 { return a + b },
+	"max": math.Max,
+}
+
+// NewCmdGraphMerge creates a merge graph command.
+func NewCmdGraphMerge(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge",
+		Short: "Merge Pixels from multiple Graphs into a combined Graph",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := mergeFuncs[o.Func]; !ok {
+				return fmt.Errorf("unsupported merge function: %s", o.Func)
+			}
+			if err := checkProtectedGraph(f, o.Target, "overwrite", o.Force); err != nil {
+				return err
+			}
+
+			result, mergeErr := mergeGraphs(cmd, f, o)
+			if result == nil {
+				return reportError(cmd, mergeErr, "graph merge failed")
+			}
+
+			b, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("marshal graph merge result failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+
+			// キャンセルされたときはそれまでの結果を出力して終了する
+			if mergeErr != nil {
+				return fmt.Errorf("graph merge cancelled: %w", mergeErr)
+			}
+
+			if len(result.Failed) > 0 {
+				return ErrNeglect
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.Sources, "sources", []string{}, "IDs of the pixelation graphs to merge")
+	_ = cmd.MarkFlagRequired("sources")
+	cmd.Flags().StringVar(&o.Target, "target", "", "ID of the pixelation graph to write the combined quantities")
+	_ = cmd.MarkFlagRequired("target")
+	cmd.Flags().StringVar(&o.Func, "func", "sum", "The function to combine the quantities: sum or max")
+	cmd.Flags().BoolVar(&o.Incremental, "incremental", false, "Only write the dates changed since the last run")
+	cmd.Flags().StringVar(&o.From, "from", "", "The start position of the period to merge (default 20000101)")
+	cmd.Flags().StringVar(&o.To, "to", "", "The end position of the period to merge (default today)")
+	cmd.Flags().BoolVar(&o.Force, "force", false, "Overwrite the Pixels even if the target is protected by protected_graphs")
+
+	return cmd
+}
+
+type mergeResult struct {
+	Target  string   `json:"target"`
+	Dates   int      `json:"dates"`
+	Updated int      `json:"updated"`
+	Deleted int      `json:"deleted"`
+	Skipped int      `json:"skipped"`
+	Failed  []string `json:"failed"`
+}
+
+// mergeState is the last run of the incremental merge.
+type mergeState struct {
+	Sources    []string          `json:"sources"`
+	Func       string            `json:"func"`
+	LastRun    time.Time         `json:"lastRun"`
+	Quantities map[string]string `json:"quantities"`
+}
+
+// mergeStatePath returns the path of the state of the incremental merge.
+// The state is kept for each user, target and set of the sources, so that the merges into a target don't share it.
+func mergeStatePath(f *pixelaClientFactory, o *graphOptions) (string, error) {
+	sum := sha256.Sum256([]byte(strings.Join(sortedSources(o.Sources), ",")))
+	return stateFile("merge", f.Username(), fmt.Sprintf("%s-%x.json", o.Target, sum[:6]))
+}
+
+func sortedSources(sources []string) []string {
+	sorted := append([]string{}, sources...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// mergeGraphs writes the combined quantities of the sources to the target.
+// When the command is cancelled while writing, it saves the state so far and returns the result so far with the error.
+func mergeGraphs(cmd *cobra.Command, f *pixelaClientFactory, o *graphOptions) (*mergeResult, error) {
+	ctx := f.Context()
+	combined, err := f.Client().CombinePixels(ctx, o.Sources, mergeFuncs[o.Func], o.From, o.To)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := mergeStatePath(f, o)
+	if err != nil {
+		return nil, fmt.Errorf("get merge state failed: %w", err)
+	}
+	state := &mergeState{Quantities: map[string]string{}}
+	if err := readStateJSON(path, state); err != nil {
+		return nil, fmt.Errorf("read merge state failed: %w", err)
+	}
+	// 関数が変わったときは前回の結果を使えないのですべての日付を書き込む
+	if !o.Incremental || !reflect.DeepEqual(state.Sources, sortedSources(o.Sources)) || state.Func != o.Func {
+		state = &mergeState{Quantities: map[string]string{}}
+	}
+
+	dates := make([]string, 0, len(combined))
+	for d := range combined {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+
+	r := &mergeResult{Target: o.Target, Dates: len(dates), Failed: []string{}}
+	// 前回と同じ数量の日付は書き込まないので、書き込む日付の位置を残して進捗に使う
+	var updates []int
+	for i, d := range dates {
+		if prev, ok := state.Quantities[d]; ok && prev == combined[d] {
+			r.Skipped++
+			continue
+		}
+		updates = append(updates, i)
+	}
+
+	results := make([]*pixela.Result, len(updates))
+	update := func(ctx context.Context, i int) error {
+		d := dates[updates[i]]
+		var err error
+		results[i], err = f.Pixel().UpdateWithContext(ctx, &pixela.PixelUpdateInput{
+			GraphID:  pixela.String(o.Target),
+			Date:     pixela.String(d),
+			Quantity: pixela.String(combined[d]),
+		})
+		return err
+	}
+	report := func(i int, err error) {
+		if err != nil {
+			return
+		}
+		d, q := dates[updates[i]], combined[dates[updates[i]]]
+		if !results[i].IsSuccess {
+			r.Failed = append(r.Failed, d)
+			delete(state.Quantities, d)
+			fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] %s failed: %s\n", updates[i]+1, len(dates), d, results[i].Message)
+			return
+		}
+		state.Quantities[d] = q
+		r.Updated++
+		fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] %s updated: %s\n", updates[i]+1, len(dates), d, q)
+	}
+	if err := f.pool.Do(ctx, len(updates), update, report); err != nil {
+		if ctx.Err() != nil {
+			return r, saveMergeState(f, path, state, o, ctx.Err())
+		}
+		return nil, fmt.Errorf("pixel update failed: %w", err)
+	}
+
+	// 前回書き込んだ日付のソースの Pixel がすべて削除されたときは合算先からも削除する
+	for d := range state.Quantities {
+		if _, ok := combined[d]; ok || !inPeriod(d, o.From, o.To) {
+			continue
+		}
+		result, err := f.Pixel().DeleteWithContext(ctx, &pixela.PixelDeleteInput{
+			GraphID: pixela.String(o.Target),
+			Date:    pixela.String(d),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return r, saveMergeState(f, path, state, o, ctx.Err())
+			}
+			return nil, fmt.Errorf("pixel delete failed: %w", err)
+		}
+		if !result.IsSuccess {
+			r.Failed = append(r.Failed, d)
+			continue
+		}
+		delete(state.Quantities, d)
+		r.Deleted++
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s deleted\n", d)
+	}
+
+	if err := saveMergeState(f, path, state, o, nil); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// saveMergeState writes the state of the run, and returns cause unless the writing fails.
+func saveMergeState(f *pixelaClientFactory, path string, state *mergeState, o *graphOptions, cause error) error {
+	state.Sources = sortedSources(o.Sources)
+	state.Func = o.Func
+	state.LastRun = time.Now()
+	if err := f.writeState(path, state); err != nil {
+		return fmt.Errorf("write merge state failed: %w", err)
+	}
+	return cause
+}
+
+func inPeriod(date, from, to string) bool {
+	return (from == "" || date >= from) && (to == "" || date <= to)
+}
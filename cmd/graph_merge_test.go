@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMergeFake() *pixelaFake {
+	fake := newPixelaFake()
+	fake.addGraph(
+		pixela.GraphDefinition{ID: "a"},
+		pixela.PixelWithBody{Date: "20200101", Quantity: "1"},
+		pixela.PixelWithBody{Date: "20200102", Quantity: "5"},
+	)
+	fake.addGraph(
+		pixela.GraphDefinition{ID: "b"},
+		pixela.PixelWithBody{Date: "20200101", Quantity: "2.5"},
+		pixela.PixelWithBody{Date: "20200103", Quantity: "3"},
+	)
+	fake.addGraph(pixela.GraphDefinition{ID: "total"})
+	return fake
+}
+
+func executeMerge(t *testing.T, f *pixelaClientFactory, commandline string) (string, error) {
+	t.Helper()
+	cmd := newCmdRoot(f)
+	buffer := bytes.NewBuffer([]byte{})
+	cmd.SetOut(buffer)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(strings.Split(commandline, " "))
+	err := cmd.Execute()
+	return buffer.String(), err
+}
+
+func TestGraphMerge(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	params := []struct {
+		commandline    string
+		expected       string
+		expectedError  string
+		expectedPixels []pixela.PixelWithBody
+	}{
+		{
+			commandline: "graph merge --sources=a,b --target=total --from=20200101 --to=20201231",
+			expected:    `{"target":"total","dates":3,"updated":3,"deleted":0,"skipped":0,"failed":[]}` + "\n",
+			expectedPixels: []pixela.PixelWithBody{
+				{Date: "20200101", Quantity: "3.5"},
+				{Date: "20200102", Quantity: "5"},
+				{Date: "20200103", Quantity: "3"},
+			},
+		},
+		{
+			commandline: "graph merge --sources=a,b --target=total --func=max --from=20200101 --to=20201231",
+			expected:    `{"target":"total","dates":3,"updated":3,"deleted":0,"skipped":0,"failed":[]}` + "\n",
+			expectedPixels: []pixela.PixelWithBody{
+				{Date: "20200101", Quantity: "2.5"},
+				{Date: "20200102", Quantity: "5"},
+				{Date: "20200103", Quantity: "3"},
+			},
+		},
+		{
+			commandline:   "graph merge --sources=a,b --target=total --func=avg",
+			expectedError: "unsupported merge function: avg",
+		},
+		{
+			commandline:   "graph merge --sources=a,not-exist --target=total --from=20200101 --to=20201231",
+			expected:      `{"message":"Specified graphID not exist.","isSuccess":false,"isRejected":false,"statusCode":404}` + "\n",
+			expectedError: ErrNeglect.Error(),
+		},
+	}
+
+	for _, p := range params {
+		fake := newMergeFake()
+		f := fake.factory()
+
+		out, err := executeMerge(t, f, p.commandline)
+
+		if p.expectedError != "" {
+			assert.EqualError(t, err, p.expectedError)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, p.expectedPixels, fake.sortedPixels("total"))
+		}
+		assert.Equal(t, p.expected, out)
+	}
+}
+
+func TestGraphMergeIncremental(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	fake := newMergeFake()
+	f := fake.factory()
+	commandline := "graph merge --sources=a,b --target=total --from=20200101 --to=20201231 --incremental"
+
+	out, err := executeMerge(t, f, commandline)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"target":"total","dates":3,"updated":3,"deleted":0,"skipped":0,"failed":[]}`+"\n", out)
+
+	fake.pixels["a"]["20200102"] = pixela.PixelWithBody{Date: "20200102", Quantity: "6"}
+	delete(fake.pixels["b"], "20200103")
+
+	out, err = executeMerge(t, f, commandline)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"target":"total","dates":2,"updated":1,"deleted":1,"skipped":1,"failed":[]}`+"\n", out)
+	assert.Equal(t, []pixela.PixelWithBody{
+		{Date: "20200101", Quantity: "3.5"},
+		{Date: "20200102", Quantity: "6"},
+	}, fake.sortedPixels("total"))
+
+	// 関数が変わったときはすべての日付を書き込む
+	out, err = executeMerge(t, f, commandline+" --func=max")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"target":"total","dates":2,"updated":2,"deleted":0,"skipped":0,"failed":[]}`+"\n", out)
+}
+
+func TestGraphMergeConcurrency(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	fake := newMergeFake()
+	f := fake.factory()
+	f.pixel = &syncPixel{pixelaPixel: f.pixel}
+	cmd := newCmdRoot(f)
+	errOut := bytes.NewBuffer([]byte{})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(errOut)
+	cmd.SetArgs([]string{"graph", "merge", "--sources=a,b", "--target=total", "--from=20200101", "--to=20201231", "--concurrency=3"})
+
+	err := cmd.Execute()
+
+	assert.NoError(t, err)
+	assert.Len(t, fake.pixels["total"], 3)
+	// 並行に書き込んでも進捗は日付の順に出力される
+	assert.Equal(t, strings.Join([]string{
+		"[1/3] 20200101 updated: 3.5",
+		"[2/3] 20200102 updated: 5",
+		"[3/3] 20200103 updated: 3",
+	}, "\n")+"\n", errOut.String())
+}
+
+func TestGraphMergeIncrementalState(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	fake := newMergeFake()
+	f := fake.factory()
+	commandline := " graph merge --target=total --from=20200101 --to=20201231 --incremental"
+
+	out, err := executeMerge(t, f, "--username=alice --sources=a,b"+commandline)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"target":"total","dates":3,"updated":3,"deleted":0,"skipped":0,"failed":[]}`+"\n", out)
+
+	// ソースの順序が違っても同じ状態を使う
+	out, err = executeMerge(t, f, "--username=alice --sources=b,a"+commandline)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"target":"total","dates":3,"updated":0,"deleted":0,"skipped":3,"failed":[]}`+"\n", out)
+
+	// 別のユーザーの状態は使わない
+	out, err = executeMerge(t, f, "--username=bob --sources=a,b"+commandline)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"target":"total","dates":3,"updated":3,"deleted":0,"skipped":0,"failed":[]}`+"\n", out)
+
+	// 別のソースの状態は使わない
+	out, err = executeMerge(t, f, "--username=alice --sources=a"+commandline)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"target":"total","dates":2,"updated":2,"deleted":0,"skipped":0,"failed":[]}`+"\n", out)
+}
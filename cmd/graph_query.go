@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+)
+
+var whereExpression = regexp.MustCompile(`^\s*([A-Za-z0-9_.\-]+)\s*(==|!=|>=|<=|=~|>|<)\s*(.+?)\s*$`)
+
+// pixelQuery filters, sorts and limits the pixels on the client side.
+type pixelQuery struct {
+	conditions []pixelCondition
+	sortField  string
+	sortDesc   bool
+	limit      int
+}
+
+type pixelCondition struct {
+	field string
+	op    string
+	value interface{}
+	re    *regexp.Regexp
+}
+
+// newPixelQuery parses the flags. It returns nil when no query is specified.
+func newPixelQuery(where []string, sortBy string, limit int) (*pixelQuery, error) {
+	if len(where) == 0 && sortBy == "" && limit <= 0 {
+		return nil, nil
+	}
+
+	q := &pixelQuery{limit: limit}
+	for _, w := range where {
+		c, err := parsePixelCondition(w)
+		if err != nil {
+			return nil, err
+		}
+		q.conditions = append(q.conditions, c)
+	}
+
+	if sortBy != "" {
+		fields := strings.FieldsFunc(sortBy, func(r rune) bool { return r == ' ' || r == ':' })
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("invalid sort %q, specify as 'field [asc|desc]'", sortBy)
+		}
+		q.sortField = fields[0]
+		if len(fields) == 2 {
+			switch strings.ToLower(fields[1]) {
+			case "asc":
+			case "desc":
+				q.sortDesc = true
+			default:
+				return nil, fmt.Errorf("invalid sort order %q, specify asc or desc", fields[1])
+			}
+		}
+	}
+
+	return q, nil
+}
+
+func parsePixelCondition(where string) (pixelCondition, error) {
+	m := whereExpression.FindStringSubmatch(where)
+	if m == nil {
+		return pixelCondition{}, fmt.Errorf("invalid where %q, specify as 'field operator value'", where)
+	}
+
+	c := pixelCondition{field: m[1], op: m[2], value: parseDataValue(m[3])}
+	if c.op == "=~" {
+		re, err := regexp.Compile(fmt.Sprint(c.value))
+		if err != nil {
+			return pixelCondition{}, fmt.Errorf("invalid regular expression in %q: %w", where, err)
+		}
+		c.re = re
+	}
+	return c, nil
+}
+
+func (q *pixelQuery) apply(pixels []pixela.PixelWithBody) []pixela.PixelWithBody {
+	result := []pixela.PixelWithBody{}
+	for _, p := range pixels {
+		if q.match(p) {
+			result = append(result, p)
+		}
+	}
+
+	if q.sortField != "" {
+		sort.SliceStable(result, func(i, j int) bool {
+			a, _ := pixelField(result[i], q.sortField)
+			b, _ := pixelField(result[j], q.sortField)
+			if q.sortDesc {
+				return compareValues(b, a) < 0
+			}
+			return compareValues(a, b) < 0
+		})
+	}
+
+	if q.limit > 0 && len(result) > q.limit {
+		result = result[:q.limit]
+	}
+	return result
+}
+
+func (q *pixelQuery) match(p pixela.PixelWithBody) bool {
+	for _, c := range q.conditions {
+		v, ok := pixelField(p, c.field)
+		if !ok || !c.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c pixelCondition) match(v interface{}) bool {
+	switch c.op {
+	case "==":
+		return compareValues(v, c.value) == 0
+	case "!=":
+		return compareValues(v, c.value) != 0
+	case ">":
+		return compareValues(v, c.value) > 0
+	case ">=":
+		return compareValues(v, c.value) >= 0
+	case "<":
+		return compareValues(v, c.value) < 0
+	case "<=":
+		return compareValues(v, c.value) <= 0
+	case "=~":
+		return c.re.MatchString(fmt.Sprint(v))
+	}
+	return false
+}
+
+// pixelField returns the quantity, the date or the value of the optionalData key.
+// Nested keys of the optionalData are separated by dots.
+func pixelField(p pixela.PixelWithBody, field string) (interface{}, bool) {
+	switch field {
+	case "quantity":
+		return parseDataValue(p.Quantity), true
+	case "date":
+		return p.Date, true
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(p.OptionalData), &v); err != nil {
+		return nil, false
+	}
+	for _, key := range strings.Split(strings.TrimPrefix(field, "optionalData."), ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok = obj[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// compareValues compares as numbers when both are numbers, otherwise as strings.
+func compareValues(a, b interface{}) int {
+	fa, okA := toNumber(a)
+	fb, okB := toNumber(b)
+	if okA && okB {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func pixelDates(pixels []pixela.PixelWithBody) []string {
+	dates := make([]string, len(pixels))
+	for i, p := range pixels {
+		dates[i] = p.Date
+	}
+	return dates
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func queryTestPixels() []pixela.PixelWithBody {
+	return []pixela.PixelWithBody{
+		{Date: "20200101", Quantity: "10", OptionalData: `{"tag":"deep-work","meta":{"place":"home"}}`},
+		{Date: "20200102", Quantity: "45", OptionalData: `{"tag":"meeting"}`},
+		{Date: "20200103", Quantity: "30.5", OptionalData: `{"tag":"deep-work","meta":{"place":"office"}}`},
+		{Date: "20200104", Quantity: "60", OptionalData: ""},
+	}
+}
+
+func TestPixelQuery(t *testing.T) {
+	params := []struct {
+		where    []string
+		sort     string
+		limit    int
+		expected []string
+	}{
+		{where: []string{`tag == "deep-work"`}, expected: []string{"20200101", "20200103"}},
+		{where: []string{`tag != deep-work`}, expected: []string{"20200102"}},
+		{where: []string{"quantity > 30"}, expected: []string{"20200102", "20200103", "20200104"}},
+		{where: []string{"quantity >= 45", "quantity<=60"}, expected: []string{"20200102", "20200104"}},
+		{where: []string{`tag == "deep-work"`, "quantity > 30"}, expected: []string{"20200103"}},
+		{where: []string{"date < 20200103"}, expected: []string{"20200101", "20200102"}},
+		{where: []string{"meta.place == office"}, expected: []string{"20200103"}},
+		{where: []string{"optionalData.meta.place =~ ^ho"}, expected: []string{"20200101"}},
+		{sort: "quantity desc", expected: []string{"20200104", "20200102", "20200103", "20200101"}},
+		{sort: "quantity:asc", limit: 2, expected: []string{"20200101", "20200103"}},
+		{where: []string{"tag =~ work$"}, sort: "date desc", limit: 1, expected: []string{"20200103"}},
+	}
+
+	for _, p := range params {
+		q, err := newPixelQuery(p.where, p.sort, p.limit)
+		assert.NoError(t, err)
+		assert.Equal(t, p.expected, pixelDates(q.apply(queryTestPixels())), "%v %s %d", p.where, p.sort, p.limit)
+	}
+}
+
+func TestNewPixelQuery(t *testing.T) {
+	q, err := newPixelQuery([]string{}, "", 0)
+	assert.NoError(t, err)
+	assert.Nil(t, q)
+
+	params := []struct {
+		where    []string
+		sort     string
+		expected string
+	}{
+		{where: []string{"tag"}, expected: "invalid where"},
+		{where: []string{"tag =~ ("}, expected: "invalid regular expression"},
+		{sort: "quantity down", expected: "invalid sort order"},
+		{sort: "quantity desc extra", expected: "invalid sort"},
+		{sort: ":", expected: "invalid sort"},
+		{sort: " ", expected: "invalid sort"},
+	}
+	for _, p := range params {
+		_, err := newPixelQuery(p.where, p.sort, 0)
+		assert.Contains(t, err.Error(), p.expected)
+	}
+}
+
+func TestGraphPixelsWithQuery(t *testing.T) {
+	params := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{"--id=graph-id", `--where=tag == "deep-work"`, "--sort=quantity desc"},
+			expected: `{"pixels":["20200103","20200101"]}` + "\n",
+		},
+		{
+			args:     []string{"--id=graph-id", "--with-body", "--where=quantity > 40", "--limit=1"},
+			expected: `{"pixels":[{"date":"20200102","quantity":"45","optionalData":"{\"tag\":\"meeting\"}"}]}` + "\n",
+		},
+	}
+
+	for _, p := range params {
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
+			pixels: pixela.Pixels{
+				Result: pixela.Result{IsSuccess: true, StatusCode: http.StatusOK},
+				Pixels: queryTestPixels(),
+			},
+		}
+		c := NewCmdGraphGetPixelDates(f, &graphOptions{})
+		buffer := bytes.NewBuffer([]byte{})
+		c.SetOut(buffer)
+		c.SetArgs(p.args)
+
+		assert.NoError(t, c.Execute())
+		assert.Equal(t, p.expected, buffer.String())
+	}
+}
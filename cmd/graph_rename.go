@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdGraphRename creates a rename graph command.
+func NewCmdGraphRename(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rename",
+		Short: "Rename a Graph ID by migrating its definition, Pixels and Webhooks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.DeleteMe {
+				// 期間の外の Pixel はコピーされないので、期間を指定したときは移行元を削除しない
+				if o.From != "" || o.To != "" {
+					return errors.New("'--from' and '--to' can't be used with '--delete-me', because the Pixels out of the period would be lost")
+				}
+				if err := checkProtectedGraph(f, o.ID, "delete", o.Force); err != nil {
+					return err
+				}
+			}
+
+			result, renameErr := renameGraph(cmd, f, o)
+			if result == nil {
+				return reportError(cmd, renameErr, "graph rename failed")
+			}
+
+			b, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("marshal graph rename result failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+
+			// キャンセルされたときや削除が確認されなかったときは移行元のグラフを残して途中までの結果を出力する
+			if renameErr != nil {
+				return fmt.Errorf("graph rename stopped, %s is kept: %w", o.ID, renameErr)
+			}
+
+			if !o.DeleteMe {
+				cmd.Println("Specify the '--delete-me' flag to delete the old graph.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph to rename")
+	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVar(&o.NewID, "new-id", "", "The new ID of the pixelation graph")
+	_ = cmd.MarkFlagRequired("new-id")
+	cmd.Flags().StringVar(&o.From, "from", "", "The start position of the period of the Pixels to migrate (default 20000101), can't be used with --delete-me")
+	cmd.Flags().StringVar(&o.To, "to", "", "The end position of the period of the Pixels to migrate (default today), can't be used with --delete-me")
+	cmd.Flags().BoolVar(&o.StartOnMonday, "start-on-monday", false, "The week of the new graph starts on Monday, which is not copied from the old graph")
+
+	// 移行元のグラフの削除は非常に危険なので `--delete-me` フラグが指定したときだけ削除する
+	cmd.Flags().BoolVarP(&o.DeleteMe, "delete-me", "", false, "Delete the old Graph after the migration")
+	cmd.Flags().BoolVar(&o.Force, "force", false, "Delete the old Graph even if it is protected by protected_graphs")
+
+	return cmd
+}
+
+type renameResult struct {
+	ID       string          `json:"id"`
+	OldID    string          `json:"oldId"`
+	Pixels   int             `json:"pixels"`
+	Webhooks []renameWebhook `json:"webhooks"`
+	Deleted  bool            `json:"deleted"`
+}
+
+type renameWebhook struct {
+	Type    string `json:"type"`
+	OldHash string `json:"oldHash"`
+	NewHash string `json:"newHash"`
+}
+
+// renameGraph migrates the graph to the new ID.
+// When the command is cancelled while copying the Pixels, it returns the Pixels copied so far with the error.
+// The old graph is archived and the deletion is confirmed on a terminal as graph delete does.
+func renameGraph(cmd *cobra.Command, f *pixelaClientFactory, o *graphOptions) (*renameResult, error) {
+	id, newID, deleteMe := o.ID, o.NewID, o.DeleteMe
+	client := f.Client()
+	cloned, err := client.CloneGraph(f.Context(), client, id, newID, cloneOptions(cmd, o, true))
+	if err != nil {
+		if cloned != nil {
+			return &renameResult{ID: newID, OldID: id, Pixels: cloned.Copied, Webhooks: []renameWebhook{}}, err
+		}
+		return nil, err
+	}
+	if len(cloned.Failed) > 0 {
+		return nil, fmt.Errorf("failed to copy pixels: %v", cloned.Failed)
+	}
+
+	// すべての Pixel がコピーできたことを確認してから移行元を削除する
+	// ドライランでは新しいグラフが作られていないので確認しない
+	if f.dryRun == nil {
+		if err := verifyRenamedPixels(f, id, newID, o); err != nil {
+			return nil, err
+		}
+	}
+
+	webhooks, err := recreateWebhooks(f, id, newID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &renameResult{ID: newID, OldID: id, Pixels: cloned.Pixels, Webhooks: webhooks}
+	if !deleteMe {
+		return result, nil
+	}
+
+	// graph delete と同じように移行元を退避してから端末では確認を求める
+	// 移行は終わっているので、削除しないときも結果を返す
+	if err := archiveGraph(cmd, f, id); err != nil {
+		return result, err
+	}
+	if err := confirmByTyping(cmd, f, "graph ID", id); err != nil {
+		return result, err
+	}
+
+	for _, wh := range webhooks {
+		r, err := f.Webhook().DeleteWithContext(f.Context(), &pixela.WebhookDeleteInput{WebhookHash: pixela.String(wh.OldHash)})
+		if err != nil {
+			return nil, fmt.Errorf("webhook delete failed: %w", err)
+		}
+		if !r.IsSuccess {
+			return nil, &resultError{Result: r}
+		}
+	}
+
+	r, err := f.Graph().DeleteWithContext(f.Context(), &pixela.GraphDeleteInput{ID: pixela.String(id)})
+	if err != nil {
+		return nil, fmt.Errorf("graph delete failed: %w", err)
+	}
+	if !r.IsSuccess {
+		return nil, &resultError{Result: r}
+	}
+	result.Deleted = true
+
+	return result, nil
+}
+
+// verifyRenamedPixels checks that the new graph has the Pixels of the old graph in the period with the same quantities.
+// When the old graph is deleted, it checks all the Pixels of the old graph regardless of the Pixels copied.
+func verifyRenamedPixels(f *pixelaClientFactory, id, newID string, o *graphOptions) error {
+	client := f.Client()
+	from, to := o.From, o.To
+	if o.DeleteMe {
+		from, to = "", ""
+	}
+	old, err := client.FetchPixels(f.Context(), id, from, to)
+	if err != nil {
+		return err
+	}
+	pixels, err := client.FetchPixels(f.Context(), newID, from, to)
+	if err != nil {
+		return err
+	}
+
+	copied := map[string]string{}
+	for _, p := range pixels {
+		copied[p.Date] = p.Quantity
+	}
+	var missing, mismatched []string
+	for _, p := range old {
+		quantity, ok := copied[p.Date]
+		switch {
+		case !ok:
+			missing = append(missing, p.Date)
+		case !sameQuantity(p.Quantity, quantity):
+			mismatched = append(mismatched, p.Date)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("pixels not copied: %s has %d pixels, but %s lacks %v", id, len(old), newID, missing)
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("pixels not copied: the quantities of %s differ from %s on %v", newID, id, mismatched)
+	}
+	return nil
+}
+
+func recreateWebhooks(f *pixelaClientFactory, id, newID string) ([]renameWebhook, error) {
+	whs, err := f.Webhook().GetAllWithContext(f.Context())
+	if err != nil {
+		return nil, fmt.Errorf("webhook get all failed: %w", err)
+	}
+	if !whs.IsSuccess {
+		return nil, &resultError{Result: &whs.Result}
+	}
+
+	result := []renameWebhook{}
+	for _, wh := range whs.Webhooks {
+		if wh.GraphID != id {
+			continue
+		}
+		r, err := f.Webhook().CreateWithContext(f.Context(), &pixela.WebhookCreateInput{
+			GraphID: pixela.String(newID),
+			Type:    pixela.String(wh.Type),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("webhook create failed: %w", err)
+		}
+		if !r.IsSuccess {
+			return nil, &resultError{Result: &r.Result}
+		}
+		result = append(result, renameWebhook{Type: wh.Type, OldHash: wh.WebhookHash, NewHash: r.WebhookHash})
+	}
+
+	return result, nil
+}
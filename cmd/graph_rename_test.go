@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphRename(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	params := []struct {
+		commandline      string
+		failDates        []string
+		expected         string
+		expectedError    string
+		expectedOldGraph bool
+		expectedWebhooks []pixela.WebhookDefinition
+	}{
+		{
+			commandline: "graph rename --id=old --new-id=new --from=20200101 --to=20201231",
+			expected: `{"id":"new","oldId":"old","pixels":2,"webhooks":[{"type":"increment","oldHash":"old-hash","newHash":"hash-new-increment"}],"deleted":false}` + "\n" +
+				"Specify the '--delete-me' flag to delete the old graph.\n",
+			expectedOldGraph: true,
+			expectedWebhooks: []pixela.WebhookDefinition{
+				{WebhookHash: "old-hash", GraphID: "old", Type: "increment"},
+				{WebhookHash: "other-hash", GraphID: "other", Type: "decrement"},
+				{WebhookHash: "hash-new-increment", GraphID: "new", Type: "increment"},
+			},
+		},
+		{
+			commandline:      "graph rename --id=old --new-id=new --delete-me",
+			expected:         `{"id":"new","oldId":"old","pixels":2,"webhooks":[{"type":"increment","oldHash":"old-hash","newHash":"hash-new-increment"}],"deleted":true}` + "\n",
+			expectedOldGraph: false,
+			expectedWebhooks: []pixela.WebhookDefinition{
+				{WebhookHash: "other-hash", GraphID: "other", Type: "decrement"},
+				{WebhookHash: "hash-new-increment", GraphID: "new", Type: "increment"},
+			},
+		},
+		{
+			commandline:      "graph rename --id=old --new-id=new --delete-me",
+			failDates:        []string{"20200102"},
+			expectedError:    "graph rename failed: failed to copy pixels: [20200102]",
+			expectedOldGraph: true,
+			expectedWebhooks: []pixela.WebhookDefinition{
+				{WebhookHash: "old-hash", GraphID: "old", Type: "increment"},
+				{WebhookHash: "other-hash", GraphID: "other", Type: "decrement"},
+			},
+		},
+		{
+			commandline:      "graph rename --id=old --new-id=new --from=20200101 --delete-me",
+			expectedError:    "'--from' and '--to' can't be used with '--delete-me', because the Pixels out of the period would be lost",
+			expectedOldGraph: true,
+			expectedWebhooks: []pixela.WebhookDefinition{
+				{WebhookHash: "old-hash", GraphID: "old", Type: "increment"},
+				{WebhookHash: "other-hash", GraphID: "other", Type: "decrement"},
+			},
+		},
+	}
+
+	for _, p := range params {
+		fake := newPixelaFake()
+		fake.addGraph(
+			pixela.GraphDefinition{ID: "old", Name: "name", Unit: "commit", Type: "int", Color: "shibafu"},
+			pixela.PixelWithBody{Date: "20200101", Quantity: "1"},
+			pixela.PixelWithBody{Date: "20200102", Quantity: "2", OptionalData: `{"key":"value"}`},
+		)
+		fake.webhooks = []pixela.WebhookDefinition{
+			{WebhookHash: "old-hash", GraphID: "old", Type: "increment"},
+			{WebhookHash: "other-hash", GraphID: "other", Type: "decrement"},
+		}
+		for _, d := range p.failDates {
+			fake.failDates[d] = true
+		}
+		f := fake.factory()
+
+		cmd := newCmdRoot(f)
+		buffer := bytes.NewBuffer([]byte{})
+		cmd.SetOut(buffer)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(strings.Split(p.commandline, " "))
+
+		err := cmd.Execute()
+
+		if p.expectedError != "" {
+			assert.EqualError(t, err, p.expectedError)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, p.expected, buffer.String())
+			assert.Equal(t, []pixela.PixelWithBody{
+				{Date: "20200101", Quantity: "1"},
+				{Date: "20200102", Quantity: "2", OptionalData: `{"key":"value"}`},
+			}, fake.sortedPixels("new"))
+		}
+		_, ok := fake.definitions["old"]
+		assert.Equal(t, p.expectedOldGraph, ok)
+		assert.Equal(t, p.expectedWebhooks, fake.webhooks)
+	}
+}
+
+// skewedPixel creates the Pixel of the date with another quantity, like a Pixel incremented while it is copied.
+type skewedPixel struct {
+	pixelaPixel
+	date string
+}
+
+func (p *skewedPixel) CreateWithContext(ctx context.Context, input *pixela.PixelCreateInput) (*pixela.Result, error) {
+	if pixela.StringValue(input.Date) == p.date {
+		copied := *input
+		copied.Quantity = pixela.String("99")
+		input = &copied
+	}
+	return p.pixelaPixel.CreateWithContext(ctx, input)
+}
+
+func TestGraphRenameQuantityMismatch(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	fake := newPixelaFake()
+	fake.addGraph(
+		pixela.GraphDefinition{ID: "old", Name: "name", Unit: "commit", Type: "int", Color: "shibafu"},
+		pixela.PixelWithBody{Date: "20200101", Quantity: "1"},
+		pixela.PixelWithBody{Date: "20200102", Quantity: "2"},
+	)
+	fake.webhooks = []pixela.WebhookDefinition{{WebhookHash: "old-hash", GraphID: "old", Type: "increment"}}
+	f := fake.factory()
+	f.pixel = &skewedPixel{pixelaPixel: f.pixel, date: "20200102"}
+
+	cmd := newCmdRoot(f)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"graph", "rename", "--id=old", "--new-id=new", "--delete-me"})
+
+	err := cmd.Execute()
+
+	// 数量が一致しないときは移行元のグラフと Webhook を削除しない
+	assert.EqualError(t, err, "graph rename failed: pixels not copied: the quantities of new differ from old on [20200102]")
+	_, ok := fake.definitions["old"]
+	assert.True(t, ok)
+	assert.Equal(t, []pixela.WebhookDefinition{{WebhookHash: "old-hash", GraphID: "old", Type: "increment"}}, fake.webhooks)
+}
@@ -2,13 +2,14 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/ebc-2in2crc/pa/pkg/pa"
 	pixela "github.com/ebc-2in2crc/pixela4go"
 	"github.com/stretchr/testify/assert"
 )
@@ -24,19 +25,19 @@ type pixelaGraphMock struct {
 	definition  pixela.GraphDefinition
 }
 
-func (p *pixelaGraphMock) Create(input *pixela.GraphCreateInput) (*pixela.Result, error) {
+func (p *pixelaGraphMock) CreateWithContext(ctx context.Context, input *pixela.GraphCreateInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaGraphMock) GetAll() (*pixela.GraphDefinitions, error) {
+func (p *pixelaGraphMock) GetAllWithContext(ctx context.Context) (*pixela.GraphDefinitions, error) {
 	return &p.definitions, p.err
 }
 
-func (p *pixelaGraphMock) Get(input *pixela.GraphGetInput) (*pixela.GraphDefinition, error) {
+func (p *pixelaGraphMock) GetWithContext(ctx context.Context, input *pixela.GraphGetInput) (*pixela.GraphDefinition, error) {
 	return &p.definition, p.err
 }
 
-func (p *pixelaGraphMock) GetSVG(input *pixela.GraphGetSVGInput) (string, error) {
+func (p *pixelaGraphMock) GetSVGWithContext(ctx context.Context, input *pixela.GraphGetSVGInput) (string, error) {
 	return p.svg, p.err
 }
 
@@ -48,35 +49,39 @@ func (p *pixelaGraphMock) GraphsURL() string {
 	return "https://pixe.la/v1/users/pa/graphs.html"
 }
 
-func (p *pixelaGraphMock) Stats(input *pixela.GraphStatsInput) (*pixela.Stats, error) {
+func (p *pixelaGraphMock) StatsWithContext(ctx context.Context, input *pixela.GraphStatsInput) (*pixela.Stats, error) {
 	return &p.stats, p.err
 }
 
-func (p *pixelaGraphMock) Update(input *pixela.GraphUpdateInput) (*pixela.Result, error) {
+func (p *pixelaGraphMock) UpdateWithContext(ctx context.Context, input *pixela.GraphUpdateInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaGraphMock) GetPixelDates(input *pixela.GraphGetPixelDatesInput) (*pixela.Pixels, error) {
+func (p *pixelaGraphMock) ClearPurgeCacheURLsWithContext(ctx context.Context, input *pa.GraphClearPurgeCacheURLsInput) (*pixela.Result, error) {
+	return &p.result, p.err
+}
+
+func (p *pixelaGraphMock) GetPixelDatesWithContext(ctx context.Context, input *pixela.GraphGetPixelDatesInput) (*pixela.Pixels, error) {
 	return &p.pixels, p.err
 }
 
-func (p *pixelaGraphMock) Delete(input *pixela.GraphDeleteInput) (*pixela.Result, error) {
+func (p *pixelaGraphMock) DeleteWithContext(ctx context.Context, input *pixela.GraphDeleteInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaGraphMock) Stopwatch(input *pixela.GraphStopwatchInput) (*pixela.Result, error) {
+func (p *pixelaGraphMock) StopwatchWithContext(ctx context.Context, input *pixela.GraphStopwatchInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaGraphMock) Add(input *pixela.GraphAddInput) (*pixela.Result, error) {
+func (p *pixelaGraphMock) AddWithContext(ctx context.Context, input *pixela.GraphAddInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaGraphMock) Subtract(input *pixela.GraphSubtractInput) (*pixela.Result, error) {
+func (p *pixelaGraphMock) SubtractWithContext(ctx context.Context, input *pixela.GraphSubtractInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaGraphMock) GetLatestPixel(input *pixela.GraphGetLatestPixelInput) (*pixela.GraphPixel, error) {
+func (p *pixelaGraphMock) GetLatestPixelWithContext(ctx context.Context, input *pixela.GraphGetLatestPixelInput) (*pixela.GraphPixel, error) {
 	result := &pixela.GraphPixel{
 		Date:         p.pixel.Date,
 		Quantity:     p.pixel.Quantity,
@@ -114,13 +119,11 @@ func TestGraphCreateInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphCreate(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphCreateInput()
+		input := createGraphCreateInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Name), pixela.StringValue(input.Name), "GraphName")
@@ -136,7 +139,6 @@ func TestGraphCreateInput(t *testing.T) {
 }
 
 func TestGraphCreate(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -168,11 +170,12 @@ func TestGraphCreate(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdGraphCreate()
+		c := NewCmdGraphCreate(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -187,7 +190,6 @@ func TestGraphCreate(t *testing.T) {
 }
 
 func TestGraphGetAll(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
 	params := []struct {
 		definitions pixela.GraphDefinitions
 		occur       error
@@ -238,11 +240,12 @@ func TestGraphGetAll(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
 			definitions: v.definitions,
 			err:         v.occur,
 		}
-		c := NewCmdGraphGetAll()
+		c := NewCmdGraphGetAll(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -272,20 +275,17 @@ func TestGraphGetInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphGet(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphGetInput()
+		input := createGraphGetInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 	}
 }
 
 func TestGraphGet(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
 	params := []struct {
 		definition pixela.GraphDefinition
 		occur      error
@@ -332,11 +332,12 @@ func TestGraphGet(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
 			definition: v.definition,
 			err:        v.occur,
 		}
-		c := NewCmdGraphGet()
+		c := NewCmdGraphGet(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -371,17 +372,11 @@ func TestGraphGetSVGInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		pixelaClient.graph = &pixelaGraphMock{
-			err: nil,
-			svg: "SVG",
-		}
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphGetSVG(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphGetSVGInput()
+		input := createGraphGetSVGInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Date), pixela.StringValue(input.Date), "Date")
@@ -391,7 +386,6 @@ func TestGraphGetSVGInput(t *testing.T) {
 }
 
 func TestGraphGetSVG(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
 	params := []struct {
 		occur    error
 		expected string
@@ -409,11 +403,12 @@ func TestGraphGetSVG(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
 			err: v.occur,
 			svg: v.svg,
 		}
-		c := NewCmdGraphGetSVG()
+		c := NewCmdGraphGetSVG(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -442,13 +437,11 @@ func TestGraphURLInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphURL(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphURLInput()
+		input := createGraphURLInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Mode), pixela.StringValue(input.Mode), "Mode")
@@ -456,16 +449,16 @@ func TestGraphURLInput(t *testing.T) {
 }
 
 func TestGraphURL(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
-	pixelaClient.graph = &pixelaGraphMock{}
-	c := NewCmdGraphURL()
+	f := newPixelaClientFactory()
+	f.graph = &pixelaGraphMock{}
+	c := NewCmdGraphURL(f, &graphOptions{})
 	buffer := bytes.NewBuffer([]byte{})
 	c.SetOut(buffer)
 
 	err := c.RunE(c, []string{})
 
 	assert.NoError(t, err)
-	expected := pixelaClient.graph.URL(nil) + "\n"
+	expected := f.graph.URL(nil) + "\n"
 	assert.Equal(t, expected, buffer.String())
 }
 
@@ -487,20 +480,17 @@ func TestGraphStatsInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphStats(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphStatsInput()
+		input := createGraphStatsInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 	}
 }
 
 func TestGraphStats(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
 	params := []struct {
 		stats    pixela.Stats
 		occur    error
@@ -546,11 +536,12 @@ func TestGraphStats(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
 			stats: v.stats,
 			err:   v.occur,
 		}
-		c := NewCmdGraphStats()
+		c := NewCmdGraphStats(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -605,13 +596,11 @@ func TestGraphUpdateInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphUpdate(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphUpdateInput()
+		input := createGraphUpdateInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Name), pixela.StringValue(input.Name), "GraphName")
@@ -642,7 +631,7 @@ func TestGraphUpdateInput(t *testing.T) {
 }
 
 func TestGraphUpdate(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
+	setupConfigHome(t)
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -674,11 +663,13 @@ func TestGraphUpdate(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
-			result: v.Result,
-			err:    v.occur,
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
+			result:     v.Result,
+			err:        v.occur,
+			definition: pixela.GraphDefinition{ID: "graph-id", Result: pixela.Result{IsSuccess: true}},
 		}
-		c := NewCmdGraphUpdate()
+		c := NewCmdGraphUpdate(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -710,20 +701,18 @@ func TestGraphDeleteInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphDelete(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphDeleteInput()
+		input := createGraphDeleteInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 	}
 }
 
 func TestGraphDelete(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
+	setupConfigHome(t)
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -755,11 +744,16 @@ func TestGraphDelete(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
-			result: v.Result,
-			err:    v.occur,
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
+			result:     v.Result,
+			err:        v.occur,
+			definition: pixela.GraphDefinition{ID: "graph-id", Result: pixela.Result{IsSuccess: true}},
+			pixels:     pixela.Pixels{Pixels: []pixela.PixelWithBody{}, Result: pixela.Result{IsSuccess: true}},
 		}
-		c := NewCmdGraphDelete()
+		// go test の標準入力が端末でも確認を求めない
+		f.stdinIsTerminal = func() bool { return false }
+		c := NewCmdGraphDelete(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 		assert.NoError(t, c.Flags().Set("delete-me", "true"))
@@ -795,13 +789,11 @@ func TestGraphPixelsInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphGetPixelDates(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphGetPixelDatesInput()
+		input := createGraphGetPixelDatesInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.From), pixela.StringValue(input.From), "From")
@@ -811,7 +803,6 @@ func TestGraphPixelsInput(t *testing.T) {
 }
 
 func TestGraphPixels(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
 	params := []struct {
 		withBody bool
 		pixels   pixela.Pixels
@@ -867,11 +858,12 @@ func TestGraphPixels(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
 			pixels: v.pixels,
 			err:    v.occur,
 		}
-		c := NewCmdGraphGetPixelDates()
+		c := NewCmdGraphGetPixelDates(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 		assert.NoError(t, c.Flags().Set("with-body", strconv.FormatBool(v.withBody)))
@@ -904,20 +896,17 @@ func TestGraphStopwatchInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphStopwatch(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphStopwatchInput()
+		input := createGraphStopwatchInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 	}
 }
 
 func TestGraphStopwatch(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -949,11 +938,12 @@ func TestGraphStopwatch(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdGraphStopwatch()
+		c := NewCmdGraphStopwatch(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -986,13 +976,11 @@ func TestGraphAddInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphAdd(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphAddInput()
+		input := createGraphAddInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Quantity), pixela.StringValue(input.Quantity), "Quantity")
@@ -1000,7 +988,6 @@ func TestGraphAddInput(t *testing.T) {
 }
 
 func TestGraphAdd(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -1023,11 +1010,12 @@ func TestGraphAdd(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdGraphAdd()
+		c := NewCmdGraphAdd(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -1060,13 +1048,11 @@ func TestGraphSubtractInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphSubtract(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphSubtractInput()
+		input := createGraphSubtractInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Quantity), pixela.StringValue(input.Quantity), "Quantity")
@@ -1074,7 +1060,6 @@ func TestGraphSubtractInput(t *testing.T) {
 }
 
 func TestGraphSubtract(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -1097,11 +1082,12 @@ func TestGraphSubtract(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdGraphSubtract()
+		c := NewCmdGraphSubtract(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -1129,20 +1115,17 @@ func TestGraphGetLatestPixelInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &graphOptions{}
+		cmd := NewCmdGraphCreate(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createGraphCreateInput()
+		input := createGraphCreateInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.ID), pixela.StringValue(input.ID), "GraphID")
 	}
 }
 
 func TestGraphGetLatestPixel(t *testing.T) {
-	defer func() { pixelaClient.graph = nil }()
 	params := []struct {
 		result   pixela.Result
 		pixel    pixela.PixelWithBody
@@ -1166,12 +1149,13 @@ func TestGraphGetLatestPixel(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.graph = &pixelaGraphMock{
+		f := newPixelaClientFactory()
+		f.graph = &pixelaGraphMock{
 			result: v.result,
 			pixel:  v.pixel,
 			err:    v.occur,
 		}
-		c := NewCmdGraphGetLatestPixel()
+		c := NewCmdGraphGetLatestPixel(f, &graphOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
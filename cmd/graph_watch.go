@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdGraphWatch creates a watch graph command.
+func NewCmdGraphWatch(f *pixelaClientFactory, o *graphOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Poll a Graph and report changes",
+		Long: "Poll the statistics and the latest Pixel of a Graph and print a line whenever\n" +
+			"today's quantity, the totals or the latest Pixel change.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.Interval <= 0 {
+				return fmt.Errorf("interval must be positive: %s", o.Interval)
+			}
+
+			ticker := time.NewTicker(o.Interval)
+			defer ticker.Stop()
+			wait := func() {
+				select {
+				case <-ticker.C:
+				case <-f.Context().Done():
+				}
+			}
+			return watchGraph(cmd, f, o, wait)
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().DurationVar(&o.Interval, "interval", 60*time.Second, "Polling interval")
+	cmd.Flags().BoolVar(&o.NDJSON, "ndjson", false, "Emit the events as newline delimited JSON")
+	cmd.Flags().IntVar(&o.Count, "count", 0, "Number of polls before exiting (default 0, poll forever)")
+
+	return cmd
+}
+
+// watchSnapshot is the state of the graph observed by a poll.
+type watchSnapshot struct {
+	TodaysQuantity   int         `json:"todaysQuantity"`
+	TotalQuantity    int         `json:"totalQuantity"`
+	TotalPixelsCount int         `json:"totalPixelsCount"`
+	Latest           *graphPixel `json:"latest"`
+}
+
+type watchEvent struct {
+	Time    time.Time     `json:"time"`
+	ID      string        `json:"id"`
+	Changed []string      `json:"changed"`
+	Current watchSnapshot `json:"current"`
+}
+
+// watchGraph polls the graph count times, or forever when count is 0, and calls wait between the polls.
+// The first poll is always reported. A failed poll is reported to stderr and the watch goes on.
+// The watch ends without an error when the command is cancelled.
+func watchGraph(cmd *cobra.Command, f *pixelaClientFactory, o *graphOptions, wait func()) error {
+	ctx := f.Context()
+	id, count := o.ID, o.Count
+	var prev *watchSnapshot
+	for i := 0; count <= 0 || i < count; i++ {
+		if i > 0 {
+			wait()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		s, err := pollGraph(ctx, f, id)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", time.Now().Format(time.RFC3339), err)
+			continue
+		}
+
+		changed := s.changedFrom(prev)
+		prev = s
+		if len(changed) == 0 {
+			continue
+		}
+		if err := printWatchEvent(cmd, o, &watchEvent{Time: time.Now(), ID: id, Changed: changed, Current: *s}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pollGraph gets the statistics and the latest Pixel of the graph.
+func pollGraph(ctx context.Context, f *pixelaClientFactory, id string) (*watchSnapshot, error) {
+	stats, err := f.Graph().StatsWithContext(ctx, &pixela.GraphStatsInput{ID: pixela.String(id)})
+	if err != nil {
+		return nil, fmt.Errorf("graph stats failed: %w", err)
+	}
+	if !stats.IsSuccess {
+		return nil, fmt.Errorf("graph stats failed: %s", stats.Message)
+	}
+
+	s := &watchSnapshot{
+		TodaysQuantity:   stats.TodaysQuantity,
+		TotalQuantity:    stats.TotalQuantity,
+		TotalPixelsCount: stats.TotalPixelsCount,
+	}
+	if stats.TotalPixelsCount == 0 {
+		return s, nil
+	}
+
+	pixel, err := f.Graph().GetLatestPixelWithContext(ctx, &pixela.GraphGetLatestPixelInput{ID: pixela.String(id)})
+	if err != nil {
+		return nil, fmt.Errorf("graph get latest pixel failed: %w", err)
+	}
+	if !pixel.IsSuccess {
+		return nil, fmt.Errorf("graph get latest pixel failed: %s", pixel.Message)
+	}
+	s.Latest = &graphPixel{Date: pixel.Date, Quantity: pixel.Quantity, OptionalData: pixel.OptionalData}
+	return s, nil
+}
+
+// changedFrom returns the names of the fields changed from prev. All fields are changed when prev is nil.
+func (s *watchSnapshot) changedFrom(prev *watchSnapshot) []string {
+	if prev == nil {
+		return []string{"todaysQuantity", "totalQuantity", "totalPixelsCount", "latest"}
+	}
+
+	changed := []string{}
+	if s.TodaysQuantity != prev.TodaysQuantity {
+		changed = append(changed, "todaysQuantity")
+	}
+	if s.TotalQuantity != prev.TotalQuantity {
+		changed = append(changed, "totalQuantity")
+	}
+	if s.TotalPixelsCount != prev.TotalPixelsCount {
+		changed = append(changed, "totalPixelsCount")
+	}
+	if (s.Latest == nil) != (prev.Latest == nil) || (s.Latest != nil && *s.Latest != *prev.Latest) {
+		changed = append(changed, "latest")
+	}
+	return changed
+}
+
+func printWatchEvent(cmd *cobra.Command, o *graphOptions, e *watchEvent) error {
+	if o.NDJSON {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("marshal graph watch event failed: %w", err)
+		}
+		cmd.Printf("%s\n", string(b))
+		return nil
+	}
+
+	latest := "-"
+	if e.Current.Latest != nil {
+		latest = e.Current.Latest.Date + ":" + e.Current.Latest.Quantity
+	}
+	cmd.Printf("%s %s today=%d total=%d pixels=%d latest=%s changed=%s\n",
+		e.Time.Format(time.RFC3339), e.ID, e.Current.TodaysQuantity, e.Current.TotalQuantity,
+		e.Current.TotalPixelsCount, latest, strings.Join(e.Changed, ","))
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphWatch(t *testing.T) {
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "graph-id"}, pixela.PixelWithBody{Date: "20200101", Quantity: "5"})
+
+	// 2 回目のポーリングは変化なし、3 回目で Pixel を追加する
+	polls := 0
+	wait := func() {
+		polls++
+		if polls == 2 {
+			fake.pixels["graph-id"]["20200102"] = pixela.PixelWithBody{Date: "20200102", Quantity: "3"}
+		}
+	}
+
+	o := &graphOptions{}
+	c := NewCmdGraphWatch(f, o)
+	buffer := bytes.NewBuffer([]byte{})
+	c.SetOut(buffer)
+	assert.NoError(t, c.Flags().Set("ndjson", "true"))
+
+	o.ID, o.Count = "graph-id", 3
+	assert.NoError(t, watchGraph(c, f, o, wait))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var first, second watchEvent
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, "graph-id", first.ID)
+	assert.Equal(t, 5, first.Current.TotalQuantity)
+	assert.Equal(t, &graphPixel{Date: "20200101", Quantity: "5"}, first.Current.Latest)
+	assert.Equal(t, []string{"totalQuantity", "totalPixelsCount", "latest"}, second.Changed)
+	assert.Equal(t, 8, second.Current.TotalQuantity)
+	assert.Equal(t, 2, second.Current.TotalPixelsCount)
+	assert.Equal(t, &graphPixel{Date: "20200102", Quantity: "3"}, second.Current.Latest)
+}
+
+func TestGraphWatchText(t *testing.T) {
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "graph-id"})
+
+	o := &graphOptions{}
+	c := NewCmdGraphWatch(f, o)
+	buffer := bytes.NewBuffer([]byte{})
+	errBuffer := bytes.NewBuffer([]byte{})
+	c.SetOut(buffer)
+	c.SetErr(errBuffer)
+
+	o.ID, o.Count = "graph-id", 1
+	assert.NoError(t, watchGraph(c, f, o, func() {}))
+	assert.Contains(t, buffer.String(), " graph-id today=0 total=0 pixels=0 latest=- changed=")
+
+	buffer.Reset()
+	o.ID, o.Count = "unknown-id", 1
+	assert.NoError(t, watchGraph(c, f, o, func() {}))
+	assert.Empty(t, buffer.String())
+	assert.Contains(t, errBuffer.String(), "graph stats failed: Specified graphID not exist.")
+}
+
+func TestGraphWatchTimeout(t *testing.T) {
+	fake := newPixelaFake()
+	fake.addGraph(pixela.GraphDefinition{ID: "graph-id"})
+	f := fake.factory()
+
+	cmd := newCmdRoot(f)
+	buffer := bytes.NewBuffer([]byte{})
+	cmd.SetOut(buffer)
+	cmd.SetArgs(strings.Split("--timeout=50ms graph watch --id=graph-id --interval=1h", " "))
+
+	start := time.Now()
+	err := cmd.Execute()
+
+	assert.NoError(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Minute))
+	assert.Contains(t, buffer.String(), " graph-id today=0 total=0 pixels=0 latest=- changed=")
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// hookMarker identifies the hooks installed by pa so that they can be overwritten safely.
+const hookMarker = "# installed by pa hooks install"
+
+// NewCmdHooks creates a hooks command.
+func NewCmdHooks(f *pixelaClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Git hooks",
+		Args:  cobra.NoArgs,
+		RunE:  showHelp,
+	}
+
+	cmd.AddCommand(NewCmdHooksInstall(f, &gitOptions{}))
+
+	return cmd
+}
+
+// NewCmdHooksInstall creates an install hooks command.
+func NewCmdHooksInstall(f *pixelaClientFactory, o *gitOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install a post-commit hook which records the commits to the Graph",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateGitMetric(o.Metric); err != nil {
+				return err
+			}
+
+			path, err := installPostCommitHook(f, o.Repo, o.Graph, o.Metric, o.Force)
+			if err != nil {
+				return fmt.Errorf("hooks install failed: %w", err)
+			}
+			cmd.Printf("%s\n", path)
+			return nil
+		},
+	}
+
+	addGitFlags(cmd, o)
+	cmd.Flags().BoolVar(&o.Force, "force", false, "Overwrite the post-commit hook not installed by pa")
+
+	return cmd
+}
+
+// installPostCommitHook writes the post-commit hook, or prints the path of the hook to write in the dry run.
+func installPostCommitHook(f *pixelaClientFactory, repo, graph, metric string, force bool) (string, error) {
+	out, err := exec.Command("git", "-C", repo, "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", repo, err)
+	}
+	dir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repo, dir)
+	}
+	path := filepath.Join(dir, "post-commit")
+
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if err == nil && !strings.Contains(string(b), hookMarker) && !force {
+		return "", fmt.Errorf("%s already exists, specify '--force' to overwrite it", path)
+	}
+
+	pa, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	script := fmt.Sprintf("#!/bin/sh\n%s\nexec %s git record --graph %s --metric %s\n",
+		hookMarker, shellQuote(pa), shellQuote(graph), shellQuote(metric))
+
+	if f.dryRun != nil {
+		printFileChange(f.dryRun, "WRITE", path)
+		return path, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return "", err
+	}
+	// 既存のファイルは WriteFile でパーミッションが変わらないので実行可能にする
+	if err := os.Chmod(path, 0755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
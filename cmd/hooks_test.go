@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooksInstall(t *testing.T) {
+	repo := setupGitRepo(t)
+	hook := filepath.Join(repo, ".git", "hooks", "post-commit")
+
+	out, err := executeGit(t, newPixelaClientFactory(), "hooks", "install", "--repo", repo, "--graph", "commits", "--metric", "lines-added")
+	assert.NoError(t, err)
+	assert.Equal(t, hook+"\n", out)
+
+	b, err := os.ReadFile(hook)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(b), "#!/bin/sh\n"+hookMarker+"\nexec '"))
+	assert.True(t, strings.HasSuffix(string(b), "' git record --graph 'commits' --metric 'lines-added'\n"))
+	info, err := os.Stat(hook)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	// pa がインストールしたフックは上書きできる
+	_, err = executeGit(t, newPixelaClientFactory(), "hooks", "install", "--repo", repo, "--graph", "commits")
+	assert.NoError(t, err)
+}
+
+func TestHooksInstallExistingHook(t *testing.T) {
+	repo := setupGitRepo(t)
+	hook := filepath.Join(repo, ".git", "hooks", "post-commit")
+	writeFile(t, hook, "#!/bin/sh\necho mine\n")
+
+	_, err := executeGit(t, newPixelaClientFactory(), "hooks", "install", "--repo", repo, "--graph", "commits")
+	assert.EqualError(t, err, "hooks install failed: "+hook+" already exists, specify '--force' to overwrite it")
+
+	_, err = executeGit(t, newPixelaClientFactory(), "hooks", "install", "--repo", repo, "--graph", "commits", "--force")
+	assert.NoError(t, err)
+	b, err := os.ReadFile(hook)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), hookMarker)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'graph'`, shellQuote("graph"))
+	assert.Equal(t, `'/path/with space/it'\''s/pa'`, shellQuote("/path/with space/it's/pa"))
+}
@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ebc-2in2crc/pa/pkg/pa"
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+type historyOptions struct {
+	Limit int
+}
+
+// journalEntry is a mutation recorded in the undo journal with the state before it.
+// Date is empty for the mutations of the graph definition.
+type journalEntry struct {
+	ID        int           `json:"id"`
+	Time      time.Time     `json:"time"`
+	Username  string        `json:"username"`
+	Operation string        `json:"operation"`
+	GraphID   string        `json:"graphId"`
+	Date      string        `json:"date,omitempty"`
+	Pixel     *quantity     `json:"pixel,omitempty"`
+	Graph     *journalGraph `json:"graph,omitempty"`
+	// Undo is the ID of the entry which this entry undid
+	Undo int `json:"undo,omitempty"`
+}
+
+// journalGraph is the updatable part of the graph definition.
+type journalGraph struct {
+	Name                string   `json:"name"`
+	Unit                string   `json:"unit"`
+	Color               string   `json:"color"`
+	TimeZone            string   `json:"timezone"`
+	PurgeCacheURLs      []string `json:"purgeCacheURLs"`
+	SelfSufficient      string   `json:"selfSufficient"`
+	IsSecret            bool     `json:"isSecret"`
+	PublishOptionalData bool     `json:"publishOptionalData"`
+}
+
+// NewCmdHistory creates a history command.
+func NewCmdHistory() *cobra.Command {
+	o := &historyOptions{}
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the recent mutations recorded in the undo journal",
+		Long:  "List the recent mutations recorded in the undo journal, newest first. 'pixel update', 'pixel delete' and 'graph update' are recorded with the state before them.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := readJournal()
+			if err != nil {
+				return err
+			}
+			undone := undoneEntries(entries)
+
+			type historyEntry struct {
+				*journalEntry
+				Undone bool `json:"undone"`
+			}
+			history := []historyEntry{}
+			for i := len(entries) - 1; i >= 0; i-- {
+				if o.Limit > 0 && len(history) >= o.Limit {
+					break
+				}
+				history = append(history, historyEntry{journalEntry: entries[i], Undone: undone[entries[i].ID]})
+			}
+
+			b, err := json.Marshal(&struct {
+				History []historyEntry `json:"history"`
+			}{History: history})
+			if err != nil {
+				return fmt.Errorf("marshal history failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&o.Limit, "limit", 20, "The maximum number of the entries, 0 lists all")
+
+	return cmd
+}
+
+// NewCmdUndo creates an undo command.
+func NewCmdUndo(f *pixelaClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "undo [id]",
+		Short: "Restore the state before the mutation recorded in the undo journal",
+		Long:  "Restore the state before the mutation recorded in the undo journal. Without the ID, the latest mutation which is not undone yet is undone. An undo is recorded as well, so undoing it by the ID redoes the mutation.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := readJournal()
+			if err != nil {
+				return err
+			}
+			entry, err := findUndoEntry(entries, args)
+			if err != nil {
+				return err
+			}
+			if username := f.Username(); entry.Username != username {
+				return fmt.Errorf("journal entry %d was recorded for user %s, not %s", entry.ID, entry.Username, username)
+			}
+
+			result, err := undoJournalEntry(f, entry)
+			if err != nil {
+				return reportError(cmd, err, "undo failed")
+			}
+			s, err := marshalResult(result)
+			if err != nil {
+				return fmt.Errorf("marshal undo result failed: %w", err)
+			}
+			cmd.Printf("%s\n", s)
+
+			if !result.IsSuccess {
+				return ErrNeglect
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func findUndoEntry(entries []*journalEntry, args []string) (*journalEntry, error) {
+	undone := undoneEntries(entries)
+	if len(args) == 0 {
+		for i := len(entries) - 1; i >= 0; i-- {
+			// 引数が無いときは undo 自体を除いて遡る
+			if entries[i].Undo == 0 && !undone[entries[i].ID] {
+				return entries[i], nil
+			}
+		}
+		return nil, errors.New("nothing to undo")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid journal entry id: %s", args[0])
+	}
+	for _, e := range entries {
+		if e.ID != id {
+			continue
+		}
+		if undone[id] {
+			return nil, fmt.Errorf("journal entry %d is already undone", id)
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("journal entry not found: %d", id)
+}
+
+// undoneEntries returns the IDs of the entries which have been undone.
+func undoneEntries(entries []*journalEntry) map[int]bool {
+	undone := map[int]bool{}
+	for _, e := range entries {
+		if e.Undo != 0 {
+			undone[e.Undo] = true
+		}
+	}
+	return undone
+}
+
+// undoJournalEntry restores the state recorded in the entry, and records the undo with the state before it.
+func undoJournalEntry(f *pixelaClientFactory, entry *journalEntry) (*pixela.Result, error) {
+	undo := &journalEntry{Operation: "undo", GraphID: entry.GraphID, Date: entry.Date, Undo: entry.ID}
+
+	var result *pixela.Result
+	if entry.Date == "" {
+		before, err := graphBefore(f, entry.GraphID)
+		if err != nil {
+			return nil, err
+		}
+		undo.Graph = before
+
+		// 空の値に戻せるように、空のフィールドも省略せずに送る
+		g := entry.Graph
+		result, err = f.Graph().UpdateWithContext(f.Context(), &pixela.GraphUpdateInput{
+			ID:                  pixela.String(entry.GraphID),
+			Name:                getStringPtr(g.Name),
+			Unit:                getStringPtr(g.Unit),
+			Color:               getStringPtr(g.Color),
+			TimeZone:            getStringPtr(g.TimeZone),
+			PurgeCacheURLs:      g.PurgeCacheURLs,
+			SelfSufficient:      getStringPtr(g.SelfSufficient),
+			IsSecret:            pixela.Bool(g.IsSecret),
+			PublishOptionalData: pixela.Bool(g.PublishOptionalData),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("graph update failed: %w", err)
+		}
+		// pixela4go は空の purgeCacheURLs を送らないので別のリクエストで消す
+		if result.IsSuccess && len(g.PurgeCacheURLs) == 0 && len(before.PurgeCacheURLs) > 0 {
+			result, err = f.Graph().ClearPurgeCacheURLsWithContext(f.Context(), &pa.GraphClearPurgeCacheURLsInput{ID: pixela.String(entry.GraphID)})
+			if err != nil {
+				return nil, fmt.Errorf("graph update failed: %w", err)
+			}
+		}
+	} else {
+		before, err := pixelBefore(f, entry.GraphID, entry.Date)
+		if err != nil {
+			return nil, err
+		}
+		undo.Pixel = before
+
+		if entry.Pixel == nil {
+			// 変更前に Pixel が無かったときは削除して戻す
+			result, err = f.Pixel().DeleteWithContext(f.Context(), &pixela.PixelDeleteInput{
+				GraphID: pixela.String(entry.GraphID),
+				Date:    pixela.String(entry.Date),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("pixel delete failed: %w", err)
+			}
+		} else if before == nil {
+			// 変更後に削除された Pixel は作成して戻す
+			result, err = f.Pixel().CreateWithContext(f.Context(), &pixela.PixelCreateInput{
+				GraphID:      pixela.String(entry.GraphID),
+				Date:         pixela.String(entry.Date),
+				Quantity:     pixela.String(entry.Pixel.Quantity),
+				OptionalData: getStringPtr(entry.Pixel.OptionalData),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("pixel create failed: %w", err)
+			}
+		} else {
+			// 既にある Pixel は POST では置き換えられないので PUT で戻す
+			// 後から追加された optionalData を消せるように、空の optionalData も送る
+			result, err = f.Pixel().UpdateWithContext(f.Context(), &pixela.PixelUpdateInput{
+				GraphID:      pixela.String(entry.GraphID),
+				Date:         pixela.String(entry.Date),
+				Quantity:     pixela.String(entry.Pixel.Quantity),
+				OptionalData: pixela.String(entry.Pixel.OptionalData),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("pixel update failed: %w", err)
+			}
+		}
+	}
+
+	if !result.IsSuccess {
+		return result, nil
+	}
+	if err := recordJournal(f, undo); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// pixelBefore returns the Pixel before the mutation, or nil when it does not exist.
+func pixelBefore(f *pixelaClientFactory, graphID, date string) (*quantity, error) {
+	q, err := f.Pixel().GetWithContext(f.Context(), &pixela.PixelGetInput{GraphID: pixela.String(graphID), Date: pixela.String(date)})
+	if err != nil {
+		return nil, fmt.Errorf("pixel get failed: %w", err)
+	}
+	if !q.IsSuccess {
+		if q.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, &resultError{Result: &q.Result}
+	}
+	return &quantity{Quantity: q.Quantity, OptionalData: q.OptionalData}, nil
+}
+
+// graphBefore returns the graph definition before the mutation.
+func graphBefore(f *pixelaClientFactory, id string) (*journalGraph, error) {
+	def, err := f.Graph().GetWithContext(f.Context(), &pixela.GraphGetInput{ID: pixela.String(id)})
+	if err != nil {
+		return nil, fmt.Errorf("graph get failed: %w", err)
+	}
+	if !def.IsSuccess {
+		return nil, &resultError{Result: &def.Result}
+	}
+	return &journalGraph{
+		Name:                def.Name,
+		Unit:                def.Unit,
+		Color:               def.Color,
+		TimeZone:            def.TimeZone,
+		PurgeCacheURLs:      def.PurgeCacheURLs,
+		SelfSufficient:      def.SelfSufficient,
+		IsSecret:            def.IsSecret,
+		PublishOptionalData: def.PublishOptionalData,
+	}, nil
+}
+
+// journalMu serializes the records of the commands run concurrently, so that the IDs are not duplicated.
+var journalMu sync.Mutex
+
+// recordJournal appends the entry to the undo journal. It records nothing in the dry run.
+func recordJournal(f *pixelaClientFactory, entry *journalEntry) error {
+	if f.dryRun != nil {
+		return nil
+	}
+	journalMu.Lock()
+	defer journalMu.Unlock()
+
+	entries, err := readJournal()
+	if err != nil {
+		return err
+	}
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+	entry.Time = f.now()
+	entry.Username = f.Username()
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal journal entry failed: %w", err)
+	}
+	path, err := stateFile("journal.jsonl")
+	if err != nil {
+		return fmt.Errorf("get journal failed: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open journal failed: %w", err)
+	}
+	if _, err := file.Write(append(b, '\n')); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("write journal failed: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write journal failed: %w", err)
+	}
+	return nil
+}
+
+// readJournal returns the entries of the undo journal, oldest first.
+func readJournal() ([]*journalEntry, error) {
+	path, err := stateFile("journal.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("get journal failed: %w", err)
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*journalEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open journal failed: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	entries := []*journalEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("read journal failed: %w", err)
+		}
+		entries = append(entries, &e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read journal failed: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func executeJournal(t *testing.T, f *pixelaClientFactory, args ...string) (string, error) {
+	t.Helper()
+	cmd := newCmdRoot(f)
+	out := bytes.NewBuffer([]byte{})
+	cmd.SetOut(out)
+	cmd.SetArgs(append([]string{"--username", "alice", "--token", "secret"}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestJournalUndo(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	f.now = func() time.Time { return time.Date(2026, 1, 3, 12, 0, 0, 0, time.UTC) }
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee", Name: "coffee", Unit: "cup", Color: "shibafu"},
+		pixela.PixelWithBody{Date: "20260101", Quantity: "3", OptionalData: `{"note":"a"}`},
+		pixela.PixelWithBody{Date: "20260102", Quantity: "4"},
+	)
+
+	_, err := executeJournal(t, f, "pixel", "update", "--graph-id", "coffee", "--date", "20260101", "--quantity", "5", "--optional-data", `{"note":"b"}`)
+	assert.NoError(t, err)
+	_, err = executeJournal(t, f, "pixel", "delete", "--graph-id", "coffee", "--date", "20260102")
+	assert.NoError(t, err)
+	_, err = executeJournal(t, f, "graph", "update", "--id", "coffee", "--name", "tea", "--color", "momiji")
+	assert.NoError(t, err)
+
+	out, err := executeJournal(t, f, "history", "--limit", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"history":[`+
+		`{"id":3,"time":"2026-01-03T12:00:00Z","username":"alice","operation":"graph update","graphId":"coffee","graph":{"name":"coffee","unit":"cup","color":"shibafu","timezone":"","purgeCacheURLs":null,"selfSufficient":"","isSecret":false,"publishOptionalData":false},"undone":false},`+
+		`{"id":2,"time":"2026-01-03T12:00:00Z","username":"alice","operation":"pixel delete","graphId":"coffee","date":"20260102","pixel":{"quantity":"4","optionalData":""},"undone":false}]}`+"\n", out)
+
+	// ID を省略すると最新の変更を元に戻す
+	_, err = executeJournal(t, f, "undo")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", fake.definitions["coffee"].Name)
+	assert.Equal(t, "shibafu", fake.definitions["coffee"].Color)
+
+	_, err = executeJournal(t, f, "undo", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", fake.pixels["coffee"]["20260101"].Quantity)
+	assert.Equal(t, `{"note":"a"}`, fake.pixels["coffee"]["20260101"].OptionalData)
+
+	_, err = executeJournal(t, f, "undo")
+	assert.NoError(t, err)
+	assert.Equal(t, "4", fake.pixels["coffee"]["20260102"].Quantity)
+
+	_, err = executeJournal(t, f, "undo", "1")
+	assert.EqualError(t, err, "journal entry 1 is already undone")
+	_, err = executeJournal(t, f, "undo", "9")
+	assert.EqualError(t, err, "journal entry not found: 9")
+
+	// undo を元に戻すとやり直しになる
+	_, err = executeJournal(t, f, "undo", "5")
+	assert.NoError(t, err)
+	assert.Equal(t, "5", fake.pixels["coffee"]["20260101"].Quantity)
+
+	out, err = executeJournal(t, f, "history", "--limit", "0")
+	assert.NoError(t, err)
+	var history struct {
+		History []struct {
+			ID     int  `json:"id"`
+			Undo   int  `json:"undo"`
+			Undone bool `json:"undone"`
+		} `json:"history"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(out), &history))
+	assert.Len(t, history.History, 7)
+	assert.Equal(t, 5, history.History[0].Undo)
+	assert.True(t, history.History[2].Undone)
+}
+
+func TestJournalUndoCreatedPixel(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee"})
+
+	// 変更前に無かった Pixel は undo で削除する
+	_, err := executeJournal(t, f, "pixel", "update", "--graph-id", "coffee", "--date", "20260101", "--quantity", "5")
+	assert.NoError(t, err)
+	assert.Contains(t, fake.pixels["coffee"], "20260101")
+
+	_, err = executeJournal(t, f, "undo")
+	assert.NoError(t, err)
+	assert.NotContains(t, fake.pixels["coffee"], "20260101")
+
+	cmd := newCmdRoot(f)
+	cmd.SetOut(bytes.NewBuffer([]byte{}))
+	cmd.SetArgs([]string{"--username", "bob", "--token", "secret", "undo", "2"})
+	assert.EqualError(t, cmd.Execute(), "journal entry 2 was recorded for user alice, not bob")
+}
+
+func TestJournalUndoEmptyGraphFields(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee", Name: "coffee"})
+
+	_, err := executeJournal(t, f, "graph", "update", "--id", "coffee", "--timezone", "Asia/Tokyo", "--purge-cache-urls", "https://example.com/badge")
+	assert.NoError(t, err)
+
+	// 空の timezone は送らず、空の purgeCacheURLs は別のリクエストで消す
+	out, err := executeJournal(t, f, "--dry-run", "undo")
+	assert.NoError(t, err)
+	assert.Contains(t, out, `PUT /v1/users/alice/graphs/coffee {"name":"coffee","isSecret":false,"publishOptionalData":false}`+"\n")
+	assert.Contains(t, out, `PUT /v1/users/alice/graphs/coffee {"purgeCacheURLs":[]}`+"\n")
+
+	_, err = executeJournal(t, f, "undo")
+	assert.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", fake.definitions["coffee"].TimeZone)
+	assert.Empty(t, fake.definitions["coffee"].PurgeCacheURLs)
+}
+
+func TestJournalUndoEmptyOptionalData(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee"}, pixela.PixelWithBody{Date: "20260101", Quantity: "3"})
+
+	_, err := executeJournal(t, f, "pixel", "update", "--graph-id", "coffee", "--date", "20260101", "--quantity", "5", "--optional-data", `{"note":"b"}`)
+	assert.NoError(t, err)
+
+	// 後から追加された optionalData も消して戻す
+	_, err = executeJournal(t, f, "undo")
+	assert.NoError(t, err)
+	assert.Equal(t, pixela.PixelWithBody{Date: "20260101", Quantity: "3"}, fake.pixels["coffee"]["20260101"])
+}
+
+func TestJournalDryRun(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee"}, pixela.PixelWithBody{Date: "20260101", Quantity: "3"})
+
+	_, err := executeJournal(t, f, "--dry-run", "pixel", "delete", "--graph-id", "coffee", "--date", "20260101")
+	assert.NoError(t, err)
+
+	_, err = executeJournal(t, f, "undo")
+	assert.EqualError(t, err, "nothing to undo")
+}
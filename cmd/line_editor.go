@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// errLineInterrupted is returned by lineEditor.readLine when the line is discarded by Ctrl-C.
+var errLineInterrupted = errors.New("interrupted")
+
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyBackspace = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCR        = 13
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// lineEditor reads a line from the terminal in the raw mode with the history and the tab completion.
+type lineEditor struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// history は古い順の入力履歴
+	history []string
+	// complete returns the candidates for the last word of the line
+	complete func(line string) []string
+
+	prompt string
+	buf    []rune
+	pos    int
+}
+
+func newLineEditor(in io.Reader, out io.Writer, complete func(line string) []string) *lineEditor {
+	return &lineEditor{in: bufio.NewReader(in), out: out, complete: complete}
+}
+
+// readLine reads a line after printing the prompt. It returns io.EOF on Ctrl-D at the empty line.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	e.prompt, e.buf, e.pos = prompt, nil, 0
+	// 履歴をたどる間も入力中の行を失わないように末尾に置いておく
+	hist := append(append([]string{}, e.history...), "")
+	h := len(hist) - 1
+	e.refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyCR, keyLF:
+			fmt.Fprint(e.out, "\r\n")
+			return string(e.buf), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errLineInterrupted
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.buf = append(e.buf[:e.pos-1], e.buf[e.pos:]...)
+				e.pos--
+			}
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.buf)
+		case keyCtrlK:
+			e.buf = e.buf[:e.pos]
+		case keyCtrlU:
+			e.buf = append([]rune{}, e.buf[e.pos:]...)
+			e.pos = 0
+		case keyTab:
+			e.completeWord()
+		case keyEscape:
+			key, err := e.readEscape()
+			if err != nil {
+				return "", err
+			}
+			switch key {
+			case 'A':
+				if h > 0 {
+					hist[h] = string(e.buf)
+					h--
+					e.setLine(hist[h])
+				}
+			case 'B':
+				if h < len(hist)-1 {
+					hist[h] = string(e.buf)
+					h++
+					e.setLine(hist[h])
+				}
+			case 'C':
+				if e.pos < len(e.buf) {
+					e.pos++
+				}
+			case 'D':
+				if e.pos > 0 {
+					e.pos--
+				}
+			case 'H':
+				e.pos = 0
+			case 'F':
+				e.pos = len(e.buf)
+			}
+		default:
+			if !unicode.IsPrint(r) {
+				continue
+			}
+			e.buf = append(e.buf[:e.pos], append([]rune{r}, e.buf[e.pos:]...)...)
+			e.pos++
+		}
+		e.refresh()
+	}
+}
+
+// readEscape reads the rest of the escape sequence of the arrow keys, and returns its final byte.
+func (e *lineEditor) readEscape() (byte, error) {
+	b, err := e.in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != '[' && b != 'O' {
+		return 0, nil
+	}
+	for {
+		b, err = e.in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		// ESC [ 1 ; 5 C のようなパラメーターは読み飛ばす
+		if b >= 0x40 && b <= 0x7e {
+			return b, nil
+		}
+	}
+}
+
+// addHistory adds the line to the history unless it is empty or the same as the last one,
+// and reports whether it is added.
+func (e *lineEditor) addHistory(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return false
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return false
+	}
+	e.history = append(e.history, line)
+	return true
+}
+
+func (e *lineEditor) setLine(line string) {
+	e.buf = []rune(line)
+	e.pos = len(e.buf)
+}
+
+// completeWord completes the word before the cursor at the end of the line.
+// The common prefix of the candidates is filled in, and the candidates are listed when it is ambiguous.
+func (e *lineEditor) completeWord() {
+	if e.complete == nil || e.pos != len(e.buf) {
+		return
+	}
+	line := string(e.buf)
+	candidates := e.complete(line)
+	if len(candidates) == 0 {
+		return
+	}
+
+	word := line[strings.LastIndexAny(line, " \t")+1:]
+	prefix := commonPrefix(candidates)
+	if len(candidates) == 1 && !strings.HasSuffix(prefix, "=") {
+		prefix += " "
+	}
+	if len(prefix) > len(word) {
+		e.setLine(line[:len(line)-len(word)] + prefix)
+		return
+	}
+	if len(candidates) > 1 {
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+// refresh redraws the prompt and the line, and moves the cursor to its position.
+func (e *lineEditor) refresh() {
+	fmt.Fprintf(e.out, "\r\x1b[K%s%s", e.prompt, string(e.buf))
+	if n := len(e.buf) - e.pos; n > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", n)
+	}
+}
+
+func commonPrefix(ss []string) string {
+	prefix := ss[0]
+	for _, s := range ss[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
+	"github.com/ebc-2in2crc/pa/pkg/pa"
 	pixela "github.com/ebc-2in2crc/pixela4go"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -13,96 +18,260 @@ import (
 // ErrNeglect is error that had reported by pa.
 var ErrNeglect = errors.New("neglect")
 
+// exitError is error that exits with the exit status of the command run by pa.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
 type pixelaClientFactory struct {
 	user    pixelaUser
 	profile pixelaUserProfile
 	graph   pixelaGraph
 	pixel   pixelaPixel
 	webhook pixelaWebhook
-}
 
-type pixelaUser interface {
-	Create(input *pixela.UserCreateInput) (*pixela.Result, error)
-	Update(input *pixela.UserUpdateInput) (*pixela.Result, error)
-	Delete() (*pixela.Result, error)
-}
+	// username と token が空のときは config の設定を使う
+	username string
+	token    string
 
-type pixelaUserProfile interface {
-	Update(input *pixela.UserProfileUpdateInput) (*pixela.Result, error)
-	URL() string
-}
+	// dryRun が nil でないときは更新系の API を呼ばずにリクエストを dryRun に出力する
+	dryRun io.Writer
 
-type pixelaGraph interface {
-	Create(input *pixela.GraphCreateInput) (*pixela.Result, error)
-	GetAll() (*pixela.GraphDefinitions, error)
-	Get(input *pixela.GraphGetInput) (*pixela.GraphDefinition, error)
-	GetSVG(input *pixela.GraphGetSVGInput) (string, error)
-	URL(input *pixela.GraphURLInput) string
-	Stats(input *pixela.GraphStatsInput) (*pixela.Stats, error)
-	Update(input *pixela.GraphUpdateInput) (*pixela.Result, error)
-	Delete(input *pixela.GraphDeleteInput) (*pixela.Result, error)
-	GetPixelDates(input *pixela.GraphGetPixelDatesInput) (*pixela.Pixels, error)
-	Stopwatch(input *pixela.GraphStopwatchInput) (*pixela.Result, error)
-	Add(input *pixela.GraphAddInput) (*pixela.Result, error)
-	Subtract(input *pixela.GraphSubtractInput) (*pixela.Result, error)
-	GetLatestPixel(input *pixela.GraphGetLatestPixelInput) (*pixela.GraphPixel, error)
-}
+	// config はフラグと環境変数、設定ファイルを束ねたコマンドの実行ごとの設定
+	config *viper.Viper
+	// configFile は --config で指定された設定ファイル
+	configFile string
+	// configInherited が true のときは親のファクトリーで読んだ設定を使い、設定ファイルを読み直さない
+	configInherited bool
+
+	// ctx はコマンドの実行のコンテキストで、SIGINT と SIGTERM、--timeout でキャンセルされる
+	ctx    context.Context
+	cancel context.CancelFunc
 
-type pixelaPixel interface {
-	Create(input *pixela.PixelCreateInput) (*pixela.Result, error)
-	Increment(input *pixela.PixelIncrementInput) (*pixela.Result, error)
-	Decrement(input *pixela.PixelDecrementInput) (*pixela.Result, error)
-	Get(input *pixela.PixelGetInput) (*pixela.Quantity, error)
-	Update(input *pixela.PixelUpdateInput) (*pixela.Result, error)
-	Delete(input *pixela.PixelDeleteInput) (*pixela.Result, error)
+	// pool は一括操作のリクエストを並行に実行するプールで、nil のときは 1 つずつ実行する
+	pool *pa.Pool
+	// limiter は --rate のリクエストの間隔を空けるリミッターで、ファクトリーが作る API に渡す
+	limiter *pa.Limiter
+
+	// 以下は時刻や端末、子プロセスに依存する処理で、テストで置き換える
+	// now は現在時刻を返す
+	now func() time.Time
+	// stdinIsTerminal は標準入力が端末かどうかを返す
+	stdinIsTerminal func() bool
+	// sleep は d だけ待つか、ctx が終わったらそのエラーを返す
+	sleep func(ctx context.Context, d time.Duration) error
+	// runScheduledCommand はスケジュールされたジョブの pa を子プロセスで実行する
+	runScheduledCommand func(ctx context.Context, cmd *cobra.Command, args []string) error
 }
 
-type pixelaWebhook interface {
-	Create(input *pixela.WebhookCreateInput) (*pixela.WebhookCreateResult, error)
-	GetAll() (*pixela.WebhookDefinitions, error)
-	Invoke(input *pixela.WebhookInvokeInput) (*pixela.Result, error)
-	Delete(input *pixela.WebhookDeleteInput) (*pixela.Result, error)
+// newPixelaClientFactory returns a factory with its own configuration, so that the commands built on it don't share state.
+func newPixelaClientFactory() *pixelaClientFactory {
+	v := viper.New()
+	v.SetEnvPrefix("pa")
+	v.AutomaticEnv()
+	return &pixelaClientFactory{
+		config:              v,
+		now:                 time.Now,
+		stdinIsTerminal:     isStdinTerminal,
+		sleep:               sleepContext,
+		runScheduledCommand: runPa,
+	}
 }
 
+// Pixela の API は pa パッケージのインターフェースを使う
+type (
+	pixelaUser        = pa.User
+	pixelaUserProfile = pa.UserProfile
+	pixelaGraph       = pa.Graph
+	pixelaPixel       = pa.Pixel
+	pixelaWebhook     = pa.Webhook
+)
+
 func (p *pixelaClientFactory) User() pixelaUser {
-	if p.user != nil {
-		return p.user
+	var c pixelaUser = p.user
+	if c == nil {
+		c = pa.FromPixela(p.client()).User
+	}
+	c = p.policy().User(c)
+	if p.dryRun != nil {
+		return &dryRunUser{pixelaUser: c, runner: p.dryRunner()}
 	}
-	return pixela.New(getUsername(), getToken()).User()
+	return c
 }
 
 func (p *pixelaClientFactory) UserProfile() pixelaUserProfile {
-	if p.profile != nil {
-		return p.profile
+	var c pixelaUserProfile = p.profile
+	if c == nil {
+		c = pa.FromPixela(p.client()).UserProfile
 	}
-	return pixela.New(getUsername(), getToken()).UserProfile()
+	c = p.policy().UserProfile(c)
+	if p.dryRun != nil {
+		return &dryRunUserProfile{pixelaUserProfile: c, runner: p.dryRunner()}
+	}
+	return c
 }
 
 func (p *pixelaClientFactory) Graph() pixelaGraph {
-	if p.graph != nil {
-		return p.graph
+	var c pixelaGraph = p.graph
+	if c == nil {
+		c = pa.FromPixela(p.client()).Graph
+	}
+	c = p.policy().Graph(c)
+	if p.dryRun != nil {
+		return &dryRunGraph{pixelaGraph: c, runner: p.dryRunner()}
 	}
-	return pixela.New(getUsername(), getToken()).Graph()
+	return c
 }
 
 func (p *pixelaClientFactory) Pixel() pixelaPixel {
-	if p.pixel != nil {
-		return p.pixel
+	var c pixelaPixel = p.pixel
+	if c == nil {
+		c = pa.FromPixela(p.client()).Pixel
 	}
-	return pixela.New(getUsername(), getToken()).Pixel()
+	c = p.policy().Pixel(c)
+	if p.dryRun != nil {
+		return &dryRunPixel{pixelaPixel: c, runner: p.dryRunner()}
+	}
+	return c
 }
 
 func (p *pixelaClientFactory) Webhook() pixelaWebhook {
-	if p.webhook != nil {
-		return p.webhook
+	var c pixelaWebhook = p.webhook
+	if c == nil {
+		c = pa.FromPixela(p.client()).Webhook
+	}
+	c = p.policy().Webhook(c)
+	if p.dryRun != nil {
+		return &dryRunWebhook{pixelaWebhook: c, runner: p.dryRunner()}
+	}
+	return c
+}
+
+// Client returns the pa.Client built on the APIs of the factory, so that the mocks and the dry run apply to it.
+func (p *pixelaClientFactory) Client() *pa.Client {
+	return &pa.Client{
+		User:        p.User(),
+		UserProfile: p.UserProfile(),
+		Graph:       p.Graph(),
+		Pixel:       p.Pixel(),
+		Webhook:     p.Webhook(),
+		Pool:        p.pool,
+	}
+}
+
+// policy returns how the APIs of the factory send their requests by --rate and --retry.
+func (p *pixelaClientFactory) policy() pa.RequestPolicy {
+	return pa.RequestPolicy{Limiter: p.limiter, Retry: p.Retry()}
+}
+
+func (p *pixelaClientFactory) dryRunner() *dryRunner {
+	return &dryRunner{out: p.dryRun, username: p.Username()}
+}
+
+func (p *pixelaClientFactory) client() *pixela.Client {
+	if p.username != "" && p.token != "" {
+		return pixela.New(p.username, p.token)
+	}
+	return pixela.New(p.Username(), p.Token())
+}
+
+// Username returns the user name of the account of the factory.
+func (p *pixelaClientFactory) Username() string {
+	if p.username != "" {
+		return p.username
+	}
+	return p.config.GetString("username")
+}
+
+// Token returns the token of the account of the factory.
+func (p *pixelaClientFactory) Token() string {
+	if p.token != "" {
+		return p.token
+	}
+	return p.config.GetString("token")
+}
+
+// Retry returns the number of retries when the API call is rejected.
+func (p *pixelaClientFactory) Retry() int {
+	return p.config.GetInt("retry")
+}
+
+// Concurrency returns the number of the requests of the bulk operations which run at the same time.
+func (p *pixelaClientFactory) Concurrency() int {
+	return p.config.GetInt("concurrency")
+}
+
+// Rate returns the maximum rate of the requests, such as 5/s. It is empty when the rate is not limited.
+func (p *pixelaClientFactory) Rate() string {
+	return p.config.GetString("rate")
+}
+
+// Profile returns a factory for the account defined in the "profiles.<name>" section of the config file.
+func (p *pixelaClientFactory) Profile(name string) (*pixelaClientFactory, error) {
+	username := p.config.GetString("profiles." + name + ".username")
+	token := p.config.GetString("profiles." + name + ".token")
+	if username == "" || token == "" {
+		return nil, fmt.Errorf("profile not found: %s", name)
 	}
-	return pixela.New(getUsername(), getToken()).Webhook()
+
+	return &pixelaClientFactory{
+		user:       p.user,
+		profile:    p.profile,
+		graph:      p.graph,
+		pixel:      p.pixel,
+		webhook:    p.webhook,
+		username:   username,
+		token:      token,
+		dryRun:     p.dryRun,
+		config:     p.config,
+		configFile: p.configFile,
+		ctx:        p.ctx,
+		pool:       p.pool,
+		limiter:    p.limiter,
+
+		now:                 p.now,
+		stdinIsTerminal:     p.stdinIsTerminal,
+		sleep:               p.sleep,
+		runScheduledCommand: p.runScheduledCommand,
+	}, nil
 }
 
-var pixelaClient = pixelaClientFactory{}
+// child returns a factory for a command run by pa itself, such as a line of the shell.
+// It shares the APIs and the configuration with the factory, so the config file is not read again.
+// It also shares the pool and the rate limit, so they apply to all the commands together.
+func (p *pixelaClientFactory) child() *pixelaClientFactory {
+	c := newPixelaClientFactory()
+	_ = c.config.MergeConfigMap(p.config.AllSettings())
+	c.config.SetConfigFile(p.config.ConfigFileUsed())
+	c.configInherited = true
+	c.user = p.user
+	c.profile = p.profile
+	c.graph = p.graph
+	c.pixel = p.pixel
+	c.webhook = p.webhook
+	c.pool = p.pool
+	c.limiter = p.limiter
+	c.now = p.now
+	c.stdinIsTerminal = p.stdinIsTerminal
+	c.sleep = p.sleep
+	c.runScheduledCommand = p.runScheduledCommand
+	return c
+}
 
-func getBoolFlag(name string) *bool {
-	return getBoolPtr(viper.GetBool(name))
+// writeState writes v to the state file. It writes nothing in the dry run.
+func (p *pixelaClientFactory) writeState(path string, v interface{}) error {
+	if p.dryRun != nil {
+		return nil
+	}
+	return writeStateJSON(path, v)
+}
+
+func (p *pixelaClientFactory) getBoolFlag(name string) *bool {
+	return getBoolPtr(p.config.GetBool(name))
 }
 
 func getBoolPtr(v bool) *bool {
@@ -112,8 +281,8 @@ func getBoolPtr(v bool) *bool {
 	return &v
 }
 
-func getStringFlag(name string) *string {
-	return getStringPtr(viper.GetString(name))
+func (p *pixelaClientFactory) getStringFlag(name string) *string {
+	return getStringPtr(p.config.GetString(name))
 }
 
 func getStringPtr(v string) *string {
@@ -127,6 +296,66 @@ func showHelp(cmd *cobra.Command, args []string) error {
 	return cmd.Help()
 }
 
+// resultError is an error that reports the API call was not successful.
+type resultError = pa.ResultError
+
+// Context returns the context of the command execution, or the background context before the execution.
+func (p *pixelaClientFactory) Context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+// withContext sets the context of the command execution, which is cancelled after timeout when it is positive.
+func (p *pixelaClientFactory) withContext(ctx context.Context, timeout time.Duration) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p.release()
+	if timeout > 0 {
+		p.ctx, p.cancel = context.WithTimeout(ctx, timeout)
+		return
+	}
+	p.ctx = ctx
+}
+
+// release releases the resources of the context set by withContext.
+func (p *pixelaClientFactory) release() {
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+}
+
+// reportError prints the result when err is a resultError, otherwise it wraps err with msg.
+func reportError(cmd *cobra.Command, err error, msg string) error {
+	var re *resultError
+	if !errors.As(err, &re) {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+
+	s, err := marshalResult(re.Result)
+	if err != nil {
+		return fmt.Errorf("marshal result failed: %w", err)
+	}
+	cmd.Printf("%s\n", s)
+	return ErrNeglect
+}
+
+// resultMessage returns the message of the result printed last in the output of a command,
+// or the last line when it is not a result.
+func resultMessage(output string) string {
+	// --dry-run のときはリクエストの後に結果が出力されるので最後の行を使う
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	last := lines[len(lines)-1]
+	var result pixela.Result
+	if json.Unmarshal([]byte(last), &result) != nil || result.Message == "" {
+		return last
+	}
+	return result.Message
+}
+
 func marshalResult(result *pixela.Result) (string, error) {
 	b, err := json.Marshal(result)
 	if err != nil {
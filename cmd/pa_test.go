@@ -0,0 +1,429 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ebc-2in2crc/pa/pkg/pa"
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// pixelaFake is an in-memory Pixela which keeps graphs, pixels and webhooks.
+type pixelaFake struct {
+	definitions map[string]pixela.GraphDefinition
+	pixels      map[string]map[string]pixela.PixelWithBody
+	webhooks    []pixela.WebhookDefinition
+	// failDates are the dates that the pixel API call is not successful
+	failDates map[string]bool
+	// added are the calls of Graph().Add, Pixel().Increment and Webhook().Invoke
+	// as "graphID:quantity", "graphID:increment" and "webhook:hash"
+	added []string
+}
+
+func newPixelaFake() *pixelaFake {
+	return &pixelaFake{
+		definitions: map[string]pixela.GraphDefinition{},
+		pixels:      map[string]map[string]pixela.PixelWithBody{},
+		failDates:   map[string]bool{},
+	}
+}
+
+// factory returns a client factory whose graph, pixel and webhook APIs are the fake.
+func (f *pixelaFake) factory() *pixelaClientFactory {
+	c := newPixelaClientFactory()
+	c.graph = &pixelaFakeGraph{f}
+	c.pixel = &pixelaFakePixel{f}
+	c.webhook = &pixelaFakeWebhook{f}
+	// go test の標準入力が端末でも確認を求めない
+	c.stdinIsTerminal = func() bool { return false }
+	return c
+}
+
+func (f *pixelaFake) addGraph(def pixela.GraphDefinition, pixels ...pixela.PixelWithBody) {
+	f.definitions[def.ID] = def
+	f.pixels[def.ID] = map[string]pixela.PixelWithBody{}
+	for _, p := range pixels {
+		f.pixels[def.ID][p.Date] = p
+	}
+}
+
+func (f *pixelaFake) sortedPixels(id string) []pixela.PixelWithBody {
+	result := []pixela.PixelWithBody{}
+	for _, p := range f.pixels[id] {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+	return result
+}
+
+func successResult() *pixela.Result {
+	return &pixela.Result{Message: "Success.", IsSuccess: true, StatusCode: http.StatusOK}
+}
+
+func notFoundResult(msg string) *pixela.Result {
+	return &pixela.Result{Message: msg, StatusCode: http.StatusNotFound}
+}
+
+type pixelaFakeGraph struct {
+	*pixelaFake
+}
+
+func (g *pixelaFakeGraph) CreateWithContext(ctx context.Context, input *pixela.GraphCreateInput) (*pixela.Result, error) {
+	id := pixela.StringValue(input.ID)
+	if _, ok := g.definitions[id]; ok {
+		return &pixela.Result{Message: "This graphID already exist.", StatusCode: http.StatusBadRequest}, nil
+	}
+	g.addGraph(pixela.GraphDefinition{
+		ID:                  id,
+		Name:                pixela.StringValue(input.Name),
+		Unit:                pixela.StringValue(input.Unit),
+		Type:                pixela.StringValue(input.Type),
+		Color:               pixela.StringValue(input.Color),
+		TimeZone:            pixela.StringValue(input.TimeZone),
+		SelfSufficient:      pixela.StringValue(input.SelfSufficient),
+		IsSecret:            pixela.BoolValue(input.IsSecret),
+		PublishOptionalData: pixela.BoolValue(input.PublishOptionalData),
+	})
+	return successResult(), nil
+}
+
+func (g *pixelaFakeGraph) GetAllWithContext(ctx context.Context) (*pixela.GraphDefinitions, error) {
+	defs := &pixela.GraphDefinitions{Graphs: []pixela.GraphDefinition{}, Result: *successResult()}
+	for _, d := range g.definitions {
+		defs.Graphs = append(defs.Graphs, d)
+	}
+	sort.Slice(defs.Graphs, func(i, j int) bool { return defs.Graphs[i].ID < defs.Graphs[j].ID })
+	return defs, nil
+}
+
+func (g *pixelaFakeGraph) GetWithContext(ctx context.Context, input *pixela.GraphGetInput) (*pixela.GraphDefinition, error) {
+	d, ok := g.definitions[pixela.StringValue(input.ID)]
+	if !ok {
+		return &pixela.GraphDefinition{Result: *notFoundResult("Specified graphID not exist.")}, nil
+	}
+	d.Result = *successResult()
+	return &d, nil
+}
+
+func (g *pixelaFakeGraph) GetSVGWithContext(ctx context.Context, input *pixela.GraphGetSVGInput) (string, error) {
+	return "<svg></svg>", nil
+}
+
+func (g *pixelaFakeGraph) URL(input *pixela.GraphURLInput) string {
+	return "https://pixe.la/v1/users/pa/graphs/" + pixela.StringValue(input.ID) + ".html"
+}
+
+func (g *pixelaFakeGraph) StatsWithContext(ctx context.Context, input *pixela.GraphStatsInput) (*pixela.Stats, error) {
+	id := pixela.StringValue(input.ID)
+	if _, ok := g.definitions[id]; !ok {
+		return &pixela.Stats{Result: *notFoundResult("Specified graphID not exist.")}, nil
+	}
+	stats := &pixela.Stats{Result: *successResult()}
+	for _, p := range g.pixels[id] {
+		q, _ := strconv.Atoi(p.Quantity)
+		stats.TotalPixelsCount++
+		stats.TotalQuantity += q
+	}
+	return stats, nil
+}
+
+func (g *pixelaFakeGraph) UpdateWithContext(ctx context.Context, input *pixela.GraphUpdateInput) (*pixela.Result, error) {
+	id := pixela.StringValue(input.ID)
+	d, ok := g.definitions[id]
+	if !ok {
+		return notFoundResult("Specified graphID not exist."), nil
+	}
+	if input.Name != nil {
+		d.Name = *input.Name
+	}
+	if input.Unit != nil {
+		d.Unit = *input.Unit
+	}
+	if input.Color != nil {
+		d.Color = *input.Color
+	}
+	if input.TimeZone != nil {
+		d.TimeZone = *input.TimeZone
+	}
+	// pixela4go は空の purgeCacheURLs を送らない
+	if len(input.PurgeCacheURLs) > 0 {
+		d.PurgeCacheURLs = input.PurgeCacheURLs
+	}
+	if input.SelfSufficient != nil {
+		d.SelfSufficient = *input.SelfSufficient
+	}
+	if input.IsSecret != nil {
+		d.IsSecret = *input.IsSecret
+	}
+	if input.PublishOptionalData != nil {
+		d.PublishOptionalData = *input.PublishOptionalData
+	}
+	g.definitions[id] = d
+	return successResult(), nil
+}
+
+func (g *pixelaFakeGraph) ClearPurgeCacheURLsWithContext(ctx context.Context, input *pa.GraphClearPurgeCacheURLsInput) (*pixela.Result, error) {
+	id := pixela.StringValue(input.ID)
+	d, ok := g.definitions[id]
+	if !ok {
+		return notFoundResult("Specified graphID not exist."), nil
+	}
+	d.PurgeCacheURLs = nil
+	g.definitions[id] = d
+	return successResult(), nil
+}
+
+func (g *pixelaFakeGraph) DeleteWithContext(ctx context.Context, input *pixela.GraphDeleteInput) (*pixela.Result, error) {
+	id := pixela.StringValue(input.ID)
+	if _, ok := g.definitions[id]; !ok {
+		return notFoundResult("Specified graphID not exist."), nil
+	}
+	delete(g.definitions, id)
+	delete(g.pixels, id)
+	return successResult(), nil
+}
+
+func (g *pixelaFakeGraph) GetPixelDatesWithContext(ctx context.Context, input *pixela.GraphGetPixelDatesInput) (*pixela.Pixels, error) {
+	id := pixela.StringValue(input.ID)
+	if _, ok := g.definitions[id]; !ok {
+		return &pixela.Pixels{Result: *notFoundResult("Specified graphID not exist.")}, nil
+	}
+	from := pixela.StringValue(input.From)
+	to := pixela.StringValue(input.To)
+
+	bodies := []pixela.PixelWithBody{}
+	dates := []string{}
+	for _, p := range g.sortedPixels(id) {
+		if (from != "" && p.Date < from) || (to != "" && p.Date > to) {
+			continue
+		}
+		bodies = append(bodies, p)
+		dates = append(dates, p.Date)
+	}
+	if pixela.BoolValue(input.WithBody) {
+		return &pixela.Pixels{Pixels: bodies, Result: pixela.Result{IsSuccess: true}}, nil
+	}
+	return &pixela.Pixels{Pixels: dates, Result: pixela.Result{IsSuccess: true}}, nil
+}
+
+func (g *pixelaFakeGraph) StopwatchWithContext(ctx context.Context, input *pixela.GraphStopwatchInput) (*pixela.Result, error) {
+	return successResult(), nil
+}
+
+func (g *pixelaFakeGraph) AddWithContext(ctx context.Context, input *pixela.GraphAddInput) (*pixela.Result, error) {
+	g.added = append(g.added, pixela.StringValue(input.ID)+":"+pixela.StringValue(input.Quantity))
+	return successResult(), nil
+}
+
+func (g *pixelaFakeGraph) SubtractWithContext(ctx context.Context, input *pixela.GraphSubtractInput) (*pixela.Result, error) {
+	return successResult(), nil
+}
+
+func (g *pixelaFakeGraph) GetLatestPixelWithContext(ctx context.Context, input *pixela.GraphGetLatestPixelInput) (*pixela.GraphPixel, error) {
+	pixels := g.sortedPixels(pixela.StringValue(input.ID))
+	if len(pixels) == 0 {
+		return &pixela.GraphPixel{Result: *notFoundResult("Specified pixel not found.")}, nil
+	}
+	p := pixels[len(pixels)-1]
+	return &pixela.GraphPixel{Date: p.Date, Quantity: p.Quantity, OptionalData: p.OptionalData, Result: *successResult()}, nil
+}
+
+type pixelaFakePixel struct {
+	*pixelaFake
+}
+
+func (p *pixelaFakePixel) put(graphID, date, quantity string, optionalData *string) *pixela.Result {
+	if _, ok := p.definitions[graphID]; !ok {
+		return notFoundResult("Specified graphID not exist.")
+	}
+	if p.failDates[date] {
+		return &pixela.Result{Message: "Please retry this request.", IsRejected: true, StatusCode: http.StatusServiceUnavailable}
+	}
+	px := p.pixels[graphID][date]
+	px.Date = date
+	if quantity != "" {
+		px.Quantity = quantity
+	}
+	if optionalData != nil {
+		px.OptionalData = *optionalData
+	}
+	p.pixels[graphID][date] = px
+	return successResult()
+}
+
+func (p *pixelaFakePixel) CreateWithContext(ctx context.Context, input *pixela.PixelCreateInput) (*pixela.Result, error) {
+	// 既にある Pixel は POST では置き換えない
+	if _, ok := p.pixels[pixela.StringValue(input.GraphID)][pixela.StringValue(input.Date)]; ok {
+		return &pixela.Result{Message: "This date pixel already exists.", StatusCode: http.StatusConflict}, nil
+	}
+	return p.put(pixela.StringValue(input.GraphID), pixela.StringValue(input.Date), pixela.StringValue(input.Quantity), input.OptionalData), nil
+}
+
+func (p *pixelaFakePixel) IncrementWithContext(ctx context.Context, input *pixela.PixelIncrementInput) (*pixela.Result, error) {
+	p.added = append(p.added, pixela.StringValue(input.GraphID)+":increment")
+	return successResult(), nil
+}
+
+func (p *pixelaFakePixel) DecrementWithContext(ctx context.Context, input *pixela.PixelDecrementInput) (*pixela.Result, error) {
+	return successResult(), nil
+}
+
+func (p *pixelaFakePixel) GetWithContext(ctx context.Context, input *pixela.PixelGetInput) (*pixela.Quantity, error) {
+	px, ok := p.pixels[pixela.StringValue(input.GraphID)][pixela.StringValue(input.Date)]
+	if !ok {
+		return &pixela.Quantity{Result: *notFoundResult("Specified pixel not found.")}, nil
+	}
+	return &pixela.Quantity{Quantity: px.Quantity, OptionalData: px.OptionalData, Result: *successResult()}, nil
+}
+
+func (p *pixelaFakePixel) UpdateWithContext(ctx context.Context, input *pixela.PixelUpdateInput) (*pixela.Result, error) {
+	return p.put(pixela.StringValue(input.GraphID), pixela.StringValue(input.Date), pixela.StringValue(input.Quantity), input.OptionalData), nil
+}
+
+func (p *pixelaFakePixel) DeleteWithContext(ctx context.Context, input *pixela.PixelDeleteInput) (*pixela.Result, error) {
+	graphID := pixela.StringValue(input.GraphID)
+	date := pixela.StringValue(input.Date)
+	if _, ok := p.pixels[graphID][date]; !ok {
+		return notFoundResult("Specified pixel not found."), nil
+	}
+	delete(p.pixels[graphID], date)
+	return successResult(), nil
+}
+
+type pixelaFakeWebhook struct {
+	*pixelaFake
+}
+
+func (w *pixelaFakeWebhook) CreateWithContext(ctx context.Context, input *pixela.WebhookCreateInput) (*pixela.WebhookCreateResult, error) {
+	graphID := pixela.StringValue(input.GraphID)
+	hash := "hash-" + graphID + "-" + pixela.StringValue(input.Type)
+	w.webhooks = append(w.webhooks, pixela.WebhookDefinition{
+		WebhookHash: hash,
+		GraphID:     graphID,
+		Type:        pixela.StringValue(input.Type),
+	})
+	return &pixela.WebhookCreateResult{WebhookHash: hash, Result: *successResult()}, nil
+}
+
+func (w *pixelaFakeWebhook) GetAllWithContext(ctx context.Context) (*pixela.WebhookDefinitions, error) {
+	whs := append([]pixela.WebhookDefinition{}, w.webhooks...)
+	return &pixela.WebhookDefinitions{Webhooks: whs, Result: *successResult()}, nil
+}
+
+func (w *pixelaFakeWebhook) InvokeWithContext(ctx context.Context, input *pixela.WebhookInvokeInput) (*pixela.Result, error) {
+	for _, wh := range w.webhooks {
+		if wh.WebhookHash == pixela.StringValue(input.WebhookHash) {
+			w.added = append(w.added, "webhook:"+wh.WebhookHash)
+			return successResult(), nil
+		}
+	}
+	return notFoundResult("Specified webhook not exist."), nil
+}
+
+func (w *pixelaFakeWebhook) DeleteWithContext(ctx context.Context, input *pixela.WebhookDeleteInput) (*pixela.Result, error) {
+	for i, wh := range w.webhooks {
+		if wh.WebhookHash == pixela.StringValue(input.WebhookHash) {
+			w.webhooks = append(w.webhooks[:i], w.webhooks[i+1:]...)
+			return successResult(), nil
+		}
+	}
+	return notFoundResult("Specified webhook not exist."), nil
+}
+
+func TestReportError(t *testing.T) {
+	params := []struct {
+		err           error
+		expectedError error
+		expected      string
+	}{
+		{
+			err:           &resultError{Result: notFoundResult("Specified graphID not exist.")},
+			expectedError: ErrNeglect,
+			expected:      `{"message":"Specified graphID not exist.","isSuccess":false,"isRejected":false,"statusCode":404}` + "\n",
+		},
+		{
+			err:           errors.New("some error occur"),
+			expectedError: nil,
+			expected:      "",
+		},
+	}
+
+	for _, p := range params {
+		c := &cobra.Command{}
+		buffer := bytes.NewBuffer([]byte{})
+		c.SetOut(buffer)
+
+		err := reportError(c, p.err, "failed")
+
+		assert.Equal(t, p.expected, buffer.String())
+		if p.expectedError != nil {
+			assert.Equal(t, p.expectedError, err)
+		} else {
+			assert.EqualError(t, err, "failed: some error occur")
+		}
+	}
+}
+
+func TestPixelaClientFactoryProfile(t *testing.T) {
+	f := newPixelaClientFactory()
+	f.config.Set("profiles", map[string]interface{}{
+		"other": map[string]interface{}{"username": "other-user", "token": "other-token"},
+	})
+
+	p, err := f.Profile("other")
+	assert.NoError(t, err)
+	assert.Equal(t, "other-user", p.client().UserName)
+	assert.Equal(t, "other-token", p.client().Token)
+
+	_, err = f.Profile("unknown")
+	assert.EqualError(t, err, "profile not found: unknown")
+}
+
+func TestPixelaClientFactoryChild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	assert.NoError(t, os.WriteFile(path, []byte("username = \"config-user\"\nretry = 3\n"), 0600))
+	f := newPixelaClientFactory()
+	f.configFile = path
+	assert.NoError(t, initConfig(f))
+
+	// 子のファクトリーは設定ファイルを読み直さない
+	assert.NoError(t, os.Remove(path))
+	c := f.child()
+	assert.NoError(t, initConfig(c))
+	assert.Equal(t, "config-user", c.Username())
+	assert.Equal(t, 3, c.Retry())
+	assert.Equal(t, path, c.config.ConfigFileUsed())
+
+	c.configFile = filepath.Join(filepath.Dir(path), "other.toml")
+	assert.Error(t, initConfig(c))
+}
+
+func TestPixelaClientFactoryHooks(t *testing.T) {
+	f := newPixelaClientFactory()
+	f.config.Set("profiles", map[string]interface{}{
+		"other": map[string]interface{}{"username": "other-user", "token": "other-token"},
+	})
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	f.stdinIsTerminal = func() bool { return true }
+
+	// 子のファクトリーとプロファイルも同じ時計と端末を使う
+	p, err := f.Profile("other")
+	assert.NoError(t, err)
+	for _, c := range []*pixelaClientFactory{f.child(), p} {
+		assert.Equal(t, now, c.now())
+		assert.True(t, c.stdinIsTerminal())
+		assert.NotNil(t, c.sleep)
+		assert.NotNil(t, c.runScheduledCommand)
+	}
+}
@@ -8,15 +8,17 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var pixelOptions = &struct {
-	GraphID      string
-	Date         string
-	Quantity     string
-	OptionalData string
-}{}
+type pixelOptions struct {
+	GraphID          string
+	Date             string
+	Quantity         string
+	OptionalData     string
+	OptionalDataFile string
+	Data             []string
+}
 
 // NewCmdPixel creates a pixel command.
-func NewCmdPixel() *cobra.Command {
+func NewCmdPixel(f *pixelaClientFactory) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "pixel",
 		Short: "Pixel",
@@ -24,25 +26,26 @@ func NewCmdPixel() *cobra.Command {
 		RunE:  showHelp,
 	}
 
-	cmd.AddCommand(NewCmdPixelCreate())
-	cmd.AddCommand(NewCmdPixelIncrement())
-	cmd.AddCommand(NewCmdPixelDecrement())
-	cmd.AddCommand(NewCmdPixelGet())
-	cmd.AddCommand(NewCmdPixelUpdate())
-	cmd.AddCommand(NewCmdPixelDelete())
+	cmd.AddCommand(NewCmdPixelCreate(f, &pixelOptions{}))
+	cmd.AddCommand(NewCmdPixelIncrement(f, &pixelOptions{}))
+	cmd.AddCommand(NewCmdPixelDecrement(f, &pixelOptions{}))
+	cmd.AddCommand(NewCmdPixelGet(f, &pixelOptions{}))
+	cmd.AddCommand(NewCmdPixelUpdate(f, &pixelOptions{}))
+	cmd.AddCommand(NewCmdPixelDelete(f, &pixelOptions{}))
 
 	return cmd
 }
 
 // NewCmdPixelCreate creates a create pixel command.
-func NewCmdPixelCreate() *cobra.Command {
+func NewCmdPixelCreate(f *pixelaClientFactory, o *pixelOptions) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "create",
-		Short: "Create Pixel",
-		Args:  cobra.NoArgs,
+		Use:     "create",
+		Short:   "Create Pixel",
+		Args:    cobra.NoArgs,
+		PreRunE: preparePixelOptionalData(f, o),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createPixelCreateInput()
-			result, err := pixelaClient.Pixel().Create(input)
+			input := createPixelCreateInput(o)
+			result, err := f.Pixel().CreateWithContext(f.Context(), input)
 			if err != nil {
 
 				return fmt.Errorf("pixel create failed: %w", err)
@@ -60,33 +63,35 @@ func NewCmdPixelCreate() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&pixelOptions.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("graph-id")
-	cmd.Flags().StringVar(&pixelOptions.Date, "date", "", "The date on which the quantity is to be recorded")
-	cmd.Flags().StringVar(&pixelOptions.Quantity, "quantity", "", "The quantity to be registered on the specified date")
-	cmd.Flags().StringVar(&pixelOptions.OptionalData, "optional-data", "", "Additional information other than quantity")
+	cmd.Flags().StringVar(&o.Date, "date", "", "The date on which the quantity is to be recorded")
+	cmd.Flags().StringVar(&o.Quantity, "quantity", "", "The quantity to be registered on the specified date")
+	cmd.Flags().StringVar(&o.OptionalData, "optional-data", "", "Additional information other than quantity")
+	cmd.Flags().StringVar(&o.OptionalDataFile, "optional-data-file", "", "The file of the optional data, '-' reads from stdin")
+	cmd.Flags().StringArrayVar(&o.Data, "data", []string{}, "Set a key=value property of the optional data")
 
 	return cmd
 }
 
-func createPixelCreateInput() *pixela.PixelCreateInput {
+func createPixelCreateInput(o *pixelOptions) *pixela.PixelCreateInput {
 	return &pixela.PixelCreateInput{
-		GraphID:      getStringPtr(pixelOptions.GraphID),
-		Date:         getStringPtr(pixelOptions.Date),
-		Quantity:     getStringPtr(pixelOptions.Quantity),
-		OptionalData: getStringPtr(pixelOptions.OptionalData),
+		GraphID:      getStringPtr(o.GraphID),
+		Date:         getStringPtr(o.Date),
+		Quantity:     getStringPtr(o.Quantity),
+		OptionalData: getStringPtr(o.OptionalData),
 	}
 }
 
 // NewCmdPixelIncrement creates a increment pixel command.
-func NewCmdPixelIncrement() *cobra.Command {
+func NewCmdPixelIncrement(f *pixelaClientFactory, o *pixelOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "increment",
 		Short: "Increment quantity 'Pixel' of the day",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createPixelIncrementInput()
-			result, err := pixelaClient.Pixel().Increment(input)
+			input := createPixelIncrementInput(o)
+			result, err := f.Pixel().IncrementWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("pixel increment failed: %w", err)
 			}
@@ -103,27 +108,27 @@ func NewCmdPixelIncrement() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&pixelOptions.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("graph-id")
 
 	return cmd
 }
 
-func createPixelIncrementInput() *pixela.PixelIncrementInput {
+func createPixelIncrementInput(o *pixelOptions) *pixela.PixelIncrementInput {
 	return &pixela.PixelIncrementInput{
-		GraphID: getStringPtr(pixelOptions.GraphID),
+		GraphID: getStringPtr(o.GraphID),
 	}
 }
 
 // NewCmdPixelDecrement creates a decrement pixel command.
-func NewCmdPixelDecrement() *cobra.Command {
+func NewCmdPixelDecrement(f *pixelaClientFactory, o *pixelOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "decrement",
 		Short: "Decrement quantity 'Pixel' of the day",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createPixelDecrementInput()
-			result, err := pixelaClient.Pixel().Decrement(input)
+			input := createPixelDecrementInput(o)
+			result, err := f.Pixel().DecrementWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("pixel decrement failed: %w", err)
 			}
@@ -140,27 +145,27 @@ func NewCmdPixelDecrement() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&pixelOptions.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("graph-id")
 
 	return cmd
 }
 
-func createPixelDecrementInput() *pixela.PixelDecrementInput {
+func createPixelDecrementInput(o *pixelOptions) *pixela.PixelDecrementInput {
 	return &pixela.PixelDecrementInput{
-		GraphID: getStringPtr(pixelOptions.GraphID),
+		GraphID: getStringPtr(o.GraphID),
 	}
 }
 
 // NewCmdPixelGet creates a get pixel command.
-func NewCmdPixelGet() *cobra.Command {
+func NewCmdPixelGet(f *pixelaClientFactory, o *pixelOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get",
 		Short: "Get registered quantity as 'Pixel'",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createPixelGetInput()
-			q, err := pixelaClient.Pixel().Get(input)
+			input := createPixelGetInput(o)
+			q, err := f.Pixel().GetWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("pixel get failed: %w", err)
 			}
@@ -184,18 +189,18 @@ func NewCmdPixelGet() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&pixelOptions.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("graph-id")
-	cmd.Flags().StringVar(&pixelOptions.Date, "date", "", "The date on which the quantity is to be recorded")
+	cmd.Flags().StringVar(&o.Date, "date", "", "The date on which the quantity is to be recorded")
 	_ = cmd.MarkFlagRequired("date")
 
 	return cmd
 }
 
-func createPixelGetInput() *pixela.PixelGetInput {
+func createPixelGetInput(o *pixelOptions) *pixela.PixelGetInput {
 	return &pixela.PixelGetInput{
-		GraphID: getStringPtr(pixelOptions.GraphID),
-		Date:    getStringPtr(pixelOptions.Date),
+		GraphID: getStringPtr(o.GraphID),
+		Date:    getStringPtr(o.Date),
 	}
 }
 
@@ -205,14 +210,19 @@ type quantity struct {
 }
 
 // NewCmdPixelUpdate creates a update pixel command.
-func NewCmdPixelUpdate() *cobra.Command {
+func NewCmdPixelUpdate(f *pixelaClientFactory, o *pixelOptions) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "update",
-		Short: "Update Pixel",
-		Args:  cobra.NoArgs,
+		Use:     "update",
+		Short:   "Update Pixel",
+		Args:    cobra.NoArgs,
+		PreRunE: preparePixelOptionalData(f, o),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createPixelUpdateInput()
-			result, err := pixelaClient.Pixel().Update(input)
+			input := createPixelUpdateInput(o)
+			before, err := pixelBefore(f, o.GraphID, o.Date)
+			if err != nil {
+				return reportError(cmd, err, "pixel update failed")
+			}
+			result, err := f.Pixel().UpdateWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("pixel update failed: %w", err)
 			}
@@ -225,38 +235,44 @@ func NewCmdPixelUpdate() *cobra.Command {
 			if !result.IsSuccess {
 				return ErrNeglect
 			}
-			return nil
+			return recordJournal(f, &journalEntry{Operation: "pixel update", GraphID: o.GraphID, Date: o.Date, Pixel: before})
 		},
 	}
 
-	cmd.Flags().StringVar(&pixelOptions.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("graph-id")
-	cmd.Flags().StringVar(&pixelOptions.Date, "date", "", "The date on which the quantity is to be recorded")
+	cmd.Flags().StringVar(&o.Date, "date", "", "The date on which the quantity is to be recorded")
 	_ = cmd.MarkFlagRequired("date")
-	cmd.Flags().StringVar(&pixelOptions.Quantity, "quantity", "", "The quantity to be registered on the specified date")
-	cmd.Flags().StringVar(&pixelOptions.OptionalData, "optional-data", "", "Additional information other than quantity")
+	cmd.Flags().StringVar(&o.Quantity, "quantity", "", "The quantity to be registered on the specified date")
+	cmd.Flags().StringVar(&o.OptionalData, "optional-data", "", "Additional information other than quantity")
+	cmd.Flags().StringVar(&o.OptionalDataFile, "optional-data-file", "", "The file of the optional data, '-' reads from stdin")
+	cmd.Flags().StringArrayVar(&o.Data, "data", []string{}, "Set a key=value property of the optional data")
 
 	return cmd
 }
 
-func createPixelUpdateInput() *pixela.PixelUpdateInput {
+func createPixelUpdateInput(o *pixelOptions) *pixela.PixelUpdateInput {
 	return &pixela.PixelUpdateInput{
-		GraphID:      getStringPtr(pixelOptions.GraphID),
-		Date:         getStringPtr(pixelOptions.Date),
-		Quantity:     getStringPtr(pixelOptions.Quantity),
-		OptionalData: getStringPtr(pixelOptions.OptionalData),
+		GraphID:      getStringPtr(o.GraphID),
+		Date:         getStringPtr(o.Date),
+		Quantity:     getStringPtr(o.Quantity),
+		OptionalData: getStringPtr(o.OptionalData),
 	}
 }
 
 // NewCmdPixelDelete creates a delete pixel command.
-func NewCmdPixelDelete() *cobra.Command {
+func NewCmdPixelDelete(f *pixelaClientFactory, o *pixelOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "delete",
 		Short: "Delete Pixel",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createPixelDeleteInput()
-			result, err := pixelaClient.Pixel().Delete(input)
+			input := createPixelDeleteInput(o)
+			before, err := pixelBefore(f, o.GraphID, o.Date)
+			if err != nil {
+				return reportError(cmd, err, "pixel delete failed")
+			}
+			result, err := f.Pixel().DeleteWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("pixel delete failed: %w", err)
 			}
@@ -269,21 +285,21 @@ func NewCmdPixelDelete() *cobra.Command {
 			if !result.IsSuccess {
 				return ErrNeglect
 			}
-			return nil
+			return recordJournal(f, &journalEntry{Operation: "pixel delete", GraphID: o.GraphID, Date: o.Date, Pixel: before})
 		},
 	}
 
-	cmd.Flags().StringVar(&pixelOptions.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
 	_ = cmd.MarkFlagRequired("graph-id")
-	cmd.Flags().StringVar(&pixelOptions.Date, "date", "", "The date on which the quantity is to be recorded")
+	cmd.Flags().StringVar(&o.Date, "date", "", "The date on which the quantity is to be recorded")
 	_ = cmd.MarkFlagRequired("date")
 
 	return cmd
 }
 
-func createPixelDeleteInput() *pixela.PixelDeleteInput {
+func createPixelDeleteInput(o *pixelOptions) *pixela.PixelDeleteInput {
 	return &pixela.PixelDeleteInput{
-		GraphID: getStringPtr(pixelOptions.GraphID),
-		Date:    getStringPtr(pixelOptions.Date),
+		GraphID: getStringPtr(o.GraphID),
+		Date:    getStringPtr(o.Date),
 	}
 }
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+// maxOptionalDataSize is the maximum size of the optionalData accepted by Pixela.
+const maxOptionalDataSize = 10 * 1024
+
+// preparePixelOptionalData returns the PreRunE which builds the optionalData from the flags
+// and validates it before calling the API.
+func preparePixelOptionalData(f *pixelaClientFactory, o *pixelOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		od, err := buildOptionalData(cmd.InOrStdin(), o.OptionalData, o.OptionalDataFile, o.Data)
+		if err != nil {
+			return fmt.Errorf("build optional data failed: %w", err)
+		}
+		if od == "" {
+			return nil
+		}
+		if err := validateOptionalData(f, o.GraphID, od); err != nil {
+			return fmt.Errorf("invalid optional data: %w", err)
+		}
+
+		o.OptionalData = od
+		return nil
+	}
+}
+
+// buildOptionalData returns the optionalData given by --optional-data or --optional-data-file,
+// overwriting its properties with --data key=value.
+func buildOptionalData(stdin io.Reader, raw, file string, data []string) (string, error) {
+	if raw != "" && file != "" {
+		return "", errors.New("specify either '--optional-data' or '--optional-data-file'")
+	}
+
+	base := raw
+	if file != "" {
+		b, err := readOptionalDataFile(stdin, file)
+		if err != nil {
+			return "", err
+		}
+		base = strings.TrimSpace(string(b))
+	}
+	if len(data) == 0 {
+		return base, nil
+	}
+
+	obj := map[string]interface{}{}
+	if base != "" {
+		if err := json.Unmarshal([]byte(base), &obj); err != nil {
+			return "", fmt.Errorf("optional data must be a JSON object to add '--data': %w", err)
+		}
+	}
+	for _, kv := range data {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return "", fmt.Errorf("invalid data %q, specify as key=value", kv)
+		}
+		obj[k] = parseDataValue(v)
+	}
+
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return "", fmt.Errorf("marshal optional data failed: %w", err)
+	}
+	return string(b), nil
+}
+
+func readOptionalDataFile(stdin io.Reader, file string) ([]byte, error) {
+	if file == "-" {
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("read optional data from stdin failed: %w", err)
+		}
+		return b, nil
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read optional data file failed: %w", err)
+	}
+	return b, nil
+}
+
+// parseDataValue parses the value as a JSON value such as a number or a boolean,
+// and treats it as a string when it is not a JSON value.
+func parseDataValue(v string) interface{} {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(v), &parsed); err == nil {
+		return parsed
+	}
+	return v
+}
+
+// validateOptionalData validates the optionalData with the JSON Schema of the graph
+// defined in the "optional_data_schemas" section of the config file.
+func validateOptionalData(f *pixelaClientFactory, graphID, od string) error {
+	if !json.Valid([]byte(od)) {
+		return errors.New("optional data is not valid JSON")
+	}
+	if len(od) >= maxOptionalDataSize {
+		return fmt.Errorf("optional data is %d bytes, it must be less than %d bytes", len(od), maxOptionalDataSize)
+	}
+
+	path := f.config.GetString("optional_data_schemas." + graphID)
+	if path == "" {
+		return nil
+	}
+	path, err := resolveConfigRelativePath(f, path)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read json schema failed: %w", err)
+	}
+	schema, err := parseJSONSchema(b)
+	if err != nil {
+		return err
+	}
+	return schema.validate([]byte(od))
+}
+
+// resolveConfigRelativePath expands "~" and resolves the relative path from the directory of the config file.
+func resolveConfigRelativePath(f *pixelaClientFactory, path string) (string, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(path) || f.config.ConfigFileUsed() == "" {
+		return path, nil
+	}
+	return filepath.Join(filepath.Dir(f.config.ConfigFileUsed()), path), nil
+}
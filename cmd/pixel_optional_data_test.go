@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildOptionalData(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "optional-data.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{"note":"from file"}`+"\n"), 0600))
+
+	params := []struct {
+		stdin         string
+		raw           string
+		file          string
+		data          []string
+		expected      string
+		expectedError string
+	}{
+		{raw: `{"note":"raw"}`, expected: `{"note":"raw"}`},
+		{file: file, expected: `{"note":"from file"}`},
+		{file: "-", stdin: `{"note":"stdin"}`, expected: `{"note":"stdin"}`},
+		{
+			data:     []string{"tag=deep-work", "minutes=30", "done=true", `quoted="30"`, "equal=a=b"},
+			expected: `{"done":true,"equal":"a=b","minutes":30,"quoted":"30","tag":"deep-work"}`,
+		},
+		{file: file, data: []string{"note=overwrite", "tag=x"}, expected: `{"note":"overwrite","tag":"x"}`},
+		{raw: `{}`, file: file, expectedError: "specify either '--optional-data' or '--optional-data-file'"},
+		{raw: `[]`, data: []string{"tag=x"}, expectedError: "optional data must be a JSON object to add '--data'"},
+		{data: []string{"=x"}, expectedError: `invalid data "=x", specify as key=value`},
+		{data: []string{"tag"}, expectedError: `invalid data "tag", specify as key=value`},
+	}
+
+	for _, p := range params {
+		od, err := buildOptionalData(strings.NewReader(p.stdin), p.raw, p.file, p.data)
+		if p.expectedError != "" {
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), p.expectedError)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, p.expected, od)
+	}
+}
+
+func TestValidateOptionalData(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.json")
+	assert.NoError(t, os.WriteFile(schema, []byte(`{"type":"object","required":["tag"]}`), 0600))
+	f := newPixelaClientFactory()
+	f.config.Set("optional_data_schemas", map[string]interface{}{"graph-id": schema})
+
+	params := []struct {
+		graphID       string
+		od            string
+		expectedError string
+	}{
+		{graphID: "graph-id", od: `{"tag":"deep-work"}`},
+		{graphID: "graph-id", od: `{"note":"no tag"}`, expectedError: `schema validation failed: /: missing required property "tag"`},
+		{graphID: "other-graph-id", od: `{"note":"no schema"}`},
+		{graphID: "other-graph-id", od: `not json`, expectedError: "optional data is not valid JSON"},
+		{graphID: "other-graph-id", od: `"` + strings.Repeat("a", maxOptionalDataSize-3) + `"`},
+		{graphID: "other-graph-id", od: `"` + strings.Repeat("a", maxOptionalDataSize-2) + `"`, expectedError: "optional data is 10240 bytes, it must be less than 10240 bytes"},
+	}
+
+	for _, p := range params {
+		err := validateOptionalData(f, p.graphID, p.od)
+		if p.expectedError != "" {
+			assert.EqualError(t, err, p.expectedError)
+			continue
+		}
+		assert.NoError(t, err)
+	}
+}
+
+func TestPixelCreateWithData(t *testing.T) {
+	fake := newPixelaFake()
+	fake.addGraph(pixela.GraphDefinition{ID: "graph-id"})
+	f := fake.factory()
+
+	cmd := newCmdRoot(f)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"pixel", "create", "--graph-id=graph-id", "--date=20200101", "--quantity=1", "--data", "tag=deep-work", "--data", "minutes=30"})
+
+	err := cmd.Execute()
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"minutes":30,"tag":"deep-work"}`, fake.pixels["graph-id"]["20200101"].OptionalData)
+
+	cmd = newCmdRoot(f)
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"pixel", "update", "--graph-id=graph-id", "--date=20200101", "--optional-data={"})
+
+	err = cmd.Execute()
+
+	assert.EqualError(t, err, "invalid optional data: optional data is not valid JSON")
+	assert.Equal(t, `{"minutes":30,"tag":"deep-work"}`, fake.pixels["graph-id"]["20200101"].OptionalData)
+}
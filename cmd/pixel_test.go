@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"io"
 	"net/http"
 	"strings"
 	"testing"
@@ -18,19 +18,19 @@ type pixelaPixelMock struct {
 	quantity pixela.Quantity
 }
 
-func (p *pixelaPixelMock) Create(input *pixela.PixelCreateInput) (*pixela.Result, error) {
+func (p *pixelaPixelMock) CreateWithContext(ctx context.Context, input *pixela.PixelCreateInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaPixelMock) Increment(input *pixela.PixelIncrementInput) (*pixela.Result, error) {
+func (p *pixelaPixelMock) IncrementWithContext(ctx context.Context, input *pixela.PixelIncrementInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaPixelMock) Decrement(input *pixela.PixelDecrementInput) (*pixela.Result, error) {
+func (p *pixelaPixelMock) DecrementWithContext(ctx context.Context, input *pixela.PixelDecrementInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaPixelMock) Get(input *pixela.PixelGetInput) (*pixela.Quantity, error) {
+func (p *pixelaPixelMock) GetWithContext(ctx context.Context, input *pixela.PixelGetInput) (*pixela.Quantity, error) {
 	result := &pixela.Quantity{
 		Quantity:     p.quantity.Quantity,
 		OptionalData: p.quantity.OptionalData,
@@ -39,11 +39,11 @@ func (p *pixelaPixelMock) Get(input *pixela.PixelGetInput) (*pixela.Quantity, er
 	return result, p.err
 }
 
-func (p *pixelaPixelMock) Update(input *pixela.PixelUpdateInput) (*pixela.Result, error) {
+func (p *pixelaPixelMock) UpdateWithContext(ctx context.Context, input *pixela.PixelUpdateInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaPixelMock) Delete(input *pixela.PixelDeleteInput) (*pixela.Result, error) {
+func (p *pixelaPixelMock) DeleteWithContext(ctx context.Context, input *pixela.PixelDeleteInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
@@ -68,13 +68,11 @@ func TestPixelCreateInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &pixelOptions{}
+		cmd := NewCmdPixelCreate(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createPixelCreateInput()
+		input := createPixelCreateInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.GraphID), pixela.StringValue(input.GraphID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Date), pixela.StringValue(input.Date), "Date")
@@ -84,7 +82,6 @@ func TestPixelCreateInput(t *testing.T) {
 }
 
 func TestPixelCreate(t *testing.T) {
-	defer func() { pixelaClient.pixel = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -116,11 +113,12 @@ func TestPixelCreate(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.pixel = &pixelaPixelMock{
+		f := newPixelaClientFactory()
+		f.pixel = &pixelaPixelMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdPixelCreate()
+		c := NewCmdPixelCreate(f, &pixelOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -152,20 +150,17 @@ func TestPixelIncrementInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &pixelOptions{}
+		cmd := NewCmdPixelIncrement(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createPixelIncrementInput()
+		input := createPixelIncrementInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.GraphID), pixela.StringValue(input.GraphID), "GraphID")
 	}
 }
 
 func TestPixelIncrement(t *testing.T) {
-	defer func() { pixelaClient.pixel = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -197,11 +192,12 @@ func TestPixelIncrement(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.pixel = &pixelaPixelMock{
+		f := newPixelaClientFactory()
+		f.pixel = &pixelaPixelMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdPixelIncrement()
+		c := NewCmdPixelIncrement(f, &pixelOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -233,20 +229,17 @@ func TestPixelDecrementInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &pixelOptions{}
+		cmd := NewCmdPixelDecrement(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createPixelDecrementInput()
+		input := createPixelDecrementInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.GraphID), pixela.StringValue(input.GraphID), "GraphID")
 	}
 }
 
 func TestPixelDecrement(t *testing.T) {
-	defer func() { pixelaClient.pixel = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -278,11 +271,12 @@ func TestPixelDecrement(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.pixel = &pixelaPixelMock{
+		f := newPixelaClientFactory()
+		f.pixel = &pixelaPixelMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdPixelDecrement()
+		c := NewCmdPixelDecrement(f, &pixelOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -315,13 +309,11 @@ func TestPixelGetInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &pixelOptions{}
+		cmd := NewCmdPixelGet(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createPixelGetInput()
+		input := createPixelGetInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.GraphID), pixela.StringValue(input.GraphID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Date), pixela.StringValue(input.Date), "Date")
@@ -329,7 +321,6 @@ func TestPixelGetInput(t *testing.T) {
 }
 
 func TestPixelGet(t *testing.T) {
-	defer func() { pixelaClient.pixel = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -366,12 +357,13 @@ func TestPixelGet(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.pixel = &pixelaPixelMock{
+		f := newPixelaClientFactory()
+		f.pixel = &pixelaPixelMock{
 			result:   v.Result,
 			err:      v.occur,
 			quantity: v.quantity,
 		}
-		c := NewCmdPixelGet()
+		c := NewCmdPixelGet(f, &pixelOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -406,13 +398,11 @@ func TestPixelUpdateInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &pixelOptions{}
+		cmd := NewCmdPixelUpdate(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createPixelUpdateInput()
+		input := createPixelUpdateInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.GraphID), pixela.StringValue(input.GraphID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Date), pixela.StringValue(input.Date), "Date")
@@ -422,7 +412,7 @@ func TestPixelUpdateInput(t *testing.T) {
 }
 
 func TestPixelUpdate(t *testing.T) {
-	defer func() { pixelaClient.pixel = nil }()
+	setupConfigHome(t)
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -454,11 +444,12 @@ func TestPixelUpdate(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.pixel = &pixelaPixelMock{
+		f := newPixelaClientFactory()
+		f.pixel = &pixelaPixelMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdPixelUpdate()
+		c := NewCmdPixelUpdate(f, &pixelOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -491,13 +482,11 @@ func TestPixelDeleteInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &pixelOptions{}
+		cmd := NewCmdPixelDelete(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createPixelDeleteInput()
+		input := createPixelDeleteInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.GraphID), pixela.StringValue(input.GraphID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Date), pixela.StringValue(input.Date), "Date")
@@ -505,7 +494,7 @@ func TestPixelDeleteInput(t *testing.T) {
 }
 
 func TestPixelDelete(t *testing.T) {
-	defer func() { pixelaClient.pixel = nil }()
+	setupConfigHome(t)
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -537,11 +526,12 @@ func TestPixelDelete(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.pixel = &pixelaPixelMock{
+		f := newPixelaClientFactory()
+		f.pixel = &pixelaPixelMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdPixelDelete()
+		c := NewCmdPixelDelete(f, &pixelOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -1,119 +1,159 @@
 package cmd
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	pixela "github.com/ebc-2in2crc/pixela4go"
-
-	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 )
 
-var globalOptions = &struct {
-	username   string
-	token      string
-	retryCount int
-}{}
-
-var rootCmd *cobra.Command
-var cfgFile string
 var version = "dev"
 
 // NewCmdRoot creates a root command.
 func NewCmdRoot() *cobra.Command {
+	return newCmdRoot(newPixelaClientFactory())
+}
+
+// newCmdRoot creates a root command whose subcommands use the factory f.
+func newCmdRoot(f *pixelaClientFactory) *cobra.Command {
+	var dryRun bool
+	var timeout time.Duration
 	cmd := &cobra.Command{
 		Use:           "pa",
 		Short:         "The Pixela Command Line Interface is a unified tool to manage your Pixela services",
 		SilenceErrors: true,
 		SilenceUsage:  true,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			pixela.RetryCount = getRetry()
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			f.withContext(cmd.Context(), timeout)
+			if err := initConfig(f); err != nil {
+				return err
+			}
+			if err := initBulk(f); err != nil {
+				return err
+			}
+			f.dryRun = nil
+			if dryRun {
+				f.dryRun = cmd.OutOrStdout()
+			}
+			return nil
 		},
 	}
 
-	cobra.OnInitialize(initConfig)
 	cmd.Version = version
 
-	viper.AutomaticEnv()
-	viper.SetEnvPrefix("pa")
-	cmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.pa)")
-	cmd.PersistentFlags().StringVarP(&globalOptions.username, "username", "u", "", "Pixela user name")
-	_ = viper.BindPFlag("username", cmd.PersistentFlags().Lookup("username"))
-	cmd.PersistentFlags().StringVarP(&globalOptions.token, "token", "t", "", "Pixela user token")
-	_ = viper.BindPFlag("token", cmd.PersistentFlags().Lookup("token"))
-	cmd.PersistentFlags().IntVarP(&globalOptions.retryCount, "retry", "r", 0, "Specify the number of retries when the API call is rejected")
-	_ = viper.BindPFlag("retry", cmd.PersistentFlags().Lookup("retry"))
-
-	addSubCommand(cmd)
+	cmd.PersistentFlags().StringVar(&f.configFile, "config", "", "config file (default is ./.pa, $XDG_CONFIG_HOME/pa/config.{toml,yaml,json} or $HOME/.pa)")
+	cmd.PersistentFlags().StringP("username", "u", "", "Pixela user name")
+	_ = f.config.BindPFlag("username", cmd.PersistentFlags().Lookup("username"))
+	cmd.PersistentFlags().StringP("token", "t", "", "Pixela user token")
+	_ = f.config.BindPFlag("token", cmd.PersistentFlags().Lookup("token"))
+	cmd.PersistentFlags().IntP("retry", "r", 0, "Specify the number of retries when the API call is rejected")
+	_ = f.config.BindPFlag("retry", cmd.PersistentFlags().Lookup("retry"))
+	cmd.PersistentFlags().Int("concurrency", 1, "Number of the requests of the bulk operations, such as graph clone and graph merge, run at the same time")
+	_ = f.config.BindPFlag("concurrency", cmd.PersistentFlags().Lookup("concurrency"))
+	cmd.PersistentFlags().String("rate", "", "Maximum rate of the requests, such as 5/s, 100/m or 1000/h, empty means no limit")
+	_ = f.config.BindPFlag("rate", cmd.PersistentFlags().Lookup("rate"))
+	cmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Cancel the command when it takes longer than the duration (e.g. 30s, 5m), 0 means no timeout")
+	cmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the requests of the mutating API calls instead of sending them, and leave the local state unchanged")
+
+	addSubCommand(cmd, f)
 
 	return cmd
 }
 
-func addSubCommand(cmd *cobra.Command) {
-	cmd.AddCommand(NewCmdUser())
-	cmd.AddCommand(NewCmdUserProfile())
-	cmd.AddCommand(NewCmdGraph())
-	cmd.AddCommand(NewCmdPixel())
-	cmd.AddCommand(NewCmdWebhook())
+func addSubCommand(cmd *cobra.Command, f *pixelaClientFactory) {
+	cmd.AddCommand(NewCmdUser(f))
+	cmd.AddCommand(NewCmdUserProfile(f))
+	cmd.AddCommand(NewCmdGraph(f))
+	cmd.AddCommand(NewCmdPixel(f))
+	cmd.AddCommand(NewCmdWebhook(f))
+	cmd.AddCommand(NewCmdTimer(f))
+	cmd.AddCommand(NewCmdExec(f))
+	cmd.AddCommand(NewCmdGit(f))
+	cmd.AddCommand(NewCmdHooks(f))
+	cmd.AddCommand(NewCmdSchedule(f))
+	cmd.AddCommand(NewCmdHistory())
+	cmd.AddCommand(NewCmdUndo(f))
+	cmd.AddCommand(NewCmdConfig(f))
+	cmd.AddCommand(NewCmdShell(f))
+	cmd.AddCommand(NewCmdTUI(f))
+	cmd.AddCommand(NewCmdBatch(f))
 	cmd.AddCommand(NewCmdCompletion())
 }
 
 // Execute executes root command.
+// The command is cancelled on SIGINT or SIGTERM, and a second signal kills pa immediately.
 func Execute() {
-	rootCmd = NewCmdRoot()
-	rootCmd.SetOut(os.Stdout)
-
-	err := rootCmd.Execute()
+	err := execute()
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.code)
+	}
 	if err != nil {
-		if !errors.Is(err, ErrNeglect) {
-			rootCmd.PrintErr(err)
-		}
 		os.Exit(1)
 	}
 }
 
-func initConfig() {
-	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
-	} else {
-		home, err := homedir.Dir()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+// execute runs the root command, and releases the resources of the execution even if the command fails.
+func execute() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// 2 回目のシグナルは既定の動作で終了させる
+		stop()
+	}()
+
+	f := newPixelaClientFactory()
+	defer f.release()
+	rootCmd := newCmdRoot(f)
+	rootCmd.SetOut(os.Stdout)
 
-		wd, err := os.Getwd()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		viper.AddConfigPath(wd)
-		viper.AddConfigPath(home)
-		viper.SetConfigName(".pa")
-		viper.SetConfigType("toml")
+	err := rootCmd.ExecuteContext(ctx)
+	var exitErr *exitError
+	if err != nil && !errors.As(err, &exitErr) && !errors.Is(err, ErrNeglect) {
+		rootCmd.PrintErr(err)
 	}
+	return err
+}
 
-	viper.AutomaticEnv()
-
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			fmt.Println(err)
-			os.Exit(1)
+// globalArgs returns the global flags given to the command line of cmd,
+// so that the commands run by pa itself take over them.
+func globalArgs(cmd *cobra.Command) []string {
+	var args []string
+	// フラグはサブコマンドのフラグセットで解析されるので、Visit ではなく Changed で判定する
+	cmd.Root().PersistentFlags().VisitAll(func(fl *pflag.Flag) {
+		if fl.Changed {
+			args = append(args, "--"+fl.Name+"="+fl.Value.String())
 		}
-	}
+	})
+	return args
 }
 
-func getUsername() string {
-	return viper.GetString("username")
-}
+// initConfig reads the config file into the configuration of the factory.
+// The inherited configuration is used as it is unless another config file is given.
+func initConfig(f *pixelaClientFactory) error {
+	if f.configInherited && (f.configFile == "" || f.configFile == f.config.ConfigFileUsed()) {
+		return nil
+	}
 
-func getToken() string {
-	return viper.GetString("token")
-}
+	path := f.configFile
+	if path == "" {
+		p, err := findConfigFile()
+		if err != nil {
+			return err
+		}
+		path = p
+	}
 
-func getRetry() int {
-	return viper.GetInt("retry")
+	if path == "" {
+		return nil
+	}
+	f.config.SetConfigFile(path)
+	f.config.SetConfigType(configTypeOf(path))
+	return f.config.ReadInConfig()
 }
@@ -33,24 +33,46 @@ func TestCmdRootFlags(t *testing.T) {
 
 	for _, p := range params {
 		setOSEnv(p.envs)
-		cmd := NewCmdRoot()
+		f := newPixelaClientFactory()
+		cmd := newCmdRoot(f)
 		args := strings.Split(p.commandline, " ")
 		cmd.SetArgs(args)
 		cmd.SetOut(io.Discard)
 		_ = cmd.Execute()
 
-		if getUsername() != p.expectedUserName {
-			t.Errorf("expected username: %s, but got %s", p.expectedUserName, getUsername())
+		if f.Username() != p.expectedUserName {
+			t.Errorf("expected username: %s, but got %s", p.expectedUserName, f.Username())
 		}
-		if getToken() != p.expectedToken {
-			t.Errorf("expected token: %s, but got %s", p.expectedToken, getToken())
+		if f.Token() != p.expectedToken {
+			t.Errorf("expected token: %s, but got %s", p.expectedToken, f.Token())
 		}
-		if getRetry() != p.expectedRetry {
-			t.Errorf("expected retry: %d, but got %d", p.expectedRetry, getRetry())
+		if f.Retry() != p.expectedRetry {
+			t.Errorf("expected retry: %d, but got %d", p.expectedRetry, f.Retry())
 		}
 	}
 }
 
+func TestCmdRootReentrant(t *testing.T) {
+	for _, username := range []string{"alice", "bob", "carol"} {
+		username := username
+		t.Run(username, func(t *testing.T) {
+			t.Parallel()
+			f := newPixelaClientFactory()
+			cmd := newCmdRoot(f)
+			cmd.SetArgs([]string{"--username=" + username, "--token=" + username + "-token"})
+			cmd.SetOut(io.Discard)
+			_ = cmd.Execute()
+
+			if f.Username() != username {
+				t.Errorf("expected username: %s, but got %s", username, f.Username())
+			}
+			if f.Token() != username+"-token" {
+				t.Errorf("expected token: %s, but got %s", username+"-token", f.Token())
+			}
+		})
+	}
+}
+
 func setOSEnv(m map[string]string) {
 	for k, v := range m {
 		_ = os.Setenv(k, v)
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// isStdinTerminal reports whether stdin is a terminal.
+func isStdinTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmByTyping asks to type the name of the target when stdin is a terminal.
+// Scripts without a terminal are confirmed by the '--delete-me' flag only.
+func confirmByTyping(cmd *cobra.Command, f *pixelaClientFactory, kind, name string) error {
+	if f.dryRun != nil || !f.stdinIsTerminal() {
+		return nil
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Type the %s '%s' to confirm the deletion: ", kind, name)
+	line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("read confirmation failed: %w", err)
+	}
+	if strings.TrimSpace(line) != name {
+		return errors.New("the deletion is cancelled")
+	}
+	return nil
+}
+
+// protectedGraph returns the pattern of 'protected_graphs' in the config file which matches the graph ID.
+func protectedGraph(f *pixelaClientFactory, id string) (string, bool) {
+	for _, pattern := range f.config.GetStringSlice("protected_graphs") {
+		if ok, _ := path.Match(pattern, id); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// checkProtectedGraph refuses the operation on the protected graph without force.
+func checkProtectedGraph(f *pixelaClientFactory, id, operation string, force bool) error {
+	if force {
+		return nil
+	}
+	if pattern, ok := protectedGraph(f, id); ok {
+		return fmt.Errorf("graph %s is protected by '%s' in protected_graphs, specify '--force' to %s it", id, pattern, operation)
+	}
+	return nil
+}
+
+// archiveGraph exports the graph definition and its Pixels to the safety archive under the state directory.
+func archiveGraph(cmd *cobra.Command, f *pixelaClientFactory, id string) error {
+	if f.dryRun != nil {
+		return nil
+	}
+
+	export, err := f.Client().ExportGraph(f.Context(), id, "", "")
+	if err != nil {
+		return err
+	}
+	p, err := stateFile("archive", fmt.Sprintf("%s-%s.json", id, f.now().Format("20060102T150405")))
+	if err != nil {
+		return fmt.Errorf("get archive failed: %w", err)
+	}
+	if err := writeStateJSON(p, export); err != nil {
+		return fmt.Errorf("write archive failed: %w", err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "archived %d pixels of %s to %s\n", len(export.Pixels), id, p)
+	return nil
+}
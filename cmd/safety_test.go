@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebc-2in2crc/pa/pkg/pa"
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func executeSafety(t *testing.T, f *pixelaClientFactory, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	cmd := newCmdRoot(f)
+	out := bytes.NewBuffer([]byte{})
+	errOut := bytes.NewBuffer([]byte{})
+	cmd.SetOut(out)
+	cmd.SetErr(errOut)
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetArgs(append([]string{"--username", "alice", "--token", "secret"}, args...))
+	err := cmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+func TestGraphDeleteConfirmation(t *testing.T) {
+	home, _ := setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	f.stdinIsTerminal = func() bool { return true }
+	f.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee", Name: "coffee"}, pixela.PixelWithBody{Date: "20260101", Quantity: "3"})
+
+	_, errOut, err := executeSafety(t, f, "tea\n", "graph", "delete", "--id", "coffee", "--delete-me")
+	assert.EqualError(t, err, "the deletion is cancelled")
+	assert.Equal(t, "Type the graph ID 'coffee' to confirm the deletion: ", errOut)
+	assert.Contains(t, fake.definitions, "coffee")
+
+	_, errOut, err = executeSafety(t, f, "coffee\n", "graph", "delete", "--id", "coffee", "--delete-me")
+	assert.NoError(t, err)
+	assert.NotContains(t, fake.definitions, "coffee")
+
+	archive := filepath.Join(home, ".local", "state", "pa", "archive", "coffee-20260102T030405.json")
+	assert.Contains(t, errOut, "archived 1 pixels of coffee to "+archive)
+	b, err := os.ReadFile(archive)
+	assert.NoError(t, err)
+	var export pa.GraphExport
+	assert.NoError(t, json.Unmarshal(b, &export))
+	assert.Equal(t, "coffee", export.Definition.ID)
+	assert.Equal(t, []pixela.PixelWithBody{{Date: "20260101", Quantity: "3"}}, export.Pixels)
+}
+
+func TestGraphRenameConfirmation(t *testing.T) {
+	home, _ := setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	f.stdinIsTerminal = func() bool { return true }
+	f.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee", Name: "coffee"}, pixela.PixelWithBody{Date: "20260101", Quantity: "3"})
+
+	out, errOut, err := executeSafety(t, f, "tea\n", "graph", "rename", "--id", "coffee", "--new-id", "tea", "--delete-me")
+	assert.EqualError(t, err, "graph rename stopped, coffee is kept: the deletion is cancelled")
+	assert.Equal(t, `{"id":"tea","oldId":"coffee","pixels":1,"webhooks":[],"deleted":false}`+"\n", out)
+	assert.Contains(t, errOut, "Type the graph ID 'coffee' to confirm the deletion: ")
+	assert.Contains(t, fake.definitions, "coffee")
+
+	fake.pixels["tea"] = map[string]pixela.PixelWithBody{}
+	delete(fake.definitions, "tea")
+	_, errOut, err = executeSafety(t, f, "coffee\n", "graph", "rename", "--id", "coffee", "--new-id", "tea", "--delete-me")
+	assert.NoError(t, err)
+	assert.NotContains(t, fake.definitions, "coffee")
+	archive := filepath.Join(home, ".local", "state", "pa", "archive", "coffee-20260102T030405.json")
+	assert.Contains(t, errOut, "archived 1 pixels of coffee to "+archive)
+}
+
+func TestUserDeleteConfirmation(t *testing.T) {
+	setupConfigHome(t)
+	f := newPixelaClientFactory()
+	f.user = &pixelaUserMock{result: *successResult()}
+	f.stdinIsTerminal = func() bool { return true }
+
+	_, _, err := executeSafety(t, f, "", "user", "delete", "--delete-me")
+	assert.EqualError(t, err, "the deletion is cancelled")
+
+	out, _, err := executeSafety(t, f, "alice\n", "user", "delete", "--delete-me")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"message":"Success.","isSuccess":true,"isRejected":false,"statusCode":200}`+"\n", out)
+}
+
+func TestProtectedGraphs(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	f.user = &pixelaUserMock{result: *successResult()}
+	f.config.Set("protected_graphs", []string{"work-*"})
+	fake.addGraph(pixela.GraphDefinition{ID: "work-hours"})
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee"})
+
+	params := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{"graph", "delete", "--id", "work-hours", "--delete-me"},
+			expected: "graph work-hours is protected by 'work-*' in protected_graphs, specify '--force' to delete it",
+		},
+		{
+			args:     []string{"graph", "merge", "--sources", "coffee", "--target", "work-hours"},
+			expected: "graph work-hours is protected by 'work-*' in protected_graphs, specify '--force' to overwrite it",
+		},
+		{
+			args:     []string{"graph", "rename", "--id", "work-hours", "--new-id", "work", "--delete-me"},
+			expected: "graph work-hours is protected by 'work-*' in protected_graphs, specify '--force' to delete it",
+		},
+		{
+			args:     []string{"git", "backfill", "--graph", "work-hours"},
+			expected: "graph work-hours is protected by 'work-*' in protected_graphs, specify '--force' to overwrite it",
+		},
+		{
+			args:     []string{"user", "delete", "--delete-me"},
+			expected: "protected_graphs is set, specify '--force' to delete the user and all the graphs",
+		},
+	}
+
+	for _, p := range params {
+		_, _, err := executeSafety(t, f, "", p.args...)
+		assert.EqualError(t, err, p.expected, p.args)
+	}
+	assert.Contains(t, fake.definitions, "work-hours")
+
+	_, _, err := executeSafety(t, f, "", "graph", "delete", "--id", "coffee", "--delete-me")
+	assert.NoError(t, err)
+	_, _, err = executeSafety(t, f, "", "graph", "delete", "--id", "work-hours", "--delete-me", "--force")
+	assert.NoError(t, err)
+	assert.Empty(t, fake.definitions)
+}
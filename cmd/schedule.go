@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type scheduleOptions struct {
+	File  string
+	Count int
+	Once  bool
+}
+
+// runPa runs pa with the args of the job. The command is killed when ctx is done.
+func runPa(ctx context.Context, cmd *cobra.Command, args []string) error {
+	pa, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	c := exec.CommandContext(ctx, pa, args...)
+	c.Stdout = cmd.ErrOrStderr()
+	c.Stderr = cmd.ErrOrStderr()
+	return c.Run()
+}
+
+// scheduleRetryDelay is the delay before the first retry. It doubles for each retry.
+const scheduleRetryDelay = 10 * time.Second
+
+// NewCmdSchedule creates a schedule command.
+func NewCmdSchedule(f *pixelaClientFactory) *cobra.Command {
+	o := &scheduleOptions{}
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Run pa commands on cron schedules",
+		Long: "Run pa commands on cron schedules defined in the jobs file.\n" +
+			"The default jobs file is schedule.toml (or .yaml, .yml, .json) in the config directory.",
+		Args: cobra.NoArgs,
+		RunE: showHelp,
+	}
+
+	cmd.PersistentFlags().StringVar(&o.File, "file", "", "The jobs file")
+
+	cmd.AddCommand(NewCmdScheduleList(f, o))
+	cmd.AddCommand(NewCmdScheduleNext(f, o))
+	cmd.AddCommand(NewCmdScheduleRun(f, o))
+
+	return cmd
+}
+
+// NewCmdScheduleList creates a list schedule command.
+func NewCmdScheduleList(f *pixelaClientFactory, o *scheduleOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Show the jobs and their next runs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jobs, err := loadScheduleJobs(o.File)
+			if err != nil {
+				return err
+			}
+
+			type jobStatus struct {
+				*scheduleJob
+				Next time.Time `json:"next"`
+			}
+			list := make([]jobStatus, 0, len(jobs))
+			now := f.now()
+			for _, j := range jobs {
+				list = append(list, jobStatus{scheduleJob: j, Next: j.next(now)})
+			}
+
+			b, err := json.Marshal(&struct {
+				Jobs []jobStatus `json:"jobs"`
+			}{Jobs: list})
+			if err != nil {
+				return fmt.Errorf("marshal schedule list failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// NewCmdScheduleNext creates a next schedule command.
+func NewCmdScheduleNext(f *pixelaClientFactory, o *scheduleOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "next",
+		Short: "Show the upcoming runs of all jobs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jobs, err := loadScheduleJobs(o.File)
+			if err != nil {
+				return err
+			}
+
+			b, err := json.Marshal(&struct {
+				Runs []scheduledRun `json:"runs"`
+			}{Runs: upcomingRuns(jobs, f.now(), o.Count)})
+			if err != nil {
+				return fmt.Errorf("marshal schedule next failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&o.Count, "count", 10, "Number of the runs to show")
+
+	return cmd
+}
+
+// NewCmdScheduleRun creates a run schedule command.
+func NewCmdScheduleRun(f *pixelaClientFactory, o *scheduleOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run the jobs in the foreground",
+		Long: "Run the jobs in the foreground. A failed job is retried with the backoff up to 'retry' times.\n" +
+			"A job with 'catch_up' runs once for the runs missed while the daemon was stopped.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jobs, err := loadScheduleJobs(o.File)
+			if err != nil {
+				return err
+			}
+			return runSchedule(cmd, f, jobs, o.Once)
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.Once, "once", false, "Run the due jobs once and exit")
+
+	return cmd
+}
+
+// scheduleJob is a job of the jobs file.
+type scheduleJob struct {
+	Name     string   `json:"name" mapstructure:"name"`
+	Cron     string   `json:"cron" mapstructure:"cron"`
+	Timezone string   `json:"timezone" mapstructure:"timezone"`
+	Args     []string `json:"args" mapstructure:"args"`
+	Retry    int      `json:"retry" mapstructure:"retry"`
+	CatchUp  bool     `json:"catchUp" mapstructure:"catch_up"`
+
+	schedule *cronSchedule
+	location *time.Location
+}
+
+func (j *scheduleJob) next(t time.Time) time.Time {
+	return j.schedule.next(t.In(j.location))
+}
+
+type scheduledRun struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+// loadScheduleJobs reads the jobs file. It looks for the jobs file in the config directory when path is empty.
+func loadScheduleJobs(path string) ([]*scheduleJob, error) {
+	if path == "" {
+		var err error
+		if path, err = findScheduleFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType(configTypeOf(path))
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read jobs file failed: %w", err)
+	}
+	var jobs []*scheduleJob
+	if err := v.UnmarshalKey("jobs", &jobs); err != nil {
+		return nil, fmt.Errorf("unmarshal jobs file failed: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, j := range jobs {
+		if j.Name == "" {
+			return nil, errors.New("job name is required")
+		}
+		if names[j.Name] {
+			return nil, fmt.Errorf("duplicate job name: %s", j.Name)
+		}
+		names[j.Name] = true
+		if len(j.Args) == 0 {
+			return nil, fmt.Errorf("args of job %s is required", j.Name)
+		}
+
+		var err error
+		if j.schedule, err = parseCron(j.Cron); err != nil {
+			return nil, fmt.Errorf("job %s: %w", j.Name, err)
+		}
+		j.location = time.Local
+		if j.Timezone != "" {
+			if j.location, err = time.LoadLocation(j.Timezone); err != nil {
+				return nil, fmt.Errorf("job %s: invalid timezone: %w", j.Name, err)
+			}
+		}
+	}
+	return jobs, nil
+}
+
+func findScheduleFile() (string, error) {
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	for _, ext := range configExtensions {
+		path := filepath.Join(dir, "schedule."+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("jobs file not found in %s", dir)
+}
+
+// upcomingRuns returns the first count runs of all jobs after t.
+func upcomingRuns(jobs []*scheduleJob, t time.Time, count int) []scheduledRun {
+	runs := []scheduledRun{}
+	for _, j := range jobs {
+		next := t
+		for i := 0; i < count; i++ {
+			next = j.next(next)
+			if next.IsZero() {
+				break
+			}
+			runs = append(runs, scheduledRun{Name: j.Name, Time: next})
+		}
+	}
+	sort.SliceStable(runs, func(i, k int) bool { return runs[i].Time.Before(runs[k].Time) })
+	if len(runs) > count {
+		runs = runs[:count]
+	}
+	return runs
+}
+
+// runSchedule runs the due jobs and sleeps until the next run. The last runs are kept in the state file
+// so that the missed runs can be caught up after restart.
+func runSchedule(cmd *cobra.Command, f *pixelaClientFactory, jobs []*scheduleJob, once bool) error {
+	path, err := stateFile("schedule.json")
+	if err != nil {
+		return fmt.Errorf("get schedule state failed: %w", err)
+	}
+	lastRuns := map[string]time.Time{}
+	if err := readStateJSON(path, &lastRuns); err != nil {
+		return fmt.Errorf("read schedule state failed: %w", err)
+	}
+
+	// キャッチアップしないジョブは停止中の実行を飛ばして、起動した分の実行から始める
+	floor := f.now().Truncate(time.Minute).Add(-time.Nanosecond)
+	for _, j := range jobs {
+		if last, ok := lastRuns[j.Name]; !ok || (!j.CatchUp && last.Before(floor)) {
+			lastRuns[j.Name] = floor
+		}
+	}
+
+	for {
+		now := f.now()
+		wake := now.Add(time.Minute)
+		for _, j := range jobs {
+			due := j.next(lastRuns[j.Name])
+			if due.IsZero() {
+				continue
+			}
+			if due.After(now) {
+				if due.Before(wake) {
+					wake = due
+				}
+				continue
+			}
+
+			if next := j.next(due); !next.IsZero() && !next.After(now) {
+				logSchedule(cmd, f, j.Name, "catch up the runs missed since %s", due.Format(time.RFC3339))
+			}
+			runScheduleJob(cmd, f, j)
+			lastRuns[j.Name] = now
+			if err := f.writeState(path, lastRuns); err != nil {
+				return fmt.Errorf("write schedule state failed: %w", err)
+			}
+		}
+
+		if once {
+			return nil
+		}
+		// シグナルや --timeout で止められたときは正常に終了する
+		if err := f.sleep(f.Context(), wake.Sub(f.now())); err != nil {
+			return nil
+		}
+	}
+}
+
+func runScheduleJob(cmd *cobra.Command, f *pixelaClientFactory, j *scheduleJob) {
+	// スケジューラーに指定されたグローバルフラグはジョブにも付ける。--timeout はスケジューラー自身の制限なので付けない
+	var args []string
+	for _, arg := range globalArgs(cmd) {
+		if !strings.HasPrefix(arg, "--timeout=") {
+			args = append(args, arg)
+		}
+	}
+	args = append(args, j.Args...)
+	delay := scheduleRetryDelay
+	for attempt := 0; ; attempt++ {
+		logSchedule(cmd, f, j.Name, "run %v", j.Args)
+		err := f.runScheduledCommand(f.Context(), cmd, args)
+		if err == nil {
+			logSchedule(cmd, f, j.Name, "succeeded")
+			return
+		}
+		if attempt >= j.Retry {
+			logSchedule(cmd, f, j.Name, "failed: %v", err)
+			return
+		}
+		logSchedule(cmd, f, j.Name, "failed: %v, retry in %s", err, delay)
+		if err := f.sleep(f.Context(), delay); err != nil {
+			logSchedule(cmd, f, j.Name, "cancelled: %v", err)
+			return
+		}
+		delay *= 2
+	}
+}
+
+// sleepContext sleeps for d, or returns the error of ctx when it is done earlier.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func logSchedule(cmd *cobra.Command, f *pixelaClientFactory, name, format string, a ...interface{}) {
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s [%s] %s\n", f.now().Format(time.RFC3339), name, fmt.Sprintf(format, a...))
+}
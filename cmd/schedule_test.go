@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+const scheduleJobsTOML = `
+[[jobs]]
+name = "water"
+cron = "0 9-18/3 * * 1-5"
+timezone = "Asia/Tokyo"
+args = ["pixel", "increment", "--graph-id", "water"]
+retry = 2
+
+[[jobs]]
+name = "review"
+cron = "@hourly"
+timezone = "UTC"
+args = ["graph", "add", "--id", "review", "--quantity", "1"]
+catch_up = true
+`
+
+// setupSchedule writes the jobs file in the config directory, and returns a factory with the clock and the command runner replaced.
+func setupSchedule(t *testing.T, now time.Time, fail func(args []string) error) (*pixelaClientFactory, *[]string, func(time.Duration)) {
+	t.Helper()
+	home, _ := setupConfigHome(t)
+	writeFile(t, filepath.Join(home, "xdg-config", "pa", "schedule.toml"), scheduleJobsTOML)
+
+	f := newPixelaClientFactory()
+	f.now = func() time.Time { return now }
+	f.sleep = func(ctx context.Context, d time.Duration) error {
+		now = now.Add(d)
+		return nil
+	}
+	runs := []string{}
+	f.runScheduledCommand = func(ctx context.Context, cmd *cobra.Command, args []string) error {
+		runs = append(runs, strings.Join(args, " "))
+		return fail(args)
+	}
+	return f, &runs, func(d time.Duration) { now = now.Add(d) }
+}
+
+func executeSchedule(t *testing.T, f *pixelaClientFactory, args ...string) (string, string, error) {
+	t.Helper()
+	cmd := newCmdRoot(f)
+	out := bytes.NewBuffer([]byte{})
+	errOut := bytes.NewBuffer([]byte{})
+	cmd.SetOut(out)
+	cmd.SetErr(errOut)
+	cmd.SetArgs(append([]string{"schedule"}, args...))
+	err := cmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+func noScheduleFailure(args []string) error { return nil }
+
+func TestScheduleNext(t *testing.T) {
+	f, _, _ := setupSchedule(t, time.Date(2026, 1, 1, 1, 30, 0, 0, time.UTC), noScheduleFailure)
+
+	out, _, err := executeSchedule(t, f, "next", "--count", "4")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"runs":[`+
+		`{"name":"review","time":"2026-01-01T02:00:00Z"},`+
+		`{"name":"water","time":"2026-01-01T12:00:00+09:00"},`+
+		`{"name":"review","time":"2026-01-01T03:00:00Z"},`+
+		`{"name":"review","time":"2026-01-01T04:00:00Z"}]}`+"\n", out)
+
+	out, _, err = executeSchedule(t, f, "list")
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"name":"water","cron":"0 9-18/3 * * 1-5","timezone":"Asia/Tokyo","args":["pixel","increment","--graph-id","water"],"retry":2,"catchUp":false,"next":"2026-01-01T12:00:00+09:00"`)
+}
+
+func TestScheduleRun(t *testing.T) {
+	// 2026-01-01T03:00:00Z は Asia/Tokyo の 12:00
+	f, runs, advance := setupSchedule(t, time.Date(2026, 1, 1, 3, 0, 20, 0, time.UTC), noScheduleFailure)
+
+	_, errOut, err := executeSchedule(t, f, "run", "--once")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pixel increment --graph-id water", "graph add --id review --quantity 1"}, *runs)
+	assert.Contains(t, errOut, "[water] succeeded")
+
+	// 同じ分にもう一度実行しても重複しない
+	*runs = []string{}
+	_, _, err = executeSchedule(t, f, "run", "--once")
+	assert.NoError(t, err)
+	assert.Empty(t, *runs)
+
+	// 停止中に逃した実行は catch_up のジョブだけ 1 回実行する
+	advance(4 * time.Hour)
+	_, errOut, err = executeSchedule(t, f, "run", "--once")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"graph add --id review --quantity 1"}, *runs)
+	assert.Contains(t, errOut, "[review] catch up the runs missed since 2026-01-01T04:00:00Z")
+}
+
+func TestScheduleRunGlobalFlags(t *testing.T) {
+	f, runs, _ := setupSchedule(t, time.Date(2026, 1, 1, 3, 0, 20, 0, time.UTC), noScheduleFailure)
+
+	// --timeout はスケジューラー自身の制限なので、ジョブには引き継がない
+	_, _, err := executeSchedule(t, f, "run", "--once", "--username=papa-user", "--rate=5/s", "--timeout=1m")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"--rate=5/s --username=papa-user pixel increment --graph-id water",
+		"--rate=5/s --username=papa-user graph add --id review --quantity 1",
+	}, *runs)
+}
+
+func TestScheduleRunRetry(t *testing.T) {
+	waterFailures := 0
+	f, runs, _ := setupSchedule(t, time.Date(2026, 1, 1, 3, 0, 20, 0, time.UTC), func(args []string) error {
+		if args[0] == "pixel" && waterFailures < 2 {
+			waterFailures++
+			return errors.New("exit status 1")
+		}
+		if args[0] == "graph" {
+			return errors.New("exit status 2")
+		}
+		return nil
+	})
+
+	_, errOut, err := executeSchedule(t, f, "run", "--once")
+	assert.NoError(t, err)
+	assert.Len(t, *runs, 4)
+	assert.Contains(t, errOut, "[water] failed: exit status 1, retry in 10s")
+	assert.Contains(t, errOut, "[water] failed: exit status 1, retry in 20s")
+	assert.Contains(t, errOut, "[water] succeeded")
+	assert.Contains(t, errOut, "[review] failed: exit status 2\n")
+}
+
+func TestLoadScheduleJobsError(t *testing.T) {
+	setupConfigHome(t)
+	dir := t.TempDir()
+	params := []struct {
+		content  string
+		expected string
+	}{
+		{content: "[[jobs]]\ncron = \"* * * * *\"\nargs = [\"user\"]\n", expected: "job name is required"},
+		{content: "[[jobs]]\nname = \"a\"\ncron = \"* * * * *\"\n", expected: "args of job a is required"},
+		{content: "[[jobs]]\nname = \"a\"\ncron = \"* * *\"\nargs = [\"user\"]\n", expected: `job a: invalid cron expression "* * *", it must have 5 fields`},
+		{content: "[[jobs]]\nname = \"a\"\ncron = \"* * * * *\"\ntimezone = \"Mars/Base\"\nargs = [\"user\"]\n", expected: "job a: invalid timezone:"},
+		{content: "[[jobs]]\nname = \"a\"\ncron = \"* * * * *\"\nargs = [\"user\"]\n[[jobs]]\nname = \"a\"\ncron = \"* * * * *\"\nargs = [\"user\"]\n", expected: "duplicate job name: a"},
+	}
+
+	for i, p := range params {
+		path := filepath.Join(dir, "schedule"+string(rune('a'+i))+".toml")
+		writeFile(t, path, p.content)
+		_, err := loadScheduleJobs(path)
+		assert.Contains(t, err.Error(), p.expected)
+	}
+
+	_, err := loadScheduleJobs("")
+	assert.Contains(t, err.Error(), "jobs file not found in ")
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonSchema is a subset of JSON Schema used to validate the optionalData of the pixel.
+// Supported keywords: type, enum, const, properties, required, additionalProperties, items,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, minLength, maxLength, pattern, minItems and maxItems.
+type jsonSchema struct {
+	Type                 interface{}            `json:"type"`
+	Enum                 []interface{}          `json:"enum"`
+	Const                interface{}            `json:"const"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties interface{}            `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+}
+
+// schemaKeywords are the keywords which parseJSONSchema accepts. The annotations are accepted and ignored.
+var schemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "properties": true, "required": true, "additionalProperties": true,
+	"items": true, "minimum": true, "maximum": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minItems": true, "maxItems": true,
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true, "default": true, "examples": true,
+}
+
+// parseJSONSchema parses the JSON Schema, and returns an error for the keywords which are not supported,
+// so that a schema such as oneOf or $ref is not silently ignored.
+func parseJSONSchema(b []byte) (*jsonSchema, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unmarshal json schema failed: %w", err)
+	}
+	if err := checkSchemaKeywords("", m); err != nil {
+		return nil, err
+	}
+
+	var s jsonSchema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("unmarshal json schema failed: %w", err)
+	}
+	return &s, nil
+}
+
+// checkSchemaKeywords checks the keywords of the schema and its subschemas at path.
+func checkSchemaKeywords(path string, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !schemaKeywords[k] {
+			p := path
+			if p == "" {
+				p = "/"
+			}
+			return fmt.Errorf("unsupported keyword in json schema: %s at %s", k, p)
+		}
+		// サブスキーマは properties の値、items と object の additionalProperties
+		v, ok := m[k].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch k {
+		case "items", "additionalProperties":
+			if err := checkSchemaKeywords(path+"/"+k, v); err != nil {
+				return err
+			}
+		case "properties":
+			names := make([]string, 0, len(v))
+			for name := range v {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if sub, ok := v[name].(map[string]interface{}); ok {
+					if err := checkSchemaKeywords(path+"/properties/"+name, sub); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validate validates the JSON document and returns all violations.
+func (s *jsonSchema) validate(doc []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+
+	var errs []string
+	s.validateValue("", v, &errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("schema validation failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *jsonSchema) validateValue(path string, v interface{}, errs *[]string) {
+	report := func(format string, a ...interface{}) {
+		p := path
+		if p == "" {
+			p = "/"
+		}
+		*errs = append(*errs, p+": "+fmt.Sprintf(format, a...))
+	}
+
+	if types := s.types(); len(types) > 0 && !matchTypes(types, v) {
+		report("expected %s, but got %s", strings.Join(types, " or "), jsonTypeOf(v))
+		return
+	}
+	if s.Const != nil && !reflect.DeepEqual(s.Const, v) {
+		report("must be %v", s.Const)
+	}
+	if len(s.Enum) > 0 && !containsValue(s.Enum, v) {
+		report("must be one of %v", s.Enum)
+	}
+
+	switch t := v.(type) {
+	case float64:
+		s.validateNumber(t, report)
+	case string:
+		s.validateString(t, report)
+	case []interface{}:
+		if s.MinItems != nil && len(t) < *s.MinItems {
+			report("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(t) > *s.MaxItems {
+			report("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range t {
+				s.Items.validateValue(fmt.Sprintf("%s/%d", path, i), item, errs)
+			}
+		}
+	case map[string]interface{}:
+		s.validateObject(path, t, report, errs)
+	}
+}
+
+func (s *jsonSchema) validateNumber(n float64, report func(string, ...interface{})) {
+	if s.Minimum != nil && n < *s.Minimum {
+		report("must be >= %v", *s.Minimum)
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		report("must be <= %v", *s.Maximum)
+	}
+	if s.ExclusiveMinimum != nil && n <= *s.ExclusiveMinimum {
+		report("must be > %v", *s.ExclusiveMinimum)
+	}
+	if s.ExclusiveMaximum != nil && n >= *s.ExclusiveMaximum {
+		report("must be < %v", *s.ExclusiveMaximum)
+	}
+}
+
+func (s *jsonSchema) validateString(str string, report func(string, ...interface{})) {
+	l := utf8.RuneCountInString(str)
+	if s.MinLength != nil && l < *s.MinLength {
+		report("must be at least %d characters", *s.MinLength)
+	}
+	if s.MaxLength != nil && l > *s.MaxLength {
+		report("must be at most %d characters", *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			report("invalid pattern %q: %v", s.Pattern, err)
+		} else if !re.MatchString(str) {
+			report("must match %q", s.Pattern)
+		}
+	}
+}
+
+func (s *jsonSchema) validateObject(path string, obj map[string]interface{}, report func(string, ...interface{}), errs *[]string) {
+	for _, r := range s.Required {
+		if _, ok := obj[r]; !ok {
+			report("missing required property %q", r)
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := path + "/" + k
+		if ps, ok := s.Properties[k]; ok {
+			ps.validateValue(p, obj[k], errs)
+			continue
+		}
+		switch ap := s.AdditionalProperties.(type) {
+		case bool:
+			if !ap {
+				report("additional property %q is not allowed", k)
+			}
+		case map[string]interface{}:
+			b, _ := json.Marshal(ap)
+			as, err := parseJSONSchema(b)
+			if err != nil {
+				report("invalid additionalProperties: %v", err)
+				continue
+			}
+			as.validateValue(p, obj[k], errs)
+		}
+	}
+}
+
+func (s *jsonSchema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			if str, ok := v.(string); ok {
+				types = append(types, str)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchTypes(types []string, v interface{}) bool {
+	actual := jsonTypeOf(v)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if t == math.Trunc(t) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, e := range values {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJSONSchemaUnsupportedKeyword(t *testing.T) {
+	params := []struct {
+		schema   string
+		expected string
+	}{
+		{schema: `{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"work","type":"object"}`, expected: ""},
+		{schema: `{"oneOf":[{"type":"string"},{"type":"integer"}]}`, expected: "unsupported keyword in json schema: oneOf at /"},
+		{schema: `{"type":"object","properties":{"tag":{"$ref":"#/$defs/tag"}}}`, expected: "unsupported keyword in json schema: $ref at /properties/tag"},
+		{schema: `{"type":"array","items":{"type":"string","format":"date"}}`, expected: "unsupported keyword in json schema: format at /items"},
+		{schema: `{"type":"array","uniqueItems":true}`, expected: "unsupported keyword in json schema: uniqueItems at /"},
+		{schema: `{"additionalProperties":{"patternProperties":{}}}`, expected: "unsupported keyword in json schema: patternProperties at /additionalProperties"},
+		{schema: `[]`, expected: "unmarshal json schema failed: json: cannot unmarshal array into Go value of type map[string]interface {}"},
+	}
+
+	for _, p := range params {
+		_, err := parseJSONSchema([]byte(p.schema))
+		if p.expected == "" {
+			assert.NoError(t, err, p.schema)
+		} else {
+			assert.EqualError(t, err, p.expected, p.schema)
+		}
+	}
+}
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema, err := parseJSONSchema([]byte(`{
+  "type": "object",
+  "required": ["tag"],
+  "additionalProperties": false,
+  "properties": {
+    "tag": {"type": "string", "enum": ["deep-work", "meeting"]},
+    "minutes": {"type": "integer", "minimum": 0, "exclusiveMaximum": 1440},
+    "note": {"type": ["string", "null"], "maxLength": 5, "pattern": "^[a-z]*$"},
+    "labels": {"type": "array", "maxItems": 2, "items": {"type": "string", "minLength": 1}}
+  }
+}`))
+	assert.NoError(t, err)
+
+	params := []struct {
+		doc      string
+		expected string
+	}{
+		{doc: `{"tag":"deep-work","minutes":30,"note":"abc","labels":["a","b"]}`, expected: ""},
+		{doc: `{"tag":"meeting","note":null}`, expected: ""},
+		{doc: `{"minutes":30}`, expected: `schema validation failed: /: missing required property "tag"`},
+		{doc: `{"tag":"sleep"}`, expected: `schema validation failed: /tag: must be one of [deep-work meeting]`},
+		{doc: `{"tag":"meeting","minutes":1.5}`, expected: `schema validation failed: /minutes: expected integer, but got number`},
+		{doc: `{"tag":"meeting","minutes":1440}`, expected: `schema validation failed: /minutes: must be < 1440`},
+		{doc: `{"tag":"meeting","note":"ABCDEF"}`, expected: `schema validation failed: /note: must be at most 5 characters; /note: must match "^[a-z]*$"`},
+		{doc: `{"tag":"meeting","labels":["a",""]}`, expected: `schema validation failed: /labels/1: must be at least 1 characters`},
+		{doc: `{"tag":"meeting","extra":1}`, expected: `schema validation failed: /: additional property "extra" is not allowed`},
+		{doc: `[]`, expected: `schema validation failed: /: expected object, but got array`},
+		{doc: `{`, expected: `invalid json: unexpected end of JSON input`},
+	}
+
+	for _, p := range params {
+		err := schema.validate([]byte(p.doc))
+		if p.expected == "" {
+			assert.NoError(t, err, p.doc)
+		} else {
+			assert.EqualError(t, err, p.expected, p.doc)
+		}
+	}
+}
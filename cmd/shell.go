@@ -0,0 +1,448 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// shellHistorySize is the number of the lines loaded from the history file.
+const shellHistorySize = 1000
+
+// errShellExit is returned by the built-in commands which leave the shell.
+var errShellExit = errors.New("exit")
+
+// NewCmdShell creates a shell command.
+func NewCmdShell(f *pixelaClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Run pa commands interactively",
+		Long: "Read pa commands line by line and run them with the same command tree as the CLI.\n" +
+			"The global flags given to the shell apply to every line.\n\n" +
+			"Built-in commands:\n" +
+			"  use <graph-id>  fill '--id' or '--graph-id' of the following commands with the graph\n" +
+			"  use             clear the graph\n" +
+			"  exit, quit      leave the shell\n\n" +
+			"On a terminal the line can be edited, the history is kept in the state directory,\n" +
+			"the Tab key completes the commands, the flags and the graph IDs, and Ctrl-C cancels\n" +
+			"the running command.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := newShell(f, cmd)
+			if cmd.InOrStdin() == os.Stdin && f.stdinIsTerminal() {
+				return s.runTerminal()
+			}
+			return s.run()
+		},
+	}
+
+	return cmd
+}
+
+// shell runs the lines on a new root command each, so that the flags of a line don't remain in the next line.
+type shell struct {
+	f   *pixelaClientFactory
+	ctx context.Context
+
+	in  *bufio.Reader
+	out io.Writer
+	err io.Writer
+
+	// globalArgs はシェルの起動時に指定されたグローバルフラグで、各行の先頭に付ける
+	globalArgs []string
+	// graph は use で選んだグラフ
+	graph string
+
+	// terminal が true のときは行を編集してプロンプトを表示する
+	terminal bool
+	editor   *lineEditor
+	history  string
+	// interrupts は実行中のコマンドをキャンセルする Ctrl-C
+	interrupts chan os.Signal
+
+	tree     *cobra.Command
+	graphIDs []string
+}
+
+func newShell(f *pixelaClientFactory, cmd *cobra.Command) *shell {
+	s := &shell{
+		f:   f,
+		ctx: cmd.Context(),
+		in:  bufio.NewReader(cmd.InOrStdin()),
+		out: cmd.OutOrStdout(),
+		err: cmd.ErrOrStderr(),
+
+		globalArgs: globalArgs(cmd),
+	}
+	s.editor = newLineEditor(s.in, s.out, s.complete)
+	return s
+}
+
+// runTerminal runs the shell with the line editor and the history, and makes Ctrl-C cancel the running command only.
+func (s *shell) runTerminal() error {
+	s.terminal = true
+
+	path, err := stateFile("shell_history")
+	if err != nil {
+		return fmt.Errorf("shell history failed: %w", err)
+	}
+	s.history = path
+	s.editor.history, err = readShellHistory(path)
+	if err != nil {
+		return fmt.Errorf("read shell history failed: %w", err)
+	}
+
+	// Execute の SIGINT の通知を外して、シェルが終了しないようにする
+	s.interrupts = make(chan os.Signal, 1)
+	signal.Reset(os.Interrupt)
+	signal.Notify(s.interrupts, os.Interrupt)
+	defer signal.Stop(s.interrupts)
+
+	return s.run()
+}
+
+// run reads and runs the lines until the end of the input or exit.
+// The errors of the lines are reported and the shell goes on.
+func (s *shell) run() error {
+	for s.ctx.Err() == nil {
+		line, err := s.readLine()
+		if errors.Is(err, errLineInterrupted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) && line == "" {
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("read line failed: %w", err)
+		}
+
+		if err := s.addHistory(line); err != nil {
+			fmt.Fprintf(s.err, "write shell history failed: %v\n", err)
+		}
+		err = s.execute(line)
+		if errors.Is(err, errShellExit) {
+			return nil
+		}
+		var exitErr *exitError
+		if err != nil && !errors.Is(err, ErrNeglect) && !errors.As(err, &exitErr) {
+			fmt.Fprintln(s.err, err)
+		}
+	}
+	return nil
+}
+
+func (s *shell) prompt() string {
+	if s.graph == "" {
+		return "pa> "
+	}
+	return fmt.Sprintf("pa(%s)> ", s.graph)
+}
+
+// readLine reads a line with the line editor on a terminal, and falls back to the plain line without it.
+func (s *shell) readLine() (string, error) {
+	if s.terminal {
+		if restore, err := makeRaw(os.Stdin); err == nil {
+			defer restore()
+			return s.editor.readLine(s.prompt())
+		}
+		fmt.Fprint(s.out, s.prompt())
+	}
+
+	line, err := s.in.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (s *shell) addHistory(line string) error {
+	if !s.terminal || !s.editor.addHistory(line) {
+		return nil
+	}
+	// --dry-run ではローカルの状態を変えない
+	if s.f.dryRun != nil {
+		return nil
+	}
+	return appendShellHistory(s.history, line)
+}
+
+// execute runs a line on a new root command.
+func (s *shell) execute(line string) error {
+	args, err := splitShellLine(line)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	switch args[0] {
+	case "exit", "quit":
+		return errShellExit
+	case "use":
+		return s.use(args[1:])
+	}
+
+	f := s.f.child()
+	defer f.release()
+	root := newCmdRoot(f)
+	root.SetIn(s.in)
+	root.SetOut(s.out)
+	root.SetErr(s.err)
+	if c, _, err := root.Find(args); err == nil {
+		if c.Name() == "shell" {
+			return errors.New("already in the shell")
+		}
+		args = s.fillGraph(c, args)
+	}
+	if args[0] == "graph" {
+		// グラフが作成や削除されたかもしれないので補完の候補を読み直す
+		s.graphIDs = nil
+	}
+	root.SetArgs(append(append([]string{}, s.globalArgs...), args...))
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	if s.interrupts != nil {
+		// 行の入力中に届いた Ctrl-C でキャンセルしないように読み捨てる
+		for len(s.interrupts) > 0 {
+			<-s.interrupts
+		}
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-s.interrupts:
+				cancel()
+			case <-done:
+			}
+		}()
+	}
+	return root.ExecuteContext(ctx)
+}
+
+func (s *shell) use(args []string) error {
+	switch len(args) {
+	case 0:
+		s.graph = ""
+	case 1:
+		s.graph = args[0]
+	default:
+		return errors.New("usage: use [<graph-id>]")
+	}
+	return nil
+}
+
+// fillGraph adds '--id' or '--graph-id' of the graph chosen by use to the args of the command c,
+// unless the line has it already. '--graph-id' is not added when the webhook is given by '--hash'.
+func (s *shell) fillGraph(c *cobra.Command, args []string) []string {
+	if s.graph == "" {
+		return args
+	}
+
+	// "--" 以降はコマンドの引数なのでフラグを探さない
+	end := len(args)
+	for i, a := range args {
+		if a == "--" {
+			end = i
+			break
+		}
+	}
+	has := func(name string) bool {
+		for _, a := range args[:end] {
+			if a == "--"+name || strings.HasPrefix(a, "--"+name+"=") {
+				return true
+			}
+		}
+		return false
+	}
+
+	name := ""
+	switch {
+	case c.Flags().Lookup("id") != nil:
+		name = "id"
+	case c.Flags().Lookup("graph-id") != nil && !has("hash"):
+		name = "graph-id"
+	}
+	if name == "" || has(name) {
+		return args
+	}
+
+	result := append([]string{}, args[:end]...)
+	result = append(result, "--"+name+"="+s.graph)
+	return append(result, args[end:]...)
+}
+
+// complete returns the candidates for the last word of the line.
+// They are the commands, the flags of the command, or the graph IDs after 'use', '--id' and '--graph-id'.
+func (s *shell) complete(line string) []string {
+	words := strings.Fields(line)
+	word := ""
+	if line != "" && !unicode.IsSpace(rune(line[len(line)-1])) {
+		word = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	var candidates []string
+	switch {
+	case len(words) == 1 && words[0] == "use":
+		candidates = s.completeGraphIDs("")
+	case len(words) > 0 && (words[len(words)-1] == "--id" || words[len(words)-1] == "--graph-id"):
+		candidates = s.completeGraphIDs("")
+	case strings.HasPrefix(word, "--id="):
+		candidates = s.completeGraphIDs("--id=")
+	case strings.HasPrefix(word, "--graph-id="):
+		candidates = s.completeGraphIDs("--graph-id=")
+	default:
+		if s.tree == nil {
+			s.tree = newCmdRoot(s.f.child())
+		}
+		c, _, err := s.tree.Find(words)
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(word, "-") {
+			candidates = completeFlags(c)
+			break
+		}
+		if c == s.tree {
+			candidates = append(candidates, "exit", "quit", "use")
+		}
+		for _, sub := range c.Commands() {
+			if sub.IsAvailableCommand() && sub.Name() != "shell" {
+				candidates = append(candidates, sub.Name())
+			}
+		}
+	}
+
+	var result []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			result = append(result, c)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func completeFlags(c *cobra.Command) []string {
+	var flags []string
+	add := func(fl *pflag.Flag) {
+		if !fl.Hidden {
+			flags = append(flags, "--"+fl.Name)
+		}
+	}
+	c.LocalFlags().VisitAll(add)
+	c.InheritedFlags().VisitAll(add)
+	return flags
+}
+
+// completeGraphIDs returns the graph IDs with the prefix. The IDs are fetched once and cached.
+func (s *shell) completeGraphIDs(prefix string) []string {
+	if s.graphIDs == nil {
+		defs, err := s.f.child().Graph().GetAllWithContext(s.ctx)
+		if err != nil {
+			return nil
+		}
+		s.graphIDs = []string{}
+		for _, d := range defs.Graphs {
+			s.graphIDs = append(s.graphIDs, d.ID)
+		}
+	}
+
+	result := make([]string, 0, len(s.graphIDs))
+	for _, id := range s.graphIDs {
+		result = append(result, prefix+id)
+	}
+	return result
+}
+
+// splitShellLine splits the line into the args like a POSIX shell.
+// The single and double quotes and the backslash escape are supported.
+func splitShellLine(line string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if escaped || quote != 0 {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// readShellHistory reads the last lines of the history file.
+func readShellHistory(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil, nil
+	}
+	if len(lines) > shellHistorySize {
+		lines = lines[len(lines)-shellHistorySize:]
+	}
+	return lines, nil
+}
+
+func appendShellHistory(path, line string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(file, line); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
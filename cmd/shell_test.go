@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell(t *testing.T) {
+	fake := newPixelaFake()
+	f := fake.factory()
+
+	cmd := newCmdRoot(f)
+	buffer := bytes.NewBuffer([]byte{})
+	errBuffer := bytes.NewBuffer([]byte{})
+	cmd.SetIn(strings.NewReader(strings.Join([]string{
+		"pixel increment",
+		"use graph-id",
+		"pixel increment",
+		"pixel increment --graph-id=other",
+		"use",
+		"shell",
+		"pixel increment --graph-id='with space'",
+		"exit",
+		"pixel increment --graph-id=never",
+	}, "\n")))
+	cmd.SetOut(buffer)
+	cmd.SetErr(errBuffer)
+	cmd.SetArgs([]string{"shell"})
+
+	err := cmd.Execute()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"graph-id:increment", "other:increment", "with space:increment"}, fake.added)
+	assert.Equal(t, "required flag(s) \"graph-id\" not set\nalready in the shell\n", errBuffer.String())
+	assert.Equal(t, 3, strings.Count(buffer.String(), `"isSuccess":true`))
+}
+
+func TestShellFillGraph(t *testing.T) {
+	s := &shell{f: newPixelaClientFactory(), graph: "graph-id"}
+	root := newCmdRoot(s.f)
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{args: []string{"graph", "stats"}, want: []string{"graph", "stats", "--id=graph-id"}},
+		{args: []string{"graph", "stats", "--id", "other"}, want: []string{"graph", "stats", "--id", "other"}},
+		{args: []string{"pixel", "get", "--date=20200101"}, want: []string{"pixel", "get", "--date=20200101", "--graph-id=graph-id"}},
+		{args: []string{"webhook", "invoke", "--hash=h"}, want: []string{"webhook", "invoke", "--hash=h"}},
+		{args: []string{"exec", "--", "make", "--id=x"}, want: []string{"exec", "--id=graph-id", "--", "make", "--id=x"}},
+		{args: []string{"graph", "list"}, want: []string{"graph", "list"}},
+	}
+	for _, tt := range tests {
+		c, _, err := root.Find(tt.args)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, s.fillGraph(c, tt.args), tt.args)
+	}
+}
+
+func TestShellComplete(t *testing.T) {
+	fake := newPixelaFake()
+	fake.addGraph(pixela.GraphDefinition{ID: "graph-b"})
+	fake.addGraph(pixela.GraphDefinition{ID: "graph-a"})
+	s := &shell{f: fake.factory(), ctx: context.Background()}
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: "gr", want: []string{"graph"}},
+		{line: "u", want: []string{"undo", "use", "user"}},
+		{line: "sh", want: nil},
+		{line: "pixel inc", want: []string{"increment"}},
+		{line: "pixel increment --gr", want: []string{"--graph-id"}},
+		{line: "use ", want: []string{"graph-a", "graph-b"}},
+		{line: "graph stats --id graph-b", want: []string{"graph-b"}},
+		{line: "pixel get --graph-id=graph-", want: []string{"--graph-id=graph-a", "--graph-id=graph-b"}},
+		{line: "unknown ", want: nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, s.complete(tt.line), tt.line)
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	complete := func(line string) []string {
+		if strings.HasPrefix(line, "pixel ") {
+			return []string{"increment"}
+		}
+		return []string{"pixel"}
+	}
+	in := strings.NewReader("pix\tinc\t\r" + "\x1b[A\r" + "ab\x1b[Dx\r" + "abc\x7f\x01z\x1b[C\x0b\r" + "xy\x03" + "\x04")
+	out := bytes.NewBuffer([]byte{})
+	e := newLineEditor(in, out, complete)
+	e.history = []string{"graph list"}
+
+	for _, want := range []string{"pixel increment ", "graph list", "axb", "za"} {
+		line, err := e.readLine("pa> ")
+		assert.NoError(t, err)
+		assert.Equal(t, want, line)
+	}
+	_, err := e.readLine("pa> ")
+	assert.True(t, errors.Is(err, errLineInterrupted))
+	_, err = e.readLine("pa> ")
+	assert.Equal(t, io.EOF, err)
+	assert.Contains(t, out.String(), "\r\x1b[Kpa> ab\x1b[1D")
+}
+
+func TestSplitShellLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "", want: nil},
+		{line: "  graph   list ", want: []string{"graph", "list"}},
+		{line: `pixel create --optional-data='{"a": 1}'`, want: []string{"pixel", "create", `--optional-data={"a": 1}`}},
+		{line: `use "my graph" a\ b ""`, want: []string{"use", "my graph", "a b", ""}},
+		{line: `use "it's"`, want: []string{"use", "it's"}},
+		{line: `use 'open`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := splitShellLine(tt.line)
+		if tt.wantErr {
+			assert.Error(t, err, tt.line)
+			continue
+		}
+		assert.NoError(t, err, tt.line)
+		assert.Equal(t, tt.want, got, tt.line)
+	}
+}
+
+func TestShellHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shell_history")
+
+	lines, err := readShellHistory(path)
+	assert.NoError(t, err)
+	assert.Empty(t, lines)
+
+	for i := 0; i < shellHistorySize+1; i++ {
+		assert.NoError(t, appendShellHistory(path, "graph list"))
+	}
+	assert.NoError(t, appendShellHistory(path, "use graph-id"))
+
+	lines, err = readShellHistory(path)
+	assert.NoError(t, err)
+	assert.Len(t, lines, shellHistorySize)
+	assert.Equal(t, "use graph-id", lines[len(lines)-1])
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package cmd
+
+import (
+	"errors"
+	"os"
+)
+
+// makeRaw is not supported on this platform, and the shell reads the lines without editing.
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("raw mode is not supported")
+}
+
+// terminalSize is not supported on this platform.
+func terminalSize(f *os.File) (int, int, error) {
+	return 0, 0, errors.New("terminal size is not supported")
+}
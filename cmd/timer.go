@@ -0,0 +1,393 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+type timerOptions struct {
+	ID       string
+	Native   bool
+	Rounding string
+	RoundTo  time.Duration
+}
+
+var timerRoundings = map[string]func(d, m time.Duration) time.Duration{
+	"nearest": time.Duration.Round,
+	"down":    time.Duration.Truncate,
+	"up": func(d, m time.Duration) time.Duration {
+		if t := d.Truncate(m); t != d {
+			return t + m
+		}
+		return d
+	},
+}
+
+// NewCmdTimer creates a timer command.
+func NewCmdTimer(f *pixelaClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "timer",
+		Short: "Local stopwatch which adds the elapsed minutes to a Graph",
+		Args:  cobra.NoArgs,
+		RunE:  showHelp,
+	}
+
+	cmd.AddCommand(NewCmdTimerStart(f, &timerOptions{}))
+	cmd.AddCommand(NewCmdTimerPause(f, &timerOptions{}))
+	cmd.AddCommand(NewCmdTimerResume(f, &timerOptions{}))
+	cmd.AddCommand(NewCmdTimerStatus(f, &timerOptions{}))
+	cmd.AddCommand(NewCmdTimerStop(f, &timerOptions{}))
+
+	return cmd
+}
+
+// NewCmdTimerStart creates a start timer command.
+func NewCmdTimerStart(f *pixelaClientFactory, o *timerOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start a timer",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, timers, err := loadTimers()
+			if err != nil {
+				return err
+			}
+			if _, ok := timers[o.ID]; ok {
+				return fmt.Errorf("timer already started: %s", o.ID)
+			}
+
+			if o.Native {
+				if ok, err := toggleNativeStopwatch(cmd, f, o.ID); !ok {
+					return err
+				}
+			}
+
+			now := f.now()
+			t := &timer{ID: o.ID, StartedAt: now, ResumedAt: &now, Native: o.Native}
+			timers[t.ID] = t
+			if err := saveTimers(f, path, timers); err != nil {
+				return err
+			}
+			return printTimers(cmd, f, []*timer{t})
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().BoolVar(&o.Native, "native", false, "Use the stopwatch of Pixela instead of the local timer")
+
+	return cmd
+}
+
+// NewCmdTimerPause creates a pause timer command.
+func NewCmdTimerPause(f *pixelaClientFactory, o *timerOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pause",
+		Short: "Pause a timer",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, timers, err := loadTimers()
+			if err != nil {
+				return err
+			}
+			t, err := findTimer(timers, o.ID)
+			if err != nil {
+				return err
+			}
+			if t.Native {
+				return fmt.Errorf("the stopwatch of Pixela cannot be paused: %s", t.ID)
+			}
+			if !t.running() {
+				return fmt.Errorf("timer already paused: %s", t.ID)
+			}
+
+			t.Elapsed = t.elapsed(f.now())
+			t.ResumedAt = nil
+			if err := saveTimers(f, path, timers); err != nil {
+				return err
+			}
+			return printTimers(cmd, f, []*timer{t})
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	_ = cmd.MarkFlagRequired("id")
+
+	return cmd
+}
+
+// NewCmdTimerResume creates a resume timer command.
+func NewCmdTimerResume(f *pixelaClientFactory, o *timerOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Resume a paused timer",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, timers, err := loadTimers()
+			if err != nil {
+				return err
+			}
+			t, err := findTimer(timers, o.ID)
+			if err != nil {
+				return err
+			}
+			if t.running() {
+				return fmt.Errorf("timer is running: %s", t.ID)
+			}
+
+			now := f.now()
+			t.ResumedAt = &now
+			if err := saveTimers(f, path, timers); err != nil {
+				return err
+			}
+			return printTimers(cmd, f, []*timer{t})
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	_ = cmd.MarkFlagRequired("id")
+
+	return cmd
+}
+
+// NewCmdTimerStatus creates a status timer command.
+func NewCmdTimerStatus(f *pixelaClientFactory, o *timerOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the timers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, timers, err := loadTimers()
+			if err != nil {
+				return err
+			}
+
+			if o.ID != "" {
+				t, err := findTimer(timers, o.ID)
+				if err != nil {
+					return err
+				}
+				return printTimers(cmd, f, []*timer{t})
+			}
+
+			list := make([]*timer, 0, len(timers))
+			for _, t := range timers {
+				list = append(list, t)
+			}
+			sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+			return printTimers(cmd, f, list)
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph (default all timers)")
+
+	return cmd
+}
+
+// NewCmdTimerStop creates a stop timer command.
+func NewCmdTimerStop(f *pixelaClientFactory, o *timerOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop a timer and add the elapsed minutes to the Graph",
+		Long: "Stop a timer and add the elapsed minutes to the Graph.\n" +
+			"The default rounding can be configured with 'rounding' and 'round_to' in the [timer] section of the config file.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			round, roundTo, err := timerRounding(cmd, f, o)
+			if err != nil {
+				return err
+			}
+
+			path, timers, err := loadTimers()
+			if err != nil {
+				return err
+			}
+			t, err := findTimer(timers, o.ID)
+			if err != nil {
+				return err
+			}
+
+			elapsed := t.elapsed(f.now())
+			r := &timerStopResult{ID: t.ID, Elapsed: elapsed.Truncate(time.Second).String()}
+			if t.Native {
+				if ok, err := toggleNativeStopwatch(cmd, f, t.ID); !ok {
+					return err
+				}
+			} else {
+				r.Quantity = strconv.Itoa(int(round(elapsed, roundTo) / time.Minute))
+				if r.Quantity != "0" {
+					if ok, err := addTimerQuantity(cmd, f, t.ID, r.Quantity); !ok {
+						return err
+					}
+				}
+			}
+
+			delete(timers, t.ID)
+			if err := saveTimers(f, path, timers); err != nil {
+				return err
+			}
+
+			b, err := json.Marshal(r)
+			if err != nil {
+				return fmt.Errorf("marshal timer stop result failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ID, "id", "", "ID for identifying the pixelation graph")
+	_ = cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVar(&o.Rounding, "rounding", "", "How to round the elapsed time: nearest, up or down (default nearest)")
+	cmd.Flags().DurationVar(&o.RoundTo, "round-to", 0, "The unit to round the elapsed time to (default 1m)")
+
+	return cmd
+}
+
+// timer is a local stopwatch. ResumedAt is nil while the timer is paused.
+type timer struct {
+	ID        string        `json:"id"`
+	StartedAt time.Time     `json:"startedAt"`
+	ResumedAt *time.Time    `json:"resumedAt"`
+	Elapsed   time.Duration `json:"elapsed"`
+	Native    bool          `json:"native"`
+}
+
+func (t *timer) running() bool {
+	return t.ResumedAt != nil
+}
+
+// elapsed returns the elapsed time except the paused periods.
+func (t *timer) elapsed(now time.Time) time.Duration {
+	if !t.running() {
+		return t.Elapsed
+	}
+	return t.Elapsed + now.Sub(*t.ResumedAt)
+}
+
+type timerStatus struct {
+	ID        string    `json:"id"`
+	Running   bool      `json:"running"`
+	Native    bool      `json:"native"`
+	StartedAt time.Time `json:"startedAt"`
+	Elapsed   string    `json:"elapsed"`
+}
+
+type timerStopResult struct {
+	ID       string `json:"id"`
+	Elapsed  string `json:"elapsed"`
+	Quantity string `json:"quantity,omitempty"`
+}
+
+// loadTimers reads the timers from the state file so that they survive reboots.
+func loadTimers() (string, map[string]*timer, error) {
+	path, err := stateFile("timers.json")
+	if err != nil {
+		return "", nil, fmt.Errorf("get timers state failed: %w", err)
+	}
+	timers := map[string]*timer{}
+	if err := readStateJSON(path, &timers); err != nil {
+		return "", nil, fmt.Errorf("read timers state failed: %w", err)
+	}
+	return path, timers, nil
+}
+
+func saveTimers(f *pixelaClientFactory, path string, timers map[string]*timer) error {
+	if err := f.writeState(path, timers); err != nil {
+		return fmt.Errorf("write timers state failed: %w", err)
+	}
+	return nil
+}
+
+func findTimer(timers map[string]*timer, id string) (*timer, error) {
+	t, ok := timers[id]
+	if !ok {
+		return nil, fmt.Errorf("timer not found: %s", id)
+	}
+	return t, nil
+}
+
+func printTimers(cmd *cobra.Command, f *pixelaClientFactory, timers []*timer) error {
+	now := f.now()
+	list := make([]timerStatus, 0, len(timers))
+	for _, t := range timers {
+		list = append(list, timerStatus{
+			ID:        t.ID,
+			Running:   t.running(),
+			Native:    t.Native,
+			StartedAt: t.StartedAt,
+			Elapsed:   t.elapsed(now).Truncate(time.Second).String(),
+		})
+	}
+
+	b, err := json.Marshal(&struct {
+		Timers []timerStatus `json:"timers"`
+	}{Timers: list})
+	if err != nil {
+		return fmt.Errorf("marshal timers failed: %w", err)
+	}
+	cmd.Printf("%s\n", string(b))
+	return nil
+}
+
+// timerRounding returns the rounding of the flags, or of the config file when the flags are not specified.
+func timerRounding(cmd *cobra.Command, f *pixelaClientFactory, o *timerOptions) (func(d, m time.Duration) time.Duration, time.Duration, error) {
+	name := o.Rounding
+	if !cmd.Flags().Changed("rounding") {
+		name = f.config.GetString("timer.rounding")
+	}
+	if name == "" {
+		name = "nearest"
+	}
+	round, ok := timerRoundings[name]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported rounding: %s", name)
+	}
+
+	roundTo := o.RoundTo
+	if !cmd.Flags().Changed("round-to") {
+		roundTo = f.config.GetDuration("timer.round_to")
+	}
+	if roundTo == 0 {
+		roundTo = time.Minute
+	}
+	if roundTo < 0 {
+		return nil, 0, fmt.Errorf("round-to must be positive: %s", roundTo)
+	}
+	return round, roundTo, nil
+}
+
+// toggleNativeStopwatch starts or ends the stopwatch of Pixela. It prints the result and returns false when it failed.
+func toggleNativeStopwatch(cmd *cobra.Command, f *pixelaClientFactory, id string) (bool, error) {
+	result, err := f.Graph().StopwatchWithContext(f.Context(), &pixela.GraphStopwatchInput{ID: pixela.String(id)})
+	if err != nil {
+		return false, fmt.Errorf("graph stopwatch failed: %w", err)
+	}
+	return checkTimerResult(cmd, result)
+}
+
+func addTimerQuantity(cmd *cobra.Command, f *pixelaClientFactory, id, quantity string) (bool, error) {
+	result, err := f.Graph().AddWithContext(f.Context(), &pixela.GraphAddInput{ID: pixela.String(id), Quantity: pixela.String(quantity)})
+	if err != nil {
+		return false, fmt.Errorf("graph add failed: %w", err)
+	}
+	return checkTimerResult(cmd, result)
+}
+
+func checkTimerResult(cmd *cobra.Command, result *pixela.Result) (bool, error) {
+	if result.IsSuccess {
+		return true, nil
+	}
+	s, err := marshalResult(result)
+	if err != nil {
+		return false, fmt.Errorf("marshal timer result failed: %w", err)
+	}
+	cmd.Printf("%s\n", s)
+	return false, ErrNeglect
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+// pixelaTimerGraphMock records the inputs of Add and Stopwatch.
+type pixelaTimerGraphMock struct {
+	pixelaGraphMock
+	added       []string
+	stopwatches int
+}
+
+func (p *pixelaTimerGraphMock) AddWithContext(ctx context.Context, input *pixela.GraphAddInput) (*pixela.Result, error) {
+	p.added = append(p.added, pixela.StringValue(input.ID)+":"+pixela.StringValue(input.Quantity))
+	return &p.result, p.err
+}
+
+func (p *pixelaTimerGraphMock) StopwatchWithContext(ctx context.Context, input *pixela.GraphStopwatchInput) (*pixela.Result, error) {
+	p.stopwatches++
+	return &p.result, p.err
+}
+
+func setupTimer(t *testing.T) (*pixelaClientFactory, *pixelaTimerGraphMock, func(time.Duration)) {
+	t.Helper()
+	setupConfigHome(t)
+
+	mock := &pixelaTimerGraphMock{pixelaGraphMock: pixelaGraphMock{result: *successResult()}}
+	f := newPixelaClientFactory()
+	f.graph = mock
+
+	now := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+
+	return f, mock, func(d time.Duration) { now = now.Add(d) }
+}
+
+func executeTimer(t *testing.T, f *pixelaClientFactory, commandline string) (string, error) {
+	t.Helper()
+	cmd := newCmdRoot(f)
+	buffer := bytes.NewBuffer([]byte{})
+	cmd.SetOut(buffer)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(strings.Split(commandline, " "))
+	err := cmd.Execute()
+	return buffer.String(), err
+}
+
+func TestTimer(t *testing.T) {
+	f, mock, advance := setupTimer(t)
+
+	out, err := executeTimer(t, f, "timer start --id=graph-id")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"timers":[{"id":"graph-id","running":true,"native":false,"startedAt":"2020-01-01T09:00:00Z","elapsed":"0s"}]}`+"\n", out)
+
+	_, err = executeTimer(t, f, "timer start --id=graph-id")
+	assert.EqualError(t, err, "timer already started: graph-id")
+
+	advance(20 * time.Minute)
+	_, err = executeTimer(t, f, "timer pause --id=graph-id")
+	assert.NoError(t, err)
+
+	// 一時停止中の時間は経過時間に含めない
+	advance(time.Hour)
+	out, err = executeTimer(t, f, "timer status")
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"running":false`)
+	assert.Contains(t, out, `"elapsed":"20m0s"`)
+
+	_, err = executeTimer(t, f, "timer resume --id=graph-id")
+	assert.NoError(t, err)
+	advance(12*time.Minute + 40*time.Second)
+
+	out, err = executeTimer(t, f, "timer stop --id=graph-id")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"graph-id","elapsed":"32m40s","quantity":"33"}`+"\n", out)
+	assert.Equal(t, []string{"graph-id:33"}, mock.added)
+
+	out, err = executeTimer(t, f, "timer status")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"timers":[]}`+"\n", out)
+}
+
+func TestTimerRounding(t *testing.T) {
+	params := []struct {
+		config   map[string]interface{}
+		flags    string
+		expected string
+	}{
+		{expected: "38"},
+		{flags: " --rounding=down", expected: "37"},
+		{flags: " --rounding=up --round-to=5m", expected: "40"},
+		{config: map[string]interface{}{"rounding": "down", "round_to": "15m"}, expected: "30"},
+		{config: map[string]interface{}{"rounding": "down", "round_to": "15m"}, flags: " --rounding=nearest", expected: "45"},
+	}
+
+	for _, p := range params {
+		f, mock, advance := setupTimer(t)
+		f.config.Set("timer", p.config)
+
+		_, err := executeTimer(t, f, "timer start --id=graph-id")
+		assert.NoError(t, err)
+		advance(37*time.Minute + 30*time.Second)
+
+		_, err = executeTimer(t, f, "timer stop --id=graph-id"+p.flags)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"graph-id:" + p.expected}, mock.added, p.flags)
+	}
+}
+
+func TestTimerNative(t *testing.T) {
+	f, mock, advance := setupTimer(t)
+
+	_, err := executeTimer(t, f, "timer start --id=graph-id --native")
+	assert.NoError(t, err)
+	_, err = executeTimer(t, f, "timer pause --id=graph-id")
+	assert.EqualError(t, err, "the stopwatch of Pixela cannot be paused: graph-id")
+
+	advance(10 * time.Minute)
+	out, err := executeTimer(t, f, "timer stop --id=graph-id")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"graph-id","elapsed":"10m0s"}`+"\n", out)
+	assert.Equal(t, 2, mock.stopwatches)
+	assert.Empty(t, mock.added)
+}
+
+func TestTimerStopFailed(t *testing.T) {
+	f, mock, advance := setupTimer(t)
+
+	_, err := executeTimer(t, f, "timer start --id=graph-id")
+	assert.NoError(t, err)
+	advance(5 * time.Minute)
+
+	// 失敗したときはタイマーを残して再実行できるようにする
+	mock.result = pixela.Result{Message: "Specified graphID not exist.", StatusCode: http.StatusNotFound}
+	out, err := executeTimer(t, f, "timer stop --id=graph-id")
+	assert.True(t, errors.Is(err, ErrNeglect))
+	assert.Contains(t, out, "Specified graphID not exist.")
+
+	mock.result = *successResult()
+	out, err = executeTimer(t, f, "timer stop --id=graph-id")
+	assert.NoError(t, err)
+	var r timerStopResult
+	assert.NoError(t, json.Unmarshal([]byte(out), &r))
+	assert.Equal(t, "5", r.Quantity)
+
+	_, err = executeTimer(t, f, "timer stop --id=graph-id")
+	assert.EqualError(t, err, "timer not found: graph-id")
+}
@@ -0,0 +1,573 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebc-2in2crc/pa/pkg/pa"
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// tuiWeeks is the number of the weeks of the heatmap fetched from Pixela.
+	tuiWeeks = 26
+	// tuiListWidth is the width of the graph list on the left.
+	tuiListWidth = 24
+	// tuiJobs is the number of the API calls waiting for the background worker.
+	tuiJobs = 16
+)
+
+// tuiColors are the ANSI colors of the heatmap for the colors of the graphs.
+var tuiColors = map[string]string{
+	"shibafu": "32",
+	"momiji":  "31",
+	"sora":    "34",
+	"ichou":   "33",
+	"ajisai":  "35",
+	"kuro":    "90",
+}
+
+// tuiJob is an API call run by the background worker. It returns the function which applies the result to the TUI.
+type tuiJob func() func(t *tui)
+
+// NewCmdTUI creates a tui command.
+func NewCmdTUI(f *pixelaClientFactory) *cobra.Command {
+	var interval time.Duration
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Show a dashboard of the graphs in the terminal",
+		Long: "Show the graphs with the heatmap, the statistics and the latest Pixel of the selected graph,\n" +
+			"and refresh them in the background.\n\n" +
+			"Keys:\n" +
+			"  up/down, k/j  select the graph\n" +
+			"  +/-           increment or decrement today's Pixel\n" +
+			"  e             edit today's quantity\n" +
+			"  r             refresh now\n" +
+			"  q             quit",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interval <= 0 {
+				return fmt.Errorf("interval must be positive: %s", interval)
+			}
+			if cmd.InOrStdin() != os.Stdin || !f.stdinIsTerminal() {
+				return errors.New("pa tui requires a terminal")
+			}
+			restore, err := makeRaw(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("pa tui requires a terminal: %w", err)
+			}
+			defer restore()
+
+			t := newTUI(f, cmd)
+			t.size = func() (int, int) {
+				w, h, err := terminalSize(os.Stdout)
+				if err != nil {
+					return 80, 24
+				}
+				return w, h
+			}
+			// 代替スクリーンに切り替えてカーソルを隠す
+			fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+			defer fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+			return t.run(cmd.InOrStdin(), interval)
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 60*time.Second, "Refresh interval")
+
+	return cmd
+}
+
+// tuiGraph is the state of a graph shown in the TUI.
+type tuiGraph struct {
+	snapshot *watchSnapshot
+	// quantities は日付ごとの Pixel の数量
+	quantities map[string]float64
+	err        error
+	updated    time.Time
+}
+
+// tui is a full-screen dashboard of the graphs.
+// The API calls run on a background worker one by one, and only the main loop changes the state.
+type tui struct {
+	f   *pixelaClientFactory
+	ctx context.Context
+	out io.Writer
+	// size returns the width and the height of the screen
+	size func() (int, int)
+
+	// globalArgs はコマンドの実行時に引き継ぐグローバルフラグ
+	globalArgs []string
+
+	graphs   []pixela.GraphDefinition
+	selected int
+	views    map[string]*tuiGraph
+	status   string
+	// editing が true のときは今日の数量を input に入力している
+	editing bool
+	input   string
+
+	jobs chan tuiJob
+}
+
+func newTUI(f *pixelaClientFactory, cmd *cobra.Command) *tui {
+	return &tui{
+		f:          f,
+		ctx:        f.Context(),
+		out:        cmd.OutOrStdout(),
+		size:       func() (int, int) { return 80, 24 },
+		globalArgs: globalArgs(cmd),
+		views:      map[string]*tuiGraph{},
+		jobs:       make(chan tuiJob, tuiJobs),
+	}
+}
+
+// run draws the screen and handles the keys read from in until quit, and refreshes the graphs every interval.
+// It waits for the running API call before returning.
+func (t *tui) run(in io.Reader, interval time.Duration) error {
+	ctx, cancel := context.WithCancel(t.ctx)
+	defer cancel()
+	t.ctx = ctx
+
+	results := make(chan func(*tui), tuiJobs)
+	go func() {
+		defer close(results)
+		for job := range t.jobs {
+			results <- job()
+		}
+	}()
+	defer func() {
+		cancel()
+		close(t.jobs)
+		for range results {
+		}
+	}()
+
+	keys := make(chan string)
+	go readKeys(bufio.NewReader(in), keys)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	t.refresh()
+	for {
+		t.draw()
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok || t.handleKey(key) {
+				return nil
+			}
+		case <-ticker.C:
+			t.refresh()
+		case apply := <-results:
+			apply(t)
+		}
+	}
+}
+
+// readKeys sends the keys read from r until an error. The special keys are sent by their names such as "up".
+func readKeys(r *bufio.Reader, keys chan<- string) {
+	defer close(keys)
+	for {
+		key, err := readKey(r)
+		if err != nil {
+			return
+		}
+		keys <- key
+	}
+}
+
+func readKey(r *bufio.Reader) (string, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+
+	switch c {
+	case keyCR, keyLF:
+		return "enter", nil
+	case keyBackspace, keyDelete:
+		return "backspace", nil
+	case keyCtrlC:
+		return "ctrl-c", nil
+	case keyCtrlD:
+		return "ctrl-d", nil
+	case keyEscape:
+		// 続きのバイトが届いていないときは単独の Esc キー
+		if r.Buffered() == 0 {
+			return "esc", nil
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b != '[' && b != 'O' {
+			return "esc", nil
+		}
+		for {
+			b, err = r.ReadByte()
+			if err != nil {
+				return "", err
+			}
+			if b >= 0x40 && b <= 0x7e {
+				break
+			}
+		}
+		switch b {
+		case 'A':
+			return "up", nil
+		case 'B':
+			return "down", nil
+		case 'C':
+			return "right", nil
+		case 'D':
+			return "left", nil
+		}
+		return "", nil
+	}
+	return string(c), nil
+}
+
+// handleKey changes the state by the key, and reports whether the TUI quits.
+func (t *tui) handleKey(key string) bool {
+	if t.editing {
+		switch key {
+		case "enter":
+			t.editing = false
+			if g := t.current(); g != nil && t.input != "" {
+				t.mutate(g.ID, "pixel", "update", "--graph-id="+g.ID, "--date="+t.f.now().Format(pa.DateLayout), "--quantity="+t.input)
+			}
+		case "esc", "ctrl-c":
+			t.editing = false
+		case "backspace":
+			if t.input != "" {
+				t.input = t.input[:len(t.input)-1]
+			}
+		default:
+			if len(key) == 1 && strings.Contains("0123456789.-", key) {
+				t.input += key
+			}
+		}
+		return false
+	}
+
+	switch key {
+	case "q", "ctrl-c", "ctrl-d":
+		return true
+	case "up", "k":
+		t.selectGraph(t.selected - 1)
+	case "down", "j":
+		t.selectGraph(t.selected + 1)
+	case "+":
+		if g := t.current(); g != nil {
+			t.mutate(g.ID, "pixel", "increment", "--graph-id="+g.ID)
+		}
+	case "-":
+		if g := t.current(); g != nil {
+			t.mutate(g.ID, "pixel", "decrement", "--graph-id="+g.ID)
+		}
+	case "e":
+		if t.current() != nil {
+			t.editing, t.input = true, ""
+		}
+	case "r":
+		t.refresh()
+	}
+	return false
+}
+
+func (t *tui) current() *pixela.GraphDefinition {
+	if t.selected < 0 || t.selected >= len(t.graphs) {
+		return nil
+	}
+	return &t.graphs[t.selected]
+}
+
+// selectGraph selects the graph at i, and fetches it unless it is fetched yet.
+func (t *tui) selectGraph(i int) {
+	if i < 0 || i >= len(t.graphs) {
+		return
+	}
+	t.selected = i
+	if id := t.graphs[i].ID; t.views[id] == nil {
+		t.enqueue(t.fetchGraph(id))
+	}
+}
+
+// enqueue queues the job for the background worker. The job is dropped while the worker is busy.
+func (t *tui) enqueue(job tuiJob) {
+	select {
+	case t.jobs <- job:
+	default:
+		t.status = "busy, try again later"
+	}
+}
+
+// refresh fetches the graph list and the selected graph.
+func (t *tui) refresh() {
+	t.enqueue(t.fetchGraphs())
+	if g := t.current(); g != nil {
+		t.enqueue(t.fetchGraph(g.ID))
+	}
+}
+
+func (t *tui) fetchGraphs() tuiJob {
+	ctx, f := t.ctx, t.f
+	return func() func(*tui) {
+		defs, err := f.Graph().GetAllWithContext(ctx)
+		if err == nil && !defs.IsSuccess {
+			err = errors.New(defs.Message)
+		}
+		return func(t *tui) {
+			if err != nil {
+				t.status = fmt.Sprintf("graph list failed: %v", err)
+				return
+			}
+
+			// 一覧が変わっても選んでいたグラフを選んだままにする
+			selected := ""
+			if g := t.current(); g != nil {
+				selected = g.ID
+			}
+			t.graphs, t.selected = defs.Graphs, 0
+			for i, g := range t.graphs {
+				if g.ID == selected {
+					t.selected = i
+				}
+			}
+			if g := t.current(); g != nil && t.views[g.ID] == nil {
+				t.enqueue(t.fetchGraph(g.ID))
+			}
+		}
+	}
+}
+
+func (t *tui) fetchGraph(id string) tuiJob {
+	ctx, f := t.ctx, t.f
+	return func() func(*tui) {
+		view := &tuiGraph{updated: f.now()}
+		view.snapshot, view.err = pollGraph(ctx, f, id)
+		if view.err == nil {
+			view.quantities, view.err = fetchQuantities(ctx, f, id)
+		}
+		return func(t *tui) {
+			t.views[id] = view
+		}
+	}
+}
+
+// fetchQuantities gets the quantities of the Pixels of the heatmap.
+func fetchQuantities(ctx context.Context, f *pixelaClientFactory, id string) (map[string]float64, error) {
+	today := f.now()
+	from := today.AddDate(0, 0, -7*tuiWeeks)
+	pixels, err := f.Client().FetchPixels(ctx, id, from.Format(pa.DateLayout), today.Format(pa.DateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	quantities := map[string]float64{}
+	for _, p := range pixels {
+		if q, err := strconv.ParseFloat(p.Quantity, 64); err == nil {
+			quantities[p.Date] = q
+		}
+	}
+	return quantities, nil
+}
+
+// mutate runs the pa command on the graph, and fetches the graph again.
+// The command runs on the command tree, so that the dry run and the undo journal apply to it.
+func (t *tui) mutate(id string, args ...string) {
+	ctx, f, globals := t.ctx, t.f, t.globalArgs
+	fetch := t.fetchGraph(id)
+	t.status = strings.Join(args[:2], " ") + " " + id + " ..."
+	t.enqueue(func() func(*tui) {
+		status := runTUICommand(ctx, f, append(append([]string{}, globals...), args...))
+		apply := fetch()
+		return func(t *tui) {
+			t.status = strings.Join(args[:2], " ") + " " + id + ": " + status
+			apply(t)
+		}
+	})
+}
+
+// runTUICommand runs the command and returns the message of its result.
+func runTUICommand(ctx context.Context, f *pixelaClientFactory, args []string) string {
+	child := f.child()
+	defer child.release()
+	root := newCmdRoot(child)
+	buffer := bytes.NewBuffer([]byte{})
+	root.SetOut(buffer)
+	root.SetErr(buffer)
+	root.SetArgs(args)
+
+	err := root.ExecuteContext(ctx)
+	if err != nil && !errors.Is(err, ErrNeglect) {
+		return err.Error()
+	}
+	return resultMessage(buffer.String())
+}
+
+// draw redraws the whole screen.
+func (t *tui) draw() {
+	width, height := t.size()
+	lines := t.render(width, height)
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range lines {
+		b.WriteString(line)
+		b.WriteString("\x1b[K")
+		if i < len(lines)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+	b.WriteString("\x1b[J")
+	fmt.Fprint(t.out, b.String())
+}
+
+// render returns the lines of the screen: the header, the graph list and the selected graph side by side,
+// the status and the keys.
+func (t *tui) render(width, height int) []string {
+	lines := []string{
+		"pa tui - " + t.f.Username(),
+		strings.Repeat("-", width),
+	}
+
+	rows := height - 4
+	if rows < 1 {
+		rows = 1
+	}
+	// 選んでいるグラフが見えるように一覧をスクロールする
+	offset := 0
+	if t.selected >= rows {
+		offset = t.selected - rows + 1
+	}
+	detail := t.renderGraph(width - tuiListWidth - 3)
+	for i := 0; i < rows; i++ {
+		item := strings.Repeat(" ", tuiListWidth)
+		if n := offset + i; n < len(t.graphs) {
+			item = fmt.Sprintf(" %-*s", tuiListWidth-1, truncate(t.graphs[n].ID, tuiListWidth-1))
+			if n == t.selected {
+				item = "\x1b[7m" + item + "\x1b[0m"
+			}
+		}
+		line := item + " |"
+		if i < len(detail) {
+			line += " " + detail[i]
+		}
+		lines = append(lines, line)
+	}
+
+	status := t.status
+	if t.editing {
+		status = fmt.Sprintf("Today's quantity: %s_", t.input)
+	}
+	return append(lines, status, "up/down: select  +/-: increment/decrement  e: edit today  r: refresh  q: quit")
+}
+
+// renderGraph returns the lines of the selected graph in the width.
+func (t *tui) renderGraph(width int) []string {
+	g := t.current()
+	if g == nil {
+		if len(t.graphs) == 0 {
+			return []string{"No graphs"}
+		}
+		return nil
+	}
+
+	lines := []string{
+		fmt.Sprintf("%s (%s)", g.Name, g.ID),
+		fmt.Sprintf("unit: %s  type: %s  color: %s", g.Unit, g.Type, g.Color),
+		"",
+	}
+	view := t.views[g.ID]
+	if view == nil {
+		return append(lines, "loading...")
+	}
+	if view.err != nil {
+		return append(lines, fmt.Sprintf("error: %v", view.err))
+	}
+
+	now := t.f.now()
+	lines = append(lines, renderHeatmap(view.quantities, tuiColors[g.Color], (width-4)/2, now)...)
+	today := now.Format(pa.DateLayout)
+	s := view.snapshot
+	lines = append(lines,
+		"",
+		fmt.Sprintf("Today %s: %s %s", today, formatQuantity(view.quantities[today]), g.Unit),
+		fmt.Sprintf("Total: %d %s in %d Pixels", s.TotalQuantity, g.Unit, s.TotalPixelsCount),
+	)
+	if s.Latest != nil {
+		lines = append(lines, fmt.Sprintf("Latest %s: %s %s", s.Latest.Date, s.Latest.Quantity, g.Unit))
+	}
+	return append(lines, fmt.Sprintf("Updated at %s", view.updated.Format("15:04:05")))
+}
+
+// renderHeatmap returns the heatmap of the weeks up to the day of now, a column for a week and a row for a day of the week.
+// The shade of a day is the quantity relative to the maximum in the heatmap.
+func renderHeatmap(quantities map[string]float64, color string, weeks int, now time.Time) []string {
+	if weeks > tuiWeeks {
+		weeks = tuiWeeks
+	}
+	if weeks < 1 {
+		weeks = 1
+	}
+
+	today := now
+	start := today.AddDate(0, 0, -int(today.Weekday())-7*(weeks-1))
+	max := 0.0
+	for d := start; !d.After(today); d = d.AddDate(0, 0, 1) {
+		max = math.Max(max, quantities[d.Format(pa.DateLayout)])
+	}
+
+	labels := []string{"   ", "Mon", "   ", "Wed", "   ", "Fri", "   "}
+	shades := []string{"░░", "▒▒", "▓▓", "██"}
+	lines := make([]string, 7)
+	for day := 0; day < 7; day++ {
+		var b strings.Builder
+		b.WriteString(labels[day] + " ")
+		for week := 0; week < weeks; week++ {
+			d := start.AddDate(0, 0, week*7+day)
+			q, ok := quantities[d.Format(pa.DateLayout)]
+			switch {
+			case d.After(today):
+				b.WriteString("  ")
+			case !ok || q <= 0 || max <= 0:
+				b.WriteString("· ")
+			default:
+				level := int(math.Ceil(q/max*float64(len(shades)))) - 1
+				if color != "" {
+					b.WriteString("\x1b[" + color + "m" + shades[level] + "\x1b[0m")
+				} else {
+					b.WriteString(shades[level])
+				}
+			}
+		}
+		lines[day] = b.String()
+	}
+	return lines
+}
+
+func formatQuantity(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
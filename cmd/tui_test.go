@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTUI(t *testing.T) (*tui, *pixelaFake) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	fake := newPixelaFake()
+	fake.addGraph(
+		pixela.GraphDefinition{ID: "graph-a", Name: "Reading", Unit: "pages", Type: "int", Color: "shibafu"},
+		pixela.PixelWithBody{Date: "20260106", Quantity: "4"},
+		pixela.PixelWithBody{Date: "20260107", Quantity: "3"},
+	)
+	fake.addGraph(pixela.GraphDefinition{ID: "graph-b", Name: "Running", Unit: "km", Type: "float", Color: "sora"})
+	f := fake.factory()
+	f.now = func() time.Time { return time.Date(2026, 1, 7, 12, 0, 0, 0, time.Local) }
+
+	cmd := NewCmdTUI(f)
+	cmd.SetOut(bytes.NewBuffer([]byte{}))
+	return newTUI(f, cmd), fake
+}
+
+// runTUIJobs runs the queued API calls in order instead of the background worker.
+func runTUIJobs(t *tui) {
+	for len(t.jobs) > 0 {
+		(<-t.jobs)()(t)
+	}
+}
+
+func TestTUI(t *testing.T) {
+	ui, fake := setupTUI(t)
+
+	ui.refresh()
+	runTUIJobs(ui)
+	assert.Len(t, ui.graphs, 2)
+	assert.Equal(t, map[string]float64{"20260106": 4, "20260107": 3}, ui.views["graph-a"].quantities)
+	assert.Equal(t, 7, ui.views["graph-a"].snapshot.TotalQuantity)
+
+	assert.False(t, ui.handleKey("down"))
+	runTUIJobs(ui)
+	assert.Equal(t, "graph-b", ui.current().ID)
+	assert.NotNil(t, ui.views["graph-b"])
+
+	ui.handleKey("+")
+	runTUIJobs(ui)
+	assert.Equal(t, []string{"graph-b:increment"}, fake.added)
+	assert.Equal(t, "pixel increment graph-b: Success.", ui.status)
+
+	for _, key := range []string{"e", "1", "x", "2", "backspace", ".", "5", "enter"} {
+		ui.handleKey(key)
+	}
+	runTUIJobs(ui)
+	assert.Equal(t, "1.5", fake.pixels["graph-b"]["20260107"].Quantity)
+	assert.Equal(t, map[string]float64{"20260107": 1.5}, ui.views["graph-b"].quantities)
+
+	ui.handleKey("e")
+	ui.handleKey("9")
+	ui.handleKey("esc")
+	assert.False(t, ui.editing)
+	assert.Empty(t, ui.jobs)
+
+	// 一覧を読み直しても選んでいるグラフは変わらない
+	delete(fake.definitions, "graph-a")
+	ui.handleKey("r")
+	runTUIJobs(ui)
+	assert.Equal(t, "graph-b", ui.current().ID)
+
+	assert.True(t, ui.handleKey("q"))
+}
+
+func TestTUIRender(t *testing.T) {
+	ui, _ := setupTUI(t)
+	ui.refresh()
+	runTUIJobs(ui)
+
+	lines := ui.render(80, 24)
+
+	assert.Len(t, lines, 24)
+	assert.Equal(t, "\x1b[7m graph-a                \x1b[0m | Reading (graph-a)", lines[2])
+	assert.Equal(t, " graph-b                 | unit: pages  type: int  color: shibafu", lines[3])
+	assert.Contains(t, lines[7], "\x1b[32m██\x1b[0m")
+	assert.Contains(t, lines[13], "Today 20260107: 3 pages")
+	assert.Contains(t, lines[14], "Total: 7 pages in 2 Pixels")
+	assert.Contains(t, lines[15], "Latest 20260107: 3 pages")
+
+	ui.handleKey("e")
+	ui.handleKey("4")
+	assert.Equal(t, "Today's quantity: 4_", ui.render(80, 24)[22])
+}
+
+func TestRenderHeatmap(t *testing.T) {
+	setupTUI(t)
+	quantities := map[string]float64{"20251229": 1, "20260105": 4, "20260107": 2, "20260108": 8}
+
+	lines := renderHeatmap(quantities, "", 2, time.Date(2026, 1, 7, 12, 0, 0, 0, time.Local))
+
+	assert.Equal(t, []string{
+		"    · · ",
+		"Mon ░░██",
+		"    · · ",
+		"Wed · ▒▒",
+		"    ·   ",
+		"Fri ·   ",
+		"    ·   ",
+	}, lines)
+}
+
+func TestTUIRun(t *testing.T) {
+	ui, _ := setupTUI(t)
+	out := bytes.NewBuffer([]byte{})
+	ui.out = out
+
+	err := ui.run(strings.NewReader("jjq"), time.Hour)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "pa tui - ")
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("a\x1b[A\x1b[B\r\x7f\x03+"))
+
+	var keys []string
+	for {
+		key, err := readKey(r)
+		if err != nil {
+			break
+		}
+		keys = append(keys, key)
+	}
+
+	assert.Equal(t, []string{"a", "up", "down", "enter", "backspace", "ctrl-c", "+"}, keys)
+}
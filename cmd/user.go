@@ -1,23 +1,24 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
 	pixela "github.com/ebc-2in2crc/pixela4go"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
-var userOptions = &struct {
+type userOptions struct {
 	AgreeTermsOfService bool
 	NotMinor            bool
 	ThanksCode          string
 	NewToken            string
 	DeleteMe            bool
-}{}
+	Force               bool
+}
 
 // NewCmdUser creates a user command.
-func NewCmdUser() *cobra.Command {
+func NewCmdUser(f *pixelaClientFactory) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "user",
 		Short: "User",
@@ -25,35 +26,35 @@ func NewCmdUser() *cobra.Command {
 		RunE:  showHelp,
 	}
 
-	cmd.AddCommand(NewCmdUserCreate())
-	cmd.AddCommand(NewCmdUserUpdate())
-	cmd.AddCommand(NewCmdUserDelete())
+	cmd.AddCommand(NewCmdUserCreate(f, &userOptions{}))
+	cmd.AddCommand(NewCmdUserUpdate(f, &userOptions{}))
+	cmd.AddCommand(NewCmdUserDelete(f, &userOptions{}))
 
 	return cmd
 }
 
 // NewCmdUserCreate creates a create user command.
-func NewCmdUserCreate() *cobra.Command {
+func NewCmdUserCreate(f *pixelaClientFactory, o *userOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create a new Pixela user",
 		Args:  cobra.NoArgs,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if err := viper.BindPFlag("agree_terms_of_service", cmd.Flags().Lookup("agree-terms-of-service")); err != nil {
+			if err := f.config.BindPFlag("agree_terms_of_service", cmd.Flags().Lookup("agree-terms-of-service")); err != nil {
 				return fmt.Errorf("bind flag failed: %w", err)
 			}
-			if err := viper.BindPFlag("not_minor", cmd.Flags().Lookup("not-minor")); err != nil {
+			if err := f.config.BindPFlag("not_minor", cmd.Flags().Lookup("not-minor")); err != nil {
 				return fmt.Errorf("bind flag failed: %w", err)
 			}
-			if err := viper.BindPFlag("thanks_code", cmd.Flags().Lookup("thanks-code")); err != nil {
+			if err := f.config.BindPFlag("thanks_code", cmd.Flags().Lookup("thanks-code")); err != nil {
 				return fmt.Errorf("bind flag failed: %w", err)
 			}
 
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createUserCreateInput()
-			result, err := pixelaClient.User().Create(input)
+			input := createUserCreateInput(f)
+			result, err := f.User().CreateWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("user create failed: %w", err)
 			}
@@ -70,40 +71,40 @@ func NewCmdUserCreate() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().BoolVarP(&userOptions.AgreeTermsOfService, "agree-terms-of-service", "a", false, "Agree to the terms of service")
-	cmd.Flags().BoolVarP(&userOptions.NotMinor, "not-minor", "m", false, "You are not a minor or if you are a minor and you have the parental consent of using this service")
-	cmd.Flags().StringVarP(&userOptions.ThanksCode, "thanks-code", "c", "", "Like a registration code obtained when you register for Patreon support")
+	cmd.Flags().BoolVarP(&o.AgreeTermsOfService, "agree-terms-of-service", "a", false, "Agree to the terms of service")
+	cmd.Flags().BoolVarP(&o.NotMinor, "not-minor", "m", false, "You are not a minor or if you are a minor and you have the parental consent of using this service")
+	cmd.Flags().StringVarP(&o.ThanksCode, "thanks-code", "c", "", "Like a registration code obtained when you register for Patreon support")
 
 	return cmd
 }
 
-func createUserCreateInput() *pixela.UserCreateInput {
+func createUserCreateInput(f *pixelaClientFactory) *pixela.UserCreateInput {
 	return &pixela.UserCreateInput{
-		AgreeTermsOfService: getBoolFlag("agree_terms_of_service"),
-		NotMinor:            getBoolFlag("not_minor"),
-		ThanksCode:          getStringFlag("thanks_code"),
+		AgreeTermsOfService: f.getBoolFlag("agree_terms_of_service"),
+		NotMinor:            f.getBoolFlag("not_minor"),
+		ThanksCode:          f.getStringFlag("thanks_code"),
 	}
 }
 
 // NewCmdUserUpdate creates a update user command.
-func NewCmdUserUpdate() *cobra.Command {
+func NewCmdUserUpdate(f *pixelaClientFactory, o *userOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "update",
 		Short: "Updates user token",
 		Args:  cobra.NoArgs,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if err := viper.BindPFlag("new_token", cmd.Flags().Lookup("new-token")); err != nil {
+			if err := f.config.BindPFlag("new_token", cmd.Flags().Lookup("new-token")); err != nil {
 				return fmt.Errorf("bind flag failed: %w", err)
 			}
-			if err := viper.BindPFlag("thanks_code", cmd.Flags().Lookup("thanks-code")); err != nil {
+			if err := f.config.BindPFlag("thanks_code", cmd.Flags().Lookup("thanks-code")); err != nil {
 				return fmt.Errorf("bind flag failed: %w", err)
 			}
 
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createUserUpdateInput()
-			result, err := pixelaClient.User().Update(input)
+			input := createUserUpdateInput(f)
+			result, err := f.User().UpdateWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("user update failed: %w", err)
 			}
@@ -120,32 +121,39 @@ func NewCmdUserUpdate() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&userOptions.NewToken, "new-token", "n", "", "A new authentication token for update")
-	cmd.Flags().StringVarP(&userOptions.ThanksCode, "thanks-code", "c", "", "Like a registration code obtained when you register for Patreon support")
+	cmd.Flags().StringVarP(&o.NewToken, "new-token", "n", "", "A new authentication token for update")
+	cmd.Flags().StringVarP(&o.ThanksCode, "thanks-code", "c", "", "Like a registration code obtained when you register for Patreon support")
 
 	return cmd
 }
 
-func createUserUpdateInput() *pixela.UserUpdateInput {
+func createUserUpdateInput(f *pixelaClientFactory) *pixela.UserUpdateInput {
 	return &pixela.UserUpdateInput{
-		NewToken:   getStringFlag("new_token"),
-		ThanksCode: getStringFlag("thanks_code"),
+		NewToken:   f.getStringFlag("new_token"),
+		ThanksCode: f.getStringFlag("thanks_code"),
 	}
 }
 
 // NewCmdUserDelete creates a update user command.
-func NewCmdUserDelete() *cobra.Command {
+func NewCmdUserDelete(f *pixelaClientFactory, o *userOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "delete",
 		Short: "Delete a Pixela user",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !userOptions.DeleteMe {
+			if !o.DeleteMe {
 				cmd.Println("Specify the '--delete-me' flag to confirm the deletion.")
 				return nil
 			}
+			// ユーザーを削除するとすべてのグラフが削除されるので保護されたグラフがあるときは拒否する
+			if len(f.config.GetStringSlice("protected_graphs")) > 0 && !o.Force {
+				return errors.New("protected_graphs is set, specify '--force' to delete the user and all the graphs")
+			}
+			if err := confirmByTyping(cmd, f, "username", f.Username()); err != nil {
+				return err
+			}
 
-			result, err := pixelaClient.User().Delete()
+			result, err := f.User().DeleteWithContext(f.Context())
 			if err != nil {
 				return fmt.Errorf("user delete failed: %w", err)
 			}
@@ -164,7 +172,8 @@ func NewCmdUserDelete() *cobra.Command {
 
 	// ユーザーの削除は非常に危険なので `--delete-me` フラグが指定したときだけ削除する
 	// 環境変数は読み込まないように viper にはバインドしないでおく
-	cmd.Flags().BoolVarP(&userOptions.DeleteMe, "delete-me", "", false, "Delete your Pixela account")
+	cmd.Flags().BoolVarP(&o.DeleteMe, "delete-me", "", false, "Delete your Pixela account")
+	cmd.Flags().BoolVar(&o.Force, "force", false, "Delete your Pixela account even if protected_graphs is set")
 
 	return cmd
 }
@@ -7,7 +7,7 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var userProfileOptions = &struct {
+type userProfileOptions struct {
 	DisplayName       string
 	GravatarIconEmail string
 	Title             string
@@ -15,10 +15,10 @@ var userProfileOptions = &struct {
 	AboutURL          string
 	ContributeURLs    []string
 	PinnedGraphID     string
-}{}
+}
 
 // NewCmdUserProfile creates a user profile command.
-func NewCmdUserProfile() *cobra.Command {
+func NewCmdUserProfile(f *pixelaClientFactory) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "profile",
 		Short: "Profile",
@@ -26,21 +26,21 @@ func NewCmdUserProfile() *cobra.Command {
 		RunE:  showHelp,
 	}
 
-	cmd.AddCommand(NewCmdUserProfileUpdate())
-	cmd.AddCommand(NewCmdUserProfileURL())
+	cmd.AddCommand(NewCmdUserProfileUpdate(f, &userProfileOptions{}))
+	cmd.AddCommand(NewCmdUserProfileURL(f))
 
 	return cmd
 }
 
 // NewCmdUserProfileUpdate creates a update user profile command.
-func NewCmdUserProfileUpdate() *cobra.Command {
+func NewCmdUserProfileUpdate(f *pixelaClientFactory, o *userProfileOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "update",
 		Short: "Updates User Profile",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createUserProfileUpdateInput()
-			result, err := pixelaClient.UserProfile().Update(input)
+			input := createUserProfileUpdateInput(o)
+			result, err := f.UserProfile().UpdateWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("user profile update failed: %w", err)
 			}
@@ -57,37 +57,37 @@ func NewCmdUserProfileUpdate() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&userProfileOptions.DisplayName, "display-name", "", "The user's name for the display")
-	cmd.Flags().StringVar(&userProfileOptions.GravatarIconEmail, "gravatar-icon-email", "", "The email address registered as an icon in Gravatar")
-	cmd.Flags().StringVar(&userProfileOptions.Title, "title", "", "The title of the user")
-	cmd.Flags().StringVar(&userProfileOptions.Timezone, "timezone", "", "Specify the user's time zone")
-	cmd.Flags().StringVar(&userProfileOptions.AboutURL, "about-url", "", "Users can only show one external link")
-	cmd.Flags().StringSliceVar(&userProfileOptions.ContributeURLs, "contribute-urls", []string{}, "The contribute URLs")
-	cmd.Flags().StringVar(&userProfileOptions.PinnedGraphID, "pinned-graph-id", "", "Pin one of their own graphs")
+	cmd.Flags().StringVar(&o.DisplayName, "display-name", "", "The user's name for the display")
+	cmd.Flags().StringVar(&o.GravatarIconEmail, "gravatar-icon-email", "", "The email address registered as an icon in Gravatar")
+	cmd.Flags().StringVar(&o.Title, "title", "", "The title of the user")
+	cmd.Flags().StringVar(&o.Timezone, "timezone", "", "Specify the user's time zone")
+	cmd.Flags().StringVar(&o.AboutURL, "about-url", "", "Users can only show one external link")
+	cmd.Flags().StringSliceVar(&o.ContributeURLs, "contribute-urls", []string{}, "The contribute URLs")
+	cmd.Flags().StringVar(&o.PinnedGraphID, "pinned-graph-id", "", "Pin one of their own graphs")
 
 	return cmd
 }
 
-func createUserProfileUpdateInput() *pixela.UserProfileUpdateInput {
+func createUserProfileUpdateInput(o *userProfileOptions) *pixela.UserProfileUpdateInput {
 	return &pixela.UserProfileUpdateInput{
-		DisplayName:       getStringPtr(userProfileOptions.DisplayName),
-		GravatarIconEmail: getStringPtr(userProfileOptions.GravatarIconEmail),
-		Title:             getStringPtr(userProfileOptions.Title),
-		Timezone:          getStringPtr(userProfileOptions.Timezone),
-		AboutURL:          getStringPtr(userProfileOptions.AboutURL),
-		ContributeURLs:    userProfileOptions.ContributeURLs,
-		PinnedGraphID:     getStringPtr(userProfileOptions.PinnedGraphID),
+		DisplayName:       getStringPtr(o.DisplayName),
+		GravatarIconEmail: getStringPtr(o.GravatarIconEmail),
+		Title:             getStringPtr(o.Title),
+		Timezone:          getStringPtr(o.Timezone),
+		AboutURL:          getStringPtr(o.AboutURL),
+		ContributeURLs:    o.ContributeURLs,
+		PinnedGraphID:     getStringPtr(o.PinnedGraphID),
 	}
 }
 
 // NewCmdUserProfileURL creates a user profile URL command.
-func NewCmdUserProfileURL() *cobra.Command {
+func NewCmdUserProfileURL(f *pixelaClientFactory) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get",
 		Short: "Get User Profile page URL",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			url := pixelaClient.UserProfile().URL()
+			url := f.UserProfile().URL()
 			cmd.Printf("%s\n", url)
 
 			return nil
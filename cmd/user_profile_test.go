@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"io"
 	"net/http"
 	"strings"
 	"testing"
@@ -17,7 +17,7 @@ type pixelaUserProfileMock struct {
 	err    error
 }
 
-func (p *pixelaUserProfileMock) Update(input *pixela.UserProfileUpdateInput) (*pixela.Result, error) {
+func (p *pixelaUserProfileMock) UpdateWithContext(ctx context.Context, input *pixela.UserProfileUpdateInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
@@ -57,13 +57,11 @@ func TestUserProfileUpdateInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &userProfileOptions{}
+		cmd := NewCmdUserProfileUpdate(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createUserProfileUpdateInput()
+		input := createUserProfileUpdateInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.DisplayName), pixela.StringValue(input.DisplayName), "DisplayName")
 		assert.EqualValues(t, pixela.StringValue(p.expected.DisplayName), pixela.StringValue(input.DisplayName), "DisplayName")
@@ -77,7 +75,6 @@ func TestUserProfileUpdateInput(t *testing.T) {
 }
 
 func TestUserProfileUpdate(t *testing.T) {
-	defer func() { pixelaClient.profile = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -109,11 +106,12 @@ func TestUserProfileUpdate(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.profile = &pixelaUserProfileMock{
+		f := newPixelaClientFactory()
+		f.profile = &pixelaUserProfileMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdUserProfileUpdate()
+		c := NewCmdUserProfileUpdate(f, &userProfileOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -128,15 +126,15 @@ func TestUserProfileUpdate(t *testing.T) {
 }
 
 func TestUserProfileURL(t *testing.T) {
-	defer func() { pixelaClient.profile = nil }()
-	pixelaClient.profile = &pixelaUserProfileMock{}
-	c := NewCmdUserProfileURL()
+	f := newPixelaClientFactory()
+	f.profile = &pixelaUserProfileMock{}
+	c := NewCmdUserProfileURL(f)
 	buffer := bytes.NewBuffer([]byte{})
 	c.SetOut(buffer)
 
 	err := c.RunE(c, []string{})
 
 	assert.NoError(t, err)
-	expected := pixelaClient.profile.URL() + "\n"
+	expected := f.profile.URL() + "\n"
 	assert.Equal(t, expected, buffer.String())
 }
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -18,15 +19,15 @@ type pixelaUserMock struct {
 	err    error
 }
 
-func (p *pixelaUserMock) Create(input *pixela.UserCreateInput) (*pixela.Result, error) {
+func (p *pixelaUserMock) CreateWithContext(ctx context.Context, input *pixela.UserCreateInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaUserMock) Update(input *pixela.UserUpdateInput) (*pixela.Result, error) {
+func (p *pixelaUserMock) UpdateWithContext(ctx context.Context, input *pixela.UserUpdateInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaUserMock) Delete() (*pixela.Result, error) {
+func (p *pixelaUserMock) DeleteWithContext(ctx context.Context) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
@@ -72,13 +73,15 @@ func TestUserCreateInput(t *testing.T) {
 
 	for _, p := range params {
 		setOSEnv(p.envs)
-		cmd := NewCmdRoot()
+		f := newPixelaClientFactory()
+		f.user = &pixelaUserMock{}
+		cmd := newCmdRoot(f)
 		cmd.SetOut(io.Discard)
 		args := strings.Split(p.commandline, " ")
 		cmd.SetArgs(args)
 		_ = cmd.Execute()
 
-		input := createUserCreateInput()
+		input := createUserCreateInput(f)
 
 		assert.EqualValues(t, p.expected.AgreeTermsOfService, input.AgreeTermsOfService, "AgreeTermsOfService")
 		assert.EqualValues(t, p.expected.NotMinor, input.NotMinor, "NotMinor")
@@ -87,7 +90,6 @@ func TestUserCreateInput(t *testing.T) {
 }
 
 func TestUserCreate(t *testing.T) {
-	defer func() { pixelaClient.user = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -119,11 +121,12 @@ func TestUserCreate(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.user = &pixelaUserMock{
+		f := newPixelaClientFactory()
+		f.user = &pixelaUserMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdUserCreate()
+		c := NewCmdUserCreate(f, &userOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -174,13 +177,15 @@ func TestUserUpdateInput(t *testing.T) {
 
 	for _, p := range params {
 		setOSEnv(p.envs)
-		cmd := NewCmdRoot()
+		f := newPixelaClientFactory()
+		f.user = &pixelaUserMock{}
+		cmd := newCmdRoot(f)
 		cmd.SetOut(io.Discard)
 		args := strings.Split(p.commandline, " ")
 		cmd.SetArgs(args)
 		_ = cmd.Execute()
 
-		input := createUserUpdateInput()
+		input := createUserUpdateInput(f)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.NewToken), pixela.StringValue(input.NewToken), "NewToken")
 		assert.EqualValues(t, pixela.StringValue(p.expected.ThanksCode), pixela.StringValue(input.ThanksCode), "ThanksCode")
@@ -188,7 +193,6 @@ func TestUserUpdateInput(t *testing.T) {
 }
 
 func TestUserUpdate(t *testing.T) {
-	defer func() { pixelaClient.user = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -220,11 +224,12 @@ func TestUserUpdate(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.user = &pixelaUserMock{
+		f := newPixelaClientFactory()
+		f.user = &pixelaUserMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdUserUpdate()
+		c := NewCmdUserUpdate(f, &userOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -239,7 +244,6 @@ func TestUserUpdate(t *testing.T) {
 }
 
 func TestUserDelete(t *testing.T) {
-	defer func() { pixelaClient.user = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -271,11 +275,14 @@ func TestUserDelete(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.user = &pixelaUserMock{
+		f := newPixelaClientFactory()
+		f.user = &pixelaUserMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdUserDelete()
+		// go test の標準入力が端末でも確認を求めない
+		f.stdinIsTerminal = func() bool { return false }
+		c := NewCmdUserDelete(f, &userOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 		assert.NoError(t, c.Flags().Set("delete-me", "true"))
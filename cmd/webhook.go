@@ -2,20 +2,22 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	pixela "github.com/ebc-2in2crc/pixela4go"
 	"github.com/spf13/cobra"
 )
 
-var webhookOptions = &struct {
-	GraphID     string
-	Type        string
-	WebhookHash string
-}{}
+type webhookOptions struct {
+	GraphID         string
+	Type            string
+	WebhookHash     string
+	CreateIfMissing bool
+}
 
 // NewCmdWebhook creates a webhook command.
-func NewCmdWebhook() *cobra.Command {
+func NewCmdWebhook(f *pixelaClientFactory) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "webhook",
 		Short: "Webhook",
@@ -23,23 +25,25 @@ func NewCmdWebhook() *cobra.Command {
 		RunE:  showHelp,
 	}
 
-	cmd.AddCommand(NewCmdWebhookCreate())
-	cmd.AddCommand(NewCmdWebhookGetAll())
-	cmd.AddCommand(NewCmdWebhookInvoke())
-	cmd.AddCommand(NewCmdWebhookDelete())
+	cmd.AddCommand(NewCmdWebhookCreate(f, &webhookOptions{}))
+	cmd.AddCommand(NewCmdWebhookGetAll(f))
+	cmd.AddCommand(NewCmdWebhookInvoke(f, &webhookOptions{}))
+	cmd.AddCommand(NewCmdWebhookDelete(f, &webhookOptions{}))
+	cmd.AddCommand(NewCmdWebhookServe(f))
+	cmd.AddCommand(NewCmdWebhookAlias(f))
 
 	return cmd
 }
 
 // NewCmdWebhookCreate creates a create webhook command.
-func NewCmdWebhookCreate() *cobra.Command {
+func NewCmdWebhookCreate(f *pixelaClientFactory, o *webhookOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create Webhook",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createWebhookCreateInput()
-			result, err := pixelaClient.Webhook().Create(input)
+			input := createWebhookCreateInput(o)
+			result, err := f.Webhook().CreateWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("webhook create failed: %w", err)
 			}
@@ -56,27 +60,27 @@ func NewCmdWebhookCreate() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&webhookOptions.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
-	cmd.Flags().StringVar(&webhookOptions.Type, "type", "", "The behavior when this Webhook is invoked")
+	cmd.Flags().StringVar(&o.GraphID, "graph-id", "", "ID for identifying the pixelation graph")
+	cmd.Flags().StringVar(&o.Type, "type", "", "The behavior when this Webhook is invoked")
 
 	return cmd
 }
 
-func createWebhookCreateInput() *pixela.WebhookCreateInput {
+func createWebhookCreateInput(o *webhookOptions) *pixela.WebhookCreateInput {
 	return &pixela.WebhookCreateInput{
-		GraphID: getStringPtr(webhookOptions.GraphID),
-		Type:    getStringPtr(webhookOptions.Type),
+		GraphID: getStringPtr(o.GraphID),
+		Type:    getStringPtr(o.Type),
 	}
 }
 
 // NewCmdWebhookGetAll creates a get all webhook command.
-func NewCmdWebhookGetAll() *cobra.Command {
+func NewCmdWebhookGetAll(f *pixelaClientFactory) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get",
 		Short: "Get Webhook definitions",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			whs, err := pixelaClient.Webhook().GetAll()
+			whs, err := f.Webhook().GetAllWithContext(f.Context())
 			if err != nil {
 				return fmt.Errorf("webhook get all failed: %w", err)
 			}
@@ -108,14 +112,15 @@ type webhookDefinitions struct {
 }
 
 // NewCmdWebhookInvoke creates a invoke webhook command.
-func NewCmdWebhookInvoke() *cobra.Command {
+func NewCmdWebhookInvoke(f *pixelaClientFactory, o *webhookOptions) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "invoke",
-		Short: "Invoke Webhook",
-		Args:  cobra.NoArgs,
+		Use:     "invoke",
+		Short:   "Invoke Webhook",
+		Args:    cobra.NoArgs,
+		PreRunE: resolveWebhookHash(f, o),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createWebhookInvokeInput()
-			result, err := pixelaClient.Webhook().Invoke(input)
+			input := createWebhookInvokeInput(o)
+			result, err := f.Webhook().InvokeWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("webhook invoke failed: %w", err)
 			}
@@ -132,27 +137,28 @@ func NewCmdWebhookInvoke() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&webhookOptions.WebhookHash, "hash", "", "Webhook hash")
-	_ = cmd.MarkFlagRequired("hash")
+	addWebhookResolveFlags(cmd, o)
+	cmd.Flags().BoolVar(&o.CreateIfMissing, "create-if-missing", false, "Create the webhook of '--graph-id' and '--type' when it does not exist")
 
 	return cmd
 }
 
-func createWebhookInvokeInput() *pixela.WebhookInvokeInput {
+func createWebhookInvokeInput(o *webhookOptions) *pixela.WebhookInvokeInput {
 	return &pixela.WebhookInvokeInput{
-		WebhookHash: getStringPtr(webhookOptions.WebhookHash),
+		WebhookHash: getStringPtr(o.WebhookHash),
 	}
 }
 
 // NewCmdWebhookDelete creates a delete webhook command.
-func NewCmdWebhookDelete() *cobra.Command {
+func NewCmdWebhookDelete(f *pixelaClientFactory, o *webhookOptions) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "delete",
-		Short: "Delete Webhook",
-		Args:  cobra.NoArgs,
+		Use:     "delete",
+		Short:   "Delete Webhook",
+		Args:    cobra.NoArgs,
+		PreRunE: resolveWebhookHash(f, o),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input := createWebhookDeleteInput()
-			result, err := pixelaClient.Webhook().Delete(input)
+			input := createWebhookDeleteInput(o)
+			result, err := f.Webhook().DeleteWithContext(f.Context(), input)
 			if err != nil {
 				return fmt.Errorf("webhook delete failed: %w", err)
 			}
@@ -169,14 +175,64 @@ func NewCmdWebhookDelete() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&webhookOptions.WebhookHash, "hash", "", "Webhook hash")
-	_ = cmd.MarkFlagRequired("hash")
+	addWebhookResolveFlags(cmd, o)
 
 	return cmd
 }
 
-func createWebhookDeleteInput() *pixela.WebhookDeleteInput {
+func createWebhookDeleteInput(o *webhookOptions) *pixela.WebhookDeleteInput {
 	return &pixela.WebhookDeleteInput{
-		WebhookHash: getStringPtr(webhookOptions.WebhookHash),
+		WebhookHash: getStringPtr(o.WebhookHash),
+	}
+}
+
+func addWebhookResolveFlags(cmd *cobra.Command, o *webhookOptions) {
+	cmd.Flags().StringVar(&o.WebhookHash, "hash", "", "Webhook hash or alias")
+	cmd.Flags().StringVar(&o.GraphID, "graph-id", "", "ID of the pixelation graph to find the webhook instead of '--hash'")
+	cmd.Flags().StringVar(&o.Type, "type", "", "Type of the webhook to find with '--graph-id'")
+}
+
+// resolveWebhookHash returns the PreRunE which resolves the alias given by --hash,
+// or finds the webhook of --graph-id and --type.
+func resolveWebhookHash(f *pixelaClientFactory, o *webhookOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if o.WebhookHash != "" {
+			if o.GraphID != "" || o.Type != "" {
+				return errors.New("specify either '--hash', or '--graph-id' and '--type'")
+			}
+			aliases, err := loadWebhookAliases()
+			if err != nil {
+				return err
+			}
+			if hash, ok := aliases[o.WebhookHash]; ok {
+				o.WebhookHash = hash
+			}
+			return nil
+		}
+
+		if o.GraphID == "" || o.Type == "" {
+			return errors.New("specify '--hash', or '--graph-id' and '--type'")
+		}
+		hash, err := f.Client().FindWebhookHash(f.Context(), o.GraphID, o.Type)
+		if err != nil {
+			return reportError(cmd, err, "webhook resolve failed")
+		}
+		if hash == "" && o.CreateIfMissing {
+			result, err := f.Webhook().CreateWithContext(f.Context(), createWebhookCreateInput(o))
+			if err != nil {
+				return fmt.Errorf("webhook create failed: %w", err)
+			}
+			if !result.IsSuccess {
+				return reportError(cmd, &resultError{Result: &result.Result}, "webhook create failed")
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "webhook created: %s\n", result.WebhookHash)
+			hash = result.WebhookHash
+		}
+		if hash == "" {
+			return fmt.Errorf("webhook not found: graph-id=%s type=%s", o.GraphID, o.Type)
+		}
+
+		o.WebhookHash = hash
+		return nil
 	}
 }
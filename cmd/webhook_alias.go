@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdWebhookAlias creates an alias webhook command.
+func NewCmdWebhookAlias(f *pixelaClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Local aliases of the webhook hashes",
+		Long:  "Local aliases of the webhook hashes. An alias can be given to '--hash' of 'pa webhook invoke' and 'pa webhook delete'.",
+		Args:  cobra.NoArgs,
+		RunE:  showHelp,
+	}
+
+	cmd.AddCommand(NewCmdWebhookAliasSet(f))
+	cmd.AddCommand(NewCmdWebhookAliasList())
+	cmd.AddCommand(NewCmdWebhookAliasDelete(f))
+
+	return cmd
+}
+
+// NewCmdWebhookAliasSet creates a set alias command.
+func NewCmdWebhookAliasSet(f *pixelaClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set <name> <hash>",
+		Short: "Set an alias of the webhook hash",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			aliases, err := loadWebhookAliases()
+			if err != nil {
+				return err
+			}
+			aliases[args[0]] = args[1]
+			return saveWebhookAliases(f, aliases)
+		},
+	}
+
+	return cmd
+}
+
+// NewCmdWebhookAliasList creates a list alias command.
+func NewCmdWebhookAliasList() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the aliases of the webhook hashes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			aliases, err := loadWebhookAliases()
+			if err != nil {
+				return err
+			}
+
+			b, err := json.Marshal(&struct {
+				Aliases map[string]string `json:"aliases"`
+			}{Aliases: aliases})
+			if err != nil {
+				return fmt.Errorf("marshal webhook aliases failed: %w", err)
+			}
+			cmd.Printf("%s\n", string(b))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// NewCmdWebhookAliasDelete creates a delete alias command.
+func NewCmdWebhookAliasDelete(f *pixelaClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete an alias of the webhook hash",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			aliases, err := loadWebhookAliases()
+			if err != nil {
+				return err
+			}
+			if _, ok := aliases[args[0]]; !ok {
+				return fmt.Errorf("alias not found: %s", args[0])
+			}
+			delete(aliases, args[0])
+			return saveWebhookAliases(f, aliases)
+		},
+	}
+
+	return cmd
+}
+
+func loadWebhookAliases() (map[string]string, error) {
+	path, err := stateFile("webhook_aliases.json")
+	if err != nil {
+		return nil, fmt.Errorf("get webhook aliases failed: %w", err)
+	}
+	aliases := map[string]string{}
+	if err := readStateJSON(path, &aliases); err != nil {
+		return nil, fmt.Errorf("read webhook aliases failed: %w", err)
+	}
+	return aliases, nil
+}
+
+func saveWebhookAliases(f *pixelaClientFactory, aliases map[string]string) error {
+	path, err := stateFile("webhook_aliases.json")
+	if err != nil {
+		return fmt.Errorf("get webhook aliases failed: %w", err)
+	}
+	if err := f.writeState(path, aliases); err != nil {
+		return fmt.Errorf("write webhook aliases failed: %w", err)
+	}
+	return nil
+}
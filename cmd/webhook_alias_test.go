@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookAlias(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.webhooks = []pixela.WebhookDefinition{{WebhookHash: "coffee-increment", GraphID: "coffee", Type: "increment"}}
+
+	_, err := executeWebhook(t, f, "alias", "set", "coffee", "coffee-increment")
+	assert.NoError(t, err)
+	_, err = executeWebhook(t, f, "alias", "set", "tea", "tea-increment")
+	assert.NoError(t, err)
+
+	out, err := executeWebhook(t, f, "alias", "list")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"aliases":{"coffee":"coffee-increment","tea":"tea-increment"}}`+"\n", out)
+
+	_, err = executeWebhook(t, f, "invoke", "--hash=coffee")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"webhook:coffee-increment"}, fake.added)
+
+	_, err = executeWebhook(t, f, "alias", "delete", "tea")
+	assert.NoError(t, err)
+	_, err = executeWebhook(t, f, "alias", "delete", "tea")
+	assert.EqualError(t, err, "alias not found: tea")
+
+	out, err = executeWebhook(t, f, "alias", "list")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"aliases":{"coffee":"coffee-increment"}}`+"\n", out)
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebc-2in2crc/pa/pkg/pa"
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/spf13/cobra"
+)
+
+type webhookServeOptions struct {
+	Listen string
+	Secret string
+	NoAuth bool
+}
+
+// NewCmdWebhookServe creates a serve webhook command.
+func NewCmdWebhookServe(f *pixelaClientFactory) *cobra.Command {
+	o := &webhookServeOptions{}
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve local endpoints which log Pixels without the Pixela token",
+		Long: "Serve local endpoints which log Pixels without the Pixela token.\n\n" +
+			"  POST /graphs/{id}/increment\n" +
+			"  POST /graphs/{id}/decrement\n" +
+			"  POST /graphs/{id}/stopwatch\n" +
+			"  POST /graphs/{id}/add?quantity=N\n" +
+			"  POST /graphs/{id}/subtract?quantity=N\n\n" +
+			"increment, decrement and stopwatch invoke the webhook of the graph when it exists at the start, otherwise call the API.\n" +
+			"The requests must have the shared secret in the 'X-Pa-Secret' header or as 'Authorization: Bearer <secret>'.\n" +
+			"The secret can also be given by the PA_WEBHOOK_SECRET environment variable or 'webhook_secret' in the config file.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			secret := o.Secret
+			if !cmd.Flags().Changed("secret") {
+				secret = f.config.GetString("webhook_secret")
+			}
+			if secret == "" && !o.NoAuth {
+				return errors.New("specify the shared secret with '--secret', or '--no-auth' to accept any request")
+			}
+
+			handler, err := newWebhookServer(f, secret, cmd.ErrOrStderr())
+			if err != nil {
+				return reportError(cmd, err, "webhook serve failed")
+			}
+			srv := &http.Server{
+				Addr:              o.Listen,
+				Handler:           handler,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			// シグナルや --timeout でコマンドがキャンセルされたらサーバーを止める
+			ctx, stop := context.WithCancel(f.Context())
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				_ = srv.Shutdown(shutdownCtx)
+			}()
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "listening on %s\n", o.Listen)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("webhook serve failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Listen, "listen", ":8080", "The address to listen on")
+	cmd.Flags().StringVar(&o.Secret, "secret", "", "The shared secret which the requests must have")
+	cmd.Flags().BoolVar(&o.NoAuth, "no-auth", false, "Accept the requests without the shared secret")
+
+	return cmd
+}
+
+// newWebhookServer returns the handler of the local endpoints. An empty secret accepts any request.
+// The hashes of the webhooks are resolved once here, so the webhooks created later are not used until the restart.
+func newWebhookServer(f *pixelaClientFactory, secret string, logger io.Writer) (http.Handler, error) {
+	hashes, err := f.Client().WebhookHashes(f.Context())
+	if err != nil {
+		return nil, err
+	}
+	s := &webhookServer{client: f, secret: secret, logger: logger, hashes: hashes}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphs/{id}/{action}", s.handleGraph)
+	return s.authenticate(mux), nil
+}
+
+// webhookServer handles the requests concurrently. It only reads its fields after it is created.
+type webhookServer struct {
+	client *pixelaClientFactory
+	secret string
+	logger io.Writer
+	hashes map[pa.WebhookKey]string
+}
+
+// bearerPrefix is the scheme of the Authorization header which has the secret.
+const bearerPrefix = "Bearer "
+
+func (s *webhookServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.secret != "" {
+			given := r.Header.Get("X-Pa-Secret")
+			// Authorization は Bearer スキームのときだけシークレットとして扱う。スキームは大文字と小文字を区別しない
+			auth := r.Header.Get("Authorization")
+			if given == "" && len(auth) > len(bearerPrefix) && strings.EqualFold(auth[:len(bearerPrefix)], bearerPrefix) {
+				given = auth[len(bearerPrefix):]
+			}
+			if subtle.ConstantTimeCompare([]byte(given), []byte(s.secret)) != 1 {
+				s.log(r, http.StatusUnauthorized)
+				writeServeResult(w, &pixela.Result{Message: "Invalid secret.", StatusCode: http.StatusUnauthorized})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *webhookServer) handleGraph(w http.ResponseWriter, r *http.Request) {
+	result, err := s.call(r.Context(), r.PathValue("id"), r.PathValue("action"), r.URL.Query().Get("quantity"))
+	var re *resultError
+	switch {
+	case errors.As(err, &re):
+		result = re.Result
+	case err != nil:
+		result = &pixela.Result{Message: err.Error(), StatusCode: http.StatusBadRequest}
+	}
+	if result.StatusCode == 0 {
+		// Webhook の呼び出し結果などステータスコードが無いときは成否から決める
+		result.StatusCode = http.StatusOK
+		if !result.IsSuccess {
+			result.StatusCode = http.StatusBadGateway
+		}
+	}
+
+	s.log(r, result.StatusCode)
+	writeServeResult(w, result)
+}
+
+func (s *webhookServer) call(ctx context.Context, id, action, quantity string) (*pixela.Result, error) {
+	switch action {
+	case "add", "subtract":
+		if _, err := strconv.ParseFloat(quantity, 64); err != nil {
+			return nil, fmt.Errorf("invalid quantity: %q", quantity)
+		}
+		if action == "add" {
+			return s.client.Graph().AddWithContext(ctx, &pixela.GraphAddInput{ID: pixela.String(id), Quantity: pixela.String(quantity)})
+		}
+		return s.client.Graph().SubtractWithContext(ctx, &pixela.GraphSubtractInput{ID: pixela.String(id), Quantity: pixela.String(quantity)})
+	case "increment", "decrement", "stopwatch":
+	default:
+		return &pixela.Result{Message: "Unknown action: " + action, StatusCode: http.StatusNotFound}, nil
+	}
+
+	if hash := s.hashes[pa.WebhookKey{GraphID: id, Type: action}]; hash != "" {
+		return s.client.Webhook().InvokeWithContext(ctx, &pixela.WebhookInvokeInput{WebhookHash: pixela.String(hash)})
+	}
+
+	switch action {
+	case "increment":
+		return s.client.Pixel().IncrementWithContext(ctx, &pixela.PixelIncrementInput{GraphID: pixela.String(id)})
+	case "decrement":
+		return s.client.Pixel().DecrementWithContext(ctx, &pixela.PixelDecrementInput{GraphID: pixela.String(id)})
+	default:
+		return s.client.Graph().StopwatchWithContext(ctx, &pixela.GraphStopwatchInput{ID: pixela.String(id)})
+	}
+}
+
+func (s *webhookServer) log(r *http.Request, status int) {
+	fmt.Fprintf(s.logger, "%s %s %s %s %d\n", time.Now().Format(time.RFC3339), r.RemoteAddr, r.Method, r.URL.Path, status)
+}
+
+func writeServeResult(w http.ResponseWriter, result *pixela.Result) {
+	b, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(result.StatusCode)
+	_, _ = w.Write(append(b, '\n'))
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookServe(t *testing.T) {
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee"})
+	fake.webhooks = []pixela.WebhookDefinition{{WebhookHash: "coffee-hash", GraphID: "coffee", Type: "increment"}}
+
+	params := []struct {
+		method   string
+		target   string
+		secret   string
+		status   int
+		expected []string
+	}{
+		{method: http.MethodPost, target: "/graphs/coffee/increment", secret: "s3cret", status: http.StatusOK, expected: []string{"webhook:coffee-hash"}},
+		{method: http.MethodPost, target: "/graphs/coffee/decrement", secret: "s3cret", status: http.StatusOK},
+		{method: http.MethodPost, target: "/graphs/water/increment", secret: "s3cret", status: http.StatusOK, expected: []string{"water:increment"}},
+		{method: http.MethodPost, target: "/graphs/coffee/add?quantity=2", secret: "s3cret", status: http.StatusOK, expected: []string{"coffee:2"}},
+		{method: http.MethodPost, target: "/graphs/coffee/add?quantity=two", secret: "s3cret", status: http.StatusBadRequest},
+		{method: http.MethodPost, target: "/graphs/coffee/reset", secret: "s3cret", status: http.StatusNotFound},
+		{method: http.MethodGet, target: "/graphs/coffee/increment", secret: "s3cret", status: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, target: "/graphs/coffee/increment", secret: "wrong", status: http.StatusUnauthorized},
+		{method: http.MethodPost, target: "/graphs/coffee/increment", status: http.StatusUnauthorized},
+	}
+
+	handler, err := newWebhookServer(f, "s3cret", io.Discard)
+	assert.NoError(t, err)
+	// webhook のハッシュは起動したときに解決するので、後から作った webhook は使わない
+	fake.webhooks = append(fake.webhooks, pixela.WebhookDefinition{WebhookHash: "water-hash", GraphID: "water", Type: "increment"})
+	for _, p := range params {
+		fake.added = nil
+		req := httptest.NewRequest(p.method, p.target, nil)
+		if p.secret != "" {
+			req.Header.Set("X-Pa-Secret", p.secret)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, p.status, rec.Code, p.target)
+		assert.Equal(t, p.expected, fake.added, p.target)
+	}
+}
+
+func TestWebhookServeBearer(t *testing.T) {
+	fake := newPixelaFake()
+	f := fake.factory()
+
+	req := httptest.NewRequest(http.MethodPost, "/graphs/coffee/subtract?quantity=1.5", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	handler, err := newWebhookServer(f, "s3cret", io.Discard)
+	assert.NoError(t, err)
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"message":"Success.","isSuccess":true,"isRejected":false,"statusCode":200}`+"\n", rec.Body.String())
+
+	// Bearer スキームの無い Authorization は受け付けない
+	for _, auth := range []string{"s3cret", "Basic s3cret", "Bearer", "Bearer wrong"} {
+		req = httptest.NewRequest(http.MethodPost, "/graphs/coffee/subtract?quantity=1.5", nil)
+		req.Header.Set("Authorization", auth)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, auth)
+	}
+}
+
+func TestWebhookServeRequiresSecret(t *testing.T) {
+	setupConfigHome(t)
+	t.Setenv("PA_WEBHOOK_SECRET", "")
+	cmd := newCmdRoot(newPixelaClientFactory())
+	cmd.SetOut(io.Discard)
+	cmd.SetArgs([]string{"webhook", "serve"})
+
+	err := cmd.Execute()
+
+	assert.EqualError(t, err, "specify the shared secret with '--secret', or '--no-auth' to accept any request")
+}
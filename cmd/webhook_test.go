@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -19,7 +20,7 @@ type pixelaWebhookMock struct {
 	Webhooks    []pixela.WebhookDefinition
 }
 
-func (p *pixelaWebhookMock) Create(input *pixela.WebhookCreateInput) (*pixela.WebhookCreateResult, error) {
+func (p *pixelaWebhookMock) CreateWithContext(ctx context.Context, input *pixela.WebhookCreateInput) (*pixela.WebhookCreateResult, error) {
 	result := &pixela.WebhookCreateResult{
 		WebhookHash: p.WebhookHash,
 		Result:      p.result,
@@ -27,7 +28,7 @@ func (p *pixelaWebhookMock) Create(input *pixela.WebhookCreateInput) (*pixela.We
 	return result, p.err
 }
 
-func (p *pixelaWebhookMock) GetAll() (*pixela.WebhookDefinitions, error) {
+func (p *pixelaWebhookMock) GetAllWithContext(ctx context.Context) (*pixela.WebhookDefinitions, error) {
 	result := &pixela.WebhookDefinitions{
 		Webhooks: p.Webhooks,
 		Result:   p.result,
@@ -35,11 +36,11 @@ func (p *pixelaWebhookMock) GetAll() (*pixela.WebhookDefinitions, error) {
 	return result, p.err
 }
 
-func (p *pixelaWebhookMock) Invoke(input *pixela.WebhookInvokeInput) (*pixela.Result, error) {
+func (p *pixelaWebhookMock) InvokeWithContext(ctx context.Context, input *pixela.WebhookInvokeInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
-func (p *pixelaWebhookMock) Delete(input *pixela.WebhookDeleteInput) (*pixela.Result, error) {
+func (p *pixelaWebhookMock) DeleteWithContext(ctx context.Context, input *pixela.WebhookDeleteInput) (*pixela.Result, error) {
 	return &p.result, p.err
 }
 
@@ -62,13 +63,11 @@ func TestWebhookCreateInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &webhookOptions{}
+		cmd := NewCmdWebhookCreate(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createWebhookCreateInput()
+		input := createWebhookCreateInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.GraphID), pixela.StringValue(input.GraphID), "GraphID")
 		assert.EqualValues(t, pixela.StringValue(p.expected.Type), pixela.StringValue(input.Type), "Type")
@@ -76,7 +75,6 @@ func TestWebhookCreateInput(t *testing.T) {
 }
 
 func TestWebhookCreate(t *testing.T) {
-	defer func() { pixelaClient.webhook = nil }()
 	params := []struct {
 		Result      pixela.Result
 		occur       error
@@ -110,12 +108,13 @@ func TestWebhookCreate(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.webhook = &pixelaWebhookMock{
+		f := newPixelaClientFactory()
+		f.webhook = &pixelaWebhookMock{
 			result:      v.Result,
 			err:         v.occur,
 			WebhookHash: v.webhookHash,
 		}
-		c := NewCmdWebhookCreate()
+		c := NewCmdWebhookCreate(f, &webhookOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -130,7 +129,6 @@ func TestWebhookCreate(t *testing.T) {
 }
 
 func TestWebhookGetAll(t *testing.T) {
-	defer func() { pixelaClient.webhook = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -170,12 +168,13 @@ func TestWebhookGetAll(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.webhook = &pixelaWebhookMock{
+		f := newPixelaClientFactory()
+		f.webhook = &pixelaWebhookMock{
 			result:   v.Result,
 			err:      v.occur,
 			Webhooks: v.webhooks,
 		}
-		c := NewCmdWebhookGetAll()
+		c := NewCmdWebhookGetAll(f)
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -207,20 +206,17 @@ func TestWebhookInvokeInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &webhookOptions{}
+		cmd := NewCmdWebhookInvoke(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createWebhookInvokeInput()
+		input := createWebhookInvokeInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.WebhookHash), pixela.StringValue(input.WebhookHash), "WebhookHash")
 	}
 }
 
 func TestWebhookInvoke(t *testing.T) {
-	defer func() { pixelaClient.webhook = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -252,11 +248,12 @@ func TestWebhookInvoke(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.webhook = &pixelaWebhookMock{
+		f := newPixelaClientFactory()
+		f.webhook = &pixelaWebhookMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdWebhookInvoke()
+		c := NewCmdWebhookInvoke(f, &webhookOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -288,20 +285,17 @@ func TestWebhookDeleteInput(t *testing.T) {
 	}
 
 	for _, p := range params {
-		cmd := NewCmdRoot()
-		cmd.SetOut(io.Discard)
-		args := strings.Split(p.commandline, " ")
-		cmd.SetArgs(args)
-		_ = cmd.Execute()
+		o := &webhookOptions{}
+		cmd := NewCmdWebhookDelete(newPixelaClientFactory(), o)
+		_ = cmd.ParseFlags(strings.Split(p.commandline, " "))
 
-		input := createWebhookDeleteInput()
+		input := createWebhookDeleteInput(o)
 
 		assert.EqualValues(t, pixela.StringValue(p.expected.WebhookHash), pixela.StringValue(input.WebhookHash), "WebhookHash")
 	}
 }
 
 func TestWebhookDelete(t *testing.T) {
-	defer func() { pixelaClient.webhook = nil }()
 	params := []struct {
 		Result   pixela.Result
 		occur    error
@@ -333,11 +327,12 @@ func TestWebhookDelete(t *testing.T) {
 	}
 
 	for _, v := range params {
-		pixelaClient.webhook = &pixelaWebhookMock{
+		f := newPixelaClientFactory()
+		f.webhook = &pixelaWebhookMock{
 			result: v.Result,
 			err:    v.occur,
 		}
-		c := NewCmdWebhookDelete()
+		c := NewCmdWebhookDelete(f, &webhookOptions{})
 		buffer := bytes.NewBuffer([]byte{})
 		c.SetOut(buffer)
 
@@ -350,3 +345,54 @@ func TestWebhookDelete(t *testing.T) {
 		}
 	}
 }
+
+func executeWebhook(t *testing.T, f *pixelaClientFactory, args ...string) (string, error) {
+	t.Helper()
+	cmd := newCmdRoot(f)
+	buffer := bytes.NewBuffer([]byte{})
+	cmd.SetOut(buffer)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(append([]string{"webhook"}, args...))
+	err := cmd.Execute()
+	return buffer.String(), err
+}
+
+func TestWebhookResolveHash(t *testing.T) {
+	setupConfigHome(t)
+	fake := newPixelaFake()
+	f := fake.factory()
+	fake.addGraph(pixela.GraphDefinition{ID: "coffee"})
+	fake.webhooks = []pixela.WebhookDefinition{
+		{WebhookHash: "coffee-increment", GraphID: "coffee", Type: "increment"},
+		{WebhookHash: "coffee-decrement", GraphID: "coffee", Type: "decrement"},
+	}
+
+	_, err := executeWebhook(t, f, "invoke", "--graph-id=coffee", "--type=decrement")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"webhook:coffee-decrement"}, fake.added)
+
+	_, err = executeWebhook(t, f, "invoke", "--graph-id=coffee", "--type=add")
+	assert.EqualError(t, err, "webhook not found: graph-id=coffee type=add")
+
+	_, err = executeWebhook(t, f, "invoke", "--graph-id=coffee", "--type=stopwatch", "--create-if-missing")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"webhook:coffee-decrement", "webhook:hash-coffee-stopwatch"}, fake.added)
+
+	_, err = executeWebhook(t, f, "delete", "--graph-id=coffee", "--type=increment")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"coffee-decrement", "hash-coffee-stopwatch"}, webhookHashes(fake.webhooks))
+
+	_, err = executeWebhook(t, f, "delete", "--hash=coffee-decrement", "--graph-id=coffee")
+	assert.EqualError(t, err, "specify either '--hash', or '--graph-id' and '--type'")
+
+	_, err = executeWebhook(t, f, "delete", "--graph-id=coffee")
+	assert.EqualError(t, err, "specify '--hash', or '--graph-id' and '--type'")
+}
+
+func webhookHashes(whs []pixela.WebhookDefinition) []string {
+	hashes := []string{}
+	for _, wh := range whs {
+		hashes = append(hashes, wh.WebhookHash)
+	}
+	return hashes
+}
@@ -1,26 +1,74 @@
 package e2e
 
 import (
-	"fmt"
+	"net/http"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// fixturePath is the Pixela traffic recorded by the E2E test, which is replayed without the network by default.
+const fixturePath = "testdata/pixela.json"
+
+// The placeholders of the secrets in the fixture, which are the user name and the tokens in the replay.
+const (
+	replayUsername    = "pa-e2e-user"
+	replayFirstToken  = "redacted-first-token"
+	replaySecondToken = "redacted-second-token"
+)
+
+// TestE2E runs the scenario against the recorded traffic in testdata/pixela.json.
+// It runs against Pixela with the environment variables below, and records the traffic with PA_E2E_RECORD=ON.
+//
+//   - PA_E2E_TEST_RUN=ON
+//   - PA_USERNAME=<pixela-username-for-testing>
+//   - PA_FIRST_TOKEN=<pixela-token-for-testing>
+//   - PA_SECOND_TOKEN=<pixela-token-for-testing>
 func TestE2E(t *testing.T) {
-	if os.Getenv("PA_E2E_TEST_RUN") != "ON" {
-		msg := `E2E test skip.
-If you run E2E test, Set below environment variables.
-
-- PA_E2E_TEST_RUN=ON
-- PA_USERNAME=<pixela-username-for-testing>
-- PA_FIRST_TOKEN=<pixela-token-for-testing>
-- PA_SECOND_TOKEN=<pixela-token-for-testing>`
-		fmt.Println(msg)
-		return
+	transport := http.DefaultTransport
+	defer func() { http.DefaultTransport = transport }()
+
+	var recorder *recordingTransport
+	var replayer *replayTransport
+	if os.Getenv("PA_E2E_TEST_RUN") == "ON" {
+		if os.Getenv("PA_E2E_RECORD") == "ON" {
+			// ユーザー名はトークンの一部を置き換えないように後に置き換える
+			recorder = newRecordingTransport(transport,
+				os.Getenv("PA_FIRST_TOKEN"), replayFirstToken,
+				os.Getenv("PA_SECOND_TOKEN"), replaySecondToken,
+				os.Getenv("PA_USERNAME"), replayUsername,
+			)
+			http.DefaultTransport = recorder
+		}
+	} else {
+		r, err := newReplayTransport(fixturePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replayer = r
+		http.DefaultTransport = r
+		t.Setenv("PA_USERNAME", replayUsername)
+		t.Setenv("PA_FIRST_TOKEN", replayFirstToken)
+		t.Setenv("PA_SECOND_TOKEN", replaySecondToken)
+		t.Setenv("PA_TOKEN", "")
+		// 開発者の設定ファイルや状態を使わない
+		t.Setenv("HOME", t.TempDir())
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		t.Setenv("XDG_STATE_HOME", t.TempDir())
 	}
 
+	testE2EScenario(t)
+
+	if recorder != nil {
+		assert.NoError(t, recorder.save(fixturePath))
+	}
+	if replayer != nil {
+		assert.Equal(t, 0, replayer.remaining(), "the recorded requests which were not sent")
+	}
+}
+
+func testE2EScenario(t *testing.T) {
 	assert.NoError(t, os.Setenv("PA_RETRY", "20"))
 
 	testE2EUserCreate(t)
@@ -47,6 +47,9 @@ func testE2EUserUpdate(t *testing.T) {
 func testE2EUserDelete(t *testing.T) {
 	cmd := cmd.NewCmdRoot()
 	cmd.SetOut(io.Discard)
+	// 端末から実行したときは確認のためにユーザー名を入力する
+	cmd.SetIn(strings.NewReader(os.Getenv("PA_USERNAME") + "\n"))
+	cmd.SetErr(io.Discard)
 	commandline := "user delete --delete-me"
 	args := strings.Split(commandline, " ")
 	cmd.SetArgs(args)
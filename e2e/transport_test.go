@@ -0,0 +1,292 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixture is the golden file of the Pixela traffic recorded by the E2E test.
+type fixture struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+// interaction is a pair of a request and its response.
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// Token は X-USER-TOKEN ヘッダーで、記録するときはプレースホルダーに置き換える
+	Token string `json:"token,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	StatusCode  int    `json:"statusCode"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body"`
+}
+
+// newRecordedRequest reads the request, and restores its body so that it can be sent.
+func newRecordedRequest(req *http.Request) (recordedRequest, error) {
+	r := recordedRequest{Method: req.Method, URL: req.URL.String(), Token: req.Header.Get("X-USER-TOKEN")}
+	if req.Body == nil {
+		return r, nil
+	}
+	b, err := io.ReadAll(req.Body)
+	if err != nil {
+		return r, fmt.Errorf("read request body failed: %w", err)
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	r.Body = string(b)
+	return r, nil
+}
+
+// redact replaces the secrets in the request with the placeholders.
+func (r recordedRequest) redact(replacer *strings.Replacer) recordedRequest {
+	r.URL = replacer.Replace(r.URL)
+	r.Token = replacer.Replace(r.Token)
+	r.Body = replacer.Replace(r.Body)
+	return r
+}
+
+func (r recordedRequest) String() string {
+	return r.Method + " " + r.URL + " " + r.Body
+}
+
+// response returns the recorded response as the response to req.
+func (r recordedResponse) response(req *http.Request) *http.Response {
+	header := http.Header{}
+	if r.ContentType != "" {
+		header.Set("Content-Type", r.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// isRejected reports whether the response is a rejected request which pixela4go retries.
+func (r recordedResponse) isRejected() bool {
+	if r.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	var result struct {
+		IsRejected bool `json:"isRejected"`
+	}
+	return json.Unmarshal([]byte(r.Body), &result) == nil && result.IsRejected
+}
+
+// recordingTransport sends the requests with base, and records the requests and the responses
+// with the secrets replaced by the placeholders.
+type recordingTransport struct {
+	base     http.RoundTripper
+	replacer *strings.Replacer
+
+	mu           sync.Mutex
+	interactions []interaction
+}
+
+// newRecordingTransport returns a recordingTransport which replaces the secrets with the placeholders,
+// given as pairs of a secret and its placeholder like strings.NewReplacer.
+func newRecordingTransport(base http.RoundTripper, secrets ...string) *recordingTransport {
+	var pairs []string
+	for i := 0; i+1 < len(secrets); i += 2 {
+		// 空の秘密情報を置き換えるとすべての文字の間にプレースホルダーが入る
+		if secrets[i] != "" {
+			pairs = append(pairs, secrets[i], secrets[i+1])
+		}
+	}
+	return &recordingTransport{base: base, replacer: strings.NewReplacer(pairs...)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := newRecordedRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read response body failed: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+
+	r := recordedResponse{StatusCode: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: t.replacer.Replace(string(b))}
+	// 拒否されたリクエストは同じリクエストで再試行されるので、再生するときに待たないように記録しない
+	if r.isRejected() {
+		return resp, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, interaction{Request: recorded.redact(t.replacer), Response: r})
+	return resp, nil
+}
+
+// save writes the recorded interactions to the fixture file.
+func (t *recordingTransport) save(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, err := json.MarshalIndent(&fixture{Interactions: t.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal fixture failed: %w", err)
+	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// replayTransport answers the requests with the recorded responses without the network.
+// The requests must be sent in the recorded order.
+type replayTransport struct {
+	mu           sync.Mutex
+	interactions []interaction
+	next         int
+}
+
+// newReplayTransport reads the fixture file.
+func newReplayTransport(path string) (*replayTransport, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fixture failed: %w", err)
+	}
+	var f fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("unmarshal fixture failed: %w", err)
+	}
+	return &replayTransport{interactions: f.Interactions}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	got, err := newRecordedRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.next >= len(t.interactions) {
+		return nil, fmt.Errorf("unexpected request: %s, no more recorded requests", got)
+	}
+	want := t.interactions[t.next]
+	if got != want.Request {
+		return nil, fmt.Errorf("unexpected request #%d: %s, want %s", t.next+1, got, want.Request)
+	}
+	t.next++
+	return want.Response.response(req), nil
+}
+
+// remaining returns the number of the recorded requests which were not sent.
+func (t *replayTransport) remaining() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.interactions) - t.next
+}
+
+func TestRecordingTransport(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, `{"message":"Please retry this request.","isSuccess":false,"isRejected":true}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"message":"Success. Let's visit https://pixe.la/@alice","isSuccess":true}`)
+	}))
+	defer server.Close()
+	recorder := newRecordingTransport(http.DefaultTransport, "secret-token", "redacted-token", "alice", "pa-e2e-user")
+	client := &http.Client{Transport: recorder}
+
+	for _, want := range []string{`"isRejected":true`, "@alice"} {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/users/alice", strings.NewReader(`{"token":"secret-token"}`))
+		assert.NoError(t, err)
+		req.Header.Set("X-USER-TOKEN", "secret-token")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		// 記録しても呼び出し元は元のレスポンスを読める
+		b, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Contains(t, string(b), want)
+	}
+
+	// 拒否されたリクエストは記録しない
+	assert.Equal(t, []interaction{{
+		Request: recordedRequest{
+			Method: http.MethodPost,
+			URL:    server.URL + "/v1/users/pa-e2e-user",
+			Token:  "redacted-token",
+			Body:   `{"token":"redacted-token"}`,
+		},
+		Response: recordedResponse{
+			StatusCode:  http.StatusOK,
+			ContentType: "application/json",
+			Body:        `{"message":"Success. Let's visit https://pixe.la/@pa-e2e-user","isSuccess":true}`,
+		},
+	}}, recorder.interactions)
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	assert.NoError(t, recorder.save(path))
+	replayer, err := newReplayTransport(path)
+	assert.NoError(t, err)
+	assert.Equal(t, recorder.interactions, replayer.interactions)
+}
+
+func TestReplayTransport(t *testing.T) {
+	replayer := &replayTransport{interactions: []interaction{
+		{
+			Request:  recordedRequest{Method: http.MethodGet, URL: "https://pixe.la/v1/users/pa-e2e-user/graphs", Token: "redacted-token"},
+			Response: recordedResponse{StatusCode: http.StatusOK, Body: `{"graphs":[]}`},
+		},
+		{
+			Request:  recordedRequest{Method: http.MethodDelete, URL: "https://pixe.la/v1/users/pa-e2e-user", Token: "redacted-token"},
+			Response: recordedResponse{StatusCode: http.StatusOK, Body: `{"message":"Success.","isSuccess":true}`},
+		},
+	}}
+	client := &http.Client{Transport: replayer}
+	get := func(token string) (*http.Response, error) {
+		req, _ := http.NewRequest(http.MethodGet, "https://pixe.la/v1/users/pa-e2e-user/graphs", nil)
+		req.Header.Set("X-USER-TOKEN", token)
+		return client.Do(req)
+	}
+
+	_, err := get("another-token")
+	assert.Error(t, err)
+	assert.Equal(t, 2, replayer.remaining())
+
+	resp, err := get("redacted-token")
+	assert.NoError(t, err)
+	b, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, `{"graphs":[]}`, string(b))
+	assert.Equal(t, 1, replayer.remaining())
+
+	_, err = get("redacted-token")
+	assert.Contains(t, err.Error(), "unexpected request #2: GET https://pixe.la/v1/users/pa-e2e-user/graphs , want DELETE https://pixe.la/v1/users/pa-e2e-user ")
+}
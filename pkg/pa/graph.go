@@ -0,0 +1,281 @@
+package pa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+)
+
+// GraphDefinition is the definition of a graph without the result of the API call.
+type GraphDefinition struct {
+	ID                  string   `json:"id"`
+	Name                string   `json:"name"`
+	Unit                string   `json:"unit"`
+	Type                string   `json:"type"`
+	Color               string   `json:"color"`
+	TimeZone            string   `json:"timezone"`
+	PurgeCacheURLs      []string `json:"purgeCacheURLs"`
+	SelfSufficient      string   `json:"selfSufficient"`
+	IsSecret            bool     `json:"isSecret"`
+	PublishOptionalData bool     `json:"publishOptionalData"`
+}
+
+// NewGraphDefinition returns the definition of the graph got by the API.
+func NewGraphDefinition(g *pixela.GraphDefinition) GraphDefinition {
+	return GraphDefinition{
+		ID:                  g.ID,
+		Name:                g.Name,
+		Unit:                g.Unit,
+		Type:                g.Type,
+		Color:               g.Color,
+		TimeZone:            g.TimeZone,
+		PurgeCacheURLs:      g.PurgeCacheURLs,
+		SelfSufficient:      g.SelfSufficient,
+		IsSecret:            g.IsSecret,
+		PublishOptionalData: g.PublishOptionalData,
+	}
+}
+
+// GraphExport is a graph definition and its Pixels.
+type GraphExport struct {
+	Definition GraphDefinition        `json:"definition"`
+	Pixels     []pixela.PixelWithBody `json:"pixels"`
+}
+
+// ExportGraph gets the graph definition and its Pixels between from and to.
+func (c *Client) ExportGraph(ctx context.Context, id, from, to string) (*GraphExport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	def, err := c.Graph.GetWithContext(ctx, &pixela.GraphGetInput{ID: pixela.String(id)})
+	if err != nil {
+		return nil, fmt.Errorf("graph get failed: %w", err)
+	}
+	if !def.IsSuccess {
+		return nil, &ResultError{Result: &def.Result}
+	}
+
+	pixels, err := c.FetchPixels(ctx, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GraphExport{Definition: NewGraphDefinition(def), Pixels: pixels}, nil
+}
+
+// CloneOptions is the options of Client.CloneGraph.
+type CloneOptions struct {
+	// WithPixels copies the Pixels between From and To as well
+	WithPixels bool
+	From       string
+	To         string
+	// StartOnMonday starts the weeks of the new graph on Monday.
+	// The graph definition of pixela4go lacks startOnMonday, so it can't be copied from the source graph.
+	StartOnMonday bool
+	// Progress is called after each Pixel is copied when it is not nil
+	Progress func(i, n int, date string, result *pixela.Result)
+}
+
+// CloneResult is the result of Client.CloneGraph.
+type CloneResult struct {
+	ID     string   `json:"id"`
+	Pixels int      `json:"pixels"`
+	Copied int      `json:"copied"`
+	Failed []string `json:"failed"`
+}
+
+// CloneGraph creates the graph newID on dst with the definition of the graph id.
+// The Pixels which could not be copied are reported in CloneResult.Failed.
+// When ctx is done or a request fails while copying the Pixels, it returns the result so far with the error.
+func (c *Client) CloneGraph(ctx context.Context, dst *Client, id, newID string, opts CloneOptions) (*CloneResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	def, err := c.Graph.GetWithContext(ctx, &pixela.GraphGetInput{ID: pixela.String(id)})
+	if err != nil {
+		return nil, fmt.Errorf("graph get failed: %w", err)
+	}
+	if !def.IsSuccess {
+		return nil, &ResultError{Result: &def.Result}
+	}
+
+	result, err := dst.Graph.CreateWithContext(ctx, createGraphCloneInput(def, newID, opts.StartOnMonday))
+	if err != nil {
+		return nil, fmt.Errorf("graph create failed: %w", err)
+	}
+	if !result.IsSuccess {
+		return nil, &ResultError{Result: result}
+	}
+
+	// purgeCacheURLs はグラフの作成時には指定できないので作成後に更新する
+	if len(def.PurgeCacheURLs) > 0 {
+		result, err := dst.Graph.UpdateWithContext(ctx, &pixela.GraphUpdateInput{
+			ID:             pixela.String(newID),
+			PurgeCacheURLs: def.PurgeCacheURLs,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("graph update failed: %w", err)
+		}
+		if !result.IsSuccess {
+			return nil, &ResultError{Result: result}
+		}
+	}
+
+	if !opts.WithPixels {
+		return &CloneResult{ID: newID, Failed: []string{}}, nil
+	}
+	return c.copyPixels(ctx, dst, id, newID, opts)
+}
+
+func createGraphCloneInput(def *pixela.GraphDefinition, newID string, startOnMonday bool) *pixela.GraphCreateInput {
+	return &pixela.GraphCreateInput{
+		ID:                  pixela.String(newID),
+		Name:                pixela.String(def.Name),
+		Unit:                pixela.String(def.Unit),
+		Type:                pixela.String(def.Type),
+		Color:               pixela.String(def.Color),
+		TimeZone:            stringPtr(def.TimeZone),
+		SelfSufficient:      stringPtr(def.SelfSufficient),
+		IsSecret:            boolPtr(def.IsSecret),
+		PublishOptionalData: boolPtr(def.PublishOptionalData),
+		StartOnMonday:       boolPtr(startOnMonday),
+	}
+}
+
+func (c *Client) copyPixels(ctx context.Context, dst *Client, id, newID string, opts CloneOptions) (*CloneResult, error) {
+	pixels, err := c.FetchPixels(ctx, id, opts.From, opts.To)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &CloneResult{ID: newID, Pixels: len(pixels), Failed: []string{}}
+	results := make([]*pixela.Result, len(pixels))
+	create := func(ctx context.Context, i int) error {
+		p := pixels[i]
+		result, err := dst.Pixel.CreateWithContext(ctx, &pixela.PixelCreateInput{
+			GraphID:      pixela.String(newID),
+			Date:         pixela.String(p.Date),
+			Quantity:     pixela.String(p.Quantity),
+			OptionalData: stringPtr(p.OptionalData),
+		})
+		results[i] = result
+		return err
+	}
+	// 結果は並行に作成しても日付の順に報告する
+	report := func(i int, err error) {
+		if err != nil {
+			// キャンセルで中断したリクエストは失敗として数えない
+			if ctx.Err() == nil {
+				r.Failed = append(r.Failed, pixels[i].Date)
+			}
+			return
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(pixels), pixels[i].Date, results[i])
+		}
+		if !results[i].IsSuccess {
+			r.Failed = append(r.Failed, pixels[i].Date)
+			return
+		}
+		r.Copied++
+	}
+
+	if err := c.Pool.Do(ctx, len(pixels), create, report); err != nil {
+		// キャンセルされたときはそれまでの結果を返す
+		if ctx.Err() != nil {
+			return r, ctx.Err()
+		}
+		return r, fmt.Errorf("pixel create failed: %w", err)
+	}
+	return r, nil
+}
+
+// CombinePixels combines the quantities of the source graphs per date with combine.
+// The combined quantities are formatted without the trailing zeros.
+func (c *Client) CombinePixels(ctx context.Context, sources []string, combine func(a, b float64) float64, from, to string) (map[string]string, error) {
+	fetched := make([][]pixela.PixelWithBody, len(sources))
+	fetch := func(ctx context.Context, i int) error {
+		var err error
+		fetched[i], err = c.FetchPixels(ctx, sources[i], from, to)
+		return err
+	}
+	if err := c.Pool.Do(ctx, len(sources), fetch, func(int, error) {}); err != nil {
+		return nil, err
+	}
+
+	values := map[string]float64{}
+	for i, id := range sources {
+		for _, p := range fetched[i] {
+			q, err := strconv.ParseFloat(p.Quantity, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quantity %s of %s at %s: %w", p.Quantity, id, p.Date, err)
+			}
+			if v, ok := values[p.Date]; ok {
+				values[p.Date] = combine(v, q)
+				continue
+			}
+			values[p.Date] = q
+		}
+	}
+
+	result := map[string]string{}
+	for d, v := range values {
+		result[d] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return result, nil
+}
+
+// apiBaseURL is the base URL of the requests which pixela4go can't send. It is replaced in tests.
+var apiBaseURL = pixela.APIBaseURLForV1
+
+// GraphClearPurgeCacheURLsInput is the input of ClearPurgeCacheURLsWithContext.
+type GraphClearPurgeCacheURLsInput struct {
+	// ID is the graph ID
+	ID *string
+}
+
+// pixelaGraph is the graph API of pixela4go with the requests which pixela4go can't send.
+type pixelaGraph struct {
+	*pixela.Graph
+	username string
+	token    string
+}
+
+// ClearPurgeCacheURLsWithContext removes all the purgeCacheURLs of the graph, which pixela4go can't do
+// because it omits the empty PurgeCacheURLs from the request of the graph update.
+// It returns pixela.ErrAPICallRejected for the rejected request like pixela4go, so that a RequestPolicy retries it.
+func (g *pixelaGraph) ClearPurgeCacheURLsWithContext(ctx context.Context, input *GraphClearPurgeCacheURLsInput) (*pixela.Result, error) {
+	url := apiBaseURL + "/users/" + g.username + "/graphs/" + pixela.StringValue(input.ID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, strings.NewReader(`{"purgeCacheURLs":[]}`))
+	if err != nil {
+		return nil, fmt.Errorf("create request failed: %w", err)
+	}
+	req.Header.Set("X-USER-TOKEN", g.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	// pixela4go と同じく http.Client{} で送る
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body failed: %w", err)
+	}
+	var result pixela.Result
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal response failed: %w", err)
+	}
+	if resp.StatusCode == http.StatusServiceUnavailable && result.IsRejected {
+		return nil, pixela.ErrAPICallRejected
+	}
+	result.StatusCode = resp.StatusCode
+	return &result, nil
+}
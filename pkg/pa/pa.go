@@ -0,0 +1,116 @@
+// Package pa provides the Pixela client which the pa command is built on.
+//
+// The Client bundles the Pixela APIs of an account and adds the operations over them,
+// such as fetching all Pixels of a graph, exporting, cloning and combining graphs.
+// The bulk operations run their requests on the Pool of the Client.
+// A RequestPolicy limits the rate of the requests of the APIs and retries the rejected requests,
+// so pixela.RetryCount is left 0 and each client has its own policy.
+// The APIs are the WithContext methods of pixela4go, so the context cancels the in-flight requests and the retries.
+package pa
+
+import (
+	"context"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+)
+
+// User is the user API of Pixela.
+type User interface {
+	CreateWithContext(ctx context.Context, input *pixela.UserCreateInput) (*pixela.Result, error)
+	UpdateWithContext(ctx context.Context, input *pixela.UserUpdateInput) (*pixela.Result, error)
+	DeleteWithContext(ctx context.Context) (*pixela.Result, error)
+}
+
+// UserProfile is the user profile API of Pixela.
+type UserProfile interface {
+	UpdateWithContext(ctx context.Context, input *pixela.UserProfileUpdateInput) (*pixela.Result, error)
+	URL() string
+}
+
+// Graph is the graph API of Pixela.
+type Graph interface {
+	CreateWithContext(ctx context.Context, input *pixela.GraphCreateInput) (*pixela.Result, error)
+	GetAllWithContext(ctx context.Context) (*pixela.GraphDefinitions, error)
+	GetWithContext(ctx context.Context, input *pixela.GraphGetInput) (*pixela.GraphDefinition, error)
+	GetSVGWithContext(ctx context.Context, input *pixela.GraphGetSVGInput) (string, error)
+	URL(input *pixela.GraphURLInput) string
+	StatsWithContext(ctx context.Context, input *pixela.GraphStatsInput) (*pixela.Stats, error)
+	UpdateWithContext(ctx context.Context, input *pixela.GraphUpdateInput) (*pixela.Result, error)
+	DeleteWithContext(ctx context.Context, input *pixela.GraphDeleteInput) (*pixela.Result, error)
+	GetPixelDatesWithContext(ctx context.Context, input *pixela.GraphGetPixelDatesInput) (*pixela.Pixels, error)
+	StopwatchWithContext(ctx context.Context, input *pixela.GraphStopwatchInput) (*pixela.Result, error)
+	AddWithContext(ctx context.Context, input *pixela.GraphAddInput) (*pixela.Result, error)
+	SubtractWithContext(ctx context.Context, input *pixela.GraphSubtractInput) (*pixela.Result, error)
+	GetLatestPixelWithContext(ctx context.Context, input *pixela.GraphGetLatestPixelInput) (*pixela.GraphPixel, error)
+	ClearPurgeCacheURLsWithContext(ctx context.Context, input *GraphClearPurgeCacheURLsInput) (*pixela.Result, error)
+}
+
+// Pixel is the pixel API of Pixela.
+type Pixel interface {
+	CreateWithContext(ctx context.Context, input *pixela.PixelCreateInput) (*pixela.Result, error)
+	IncrementWithContext(ctx context.Context, input *pixela.PixelIncrementInput) (*pixela.Result, error)
+	DecrementWithContext(ctx context.Context, input *pixela.PixelDecrementInput) (*pixela.Result, error)
+	GetWithContext(ctx context.Context, input *pixela.PixelGetInput) (*pixela.Quantity, error)
+	UpdateWithContext(ctx context.Context, input *pixela.PixelUpdateInput) (*pixela.Result, error)
+	DeleteWithContext(ctx context.Context, input *pixela.PixelDeleteInput) (*pixela.Result, error)
+}
+
+// Webhook is the webhook API of Pixela.
+type Webhook interface {
+	CreateWithContext(ctx context.Context, input *pixela.WebhookCreateInput) (*pixela.WebhookCreateResult, error)
+	GetAllWithContext(ctx context.Context) (*pixela.WebhookDefinitions, error)
+	InvokeWithContext(ctx context.Context, input *pixela.WebhookInvokeInput) (*pixela.Result, error)
+	DeleteWithContext(ctx context.Context, input *pixela.WebhookDeleteInput) (*pixela.Result, error)
+}
+
+// Client is the Pixela client of an account.
+// The APIs can be replaced, for example with fakes in tests.
+type Client struct {
+	User        User
+	UserProfile UserProfile
+	Graph       Graph
+	Pixel       Pixel
+	Webhook     Webhook
+
+	// Pool runs the requests of the bulk operations concurrently. They run one by one when it is nil.
+	Pool *Pool
+}
+
+// New returns a Client of the account.
+func New(username, token string) *Client {
+	return FromPixela(pixela.New(username, token))
+}
+
+// FromPixela returns a Client with the APIs of the pixela4go client.
+func FromPixela(c *pixela.Client) *Client {
+	return &Client{
+		User:        c.User(),
+		UserProfile: c.UserProfile(),
+		Graph:       &pixelaGraph{Graph: c.Graph(), username: c.UserName, token: c.Token},
+		Pixel:       c.Pixel(),
+		Webhook:     c.Webhook(),
+	}
+}
+
+// ResultError is an error that reports the API call was not successful.
+type ResultError struct {
+	Result *pixela.Result
+}
+
+func (e *ResultError) Error() string {
+	return e.Result.Message
+}
+
+func stringPtr(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func boolPtr(v bool) *bool {
+	if !v {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,282 @@
+package pa
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGraph は GetPixelDates などをメモリ上のグラフで実装する
+type fakeGraph struct {
+	Graph
+	definitions map[string]pixela.GraphDefinition
+	pixels      map[string]map[string]pixela.PixelWithBody
+	calls       int
+	created     []*pixela.GraphCreateInput
+}
+
+func newFakeClient() (*Client, *fakeGraph) {
+	g := &fakeGraph{
+		definitions: map[string]pixela.GraphDefinition{},
+		pixels:      map[string]map[string]pixela.PixelWithBody{},
+	}
+	return &Client{Graph: g, Pixel: &fakePixel{graph: g}}, g
+}
+
+func (g *fakeGraph) addGraph(def pixela.GraphDefinition, pixels ...pixela.PixelWithBody) {
+	def.IsSuccess = true
+	g.definitions[def.ID] = def
+	g.pixels[def.ID] = map[string]pixela.PixelWithBody{}
+	for _, p := range pixels {
+		g.pixels[def.ID][p.Date] = p
+	}
+}
+
+func (g *fakeGraph) CreateWithContext(ctx context.Context, input *pixela.GraphCreateInput) (*pixela.Result, error) {
+	g.created = append(g.created, input)
+	g.addGraph(pixela.GraphDefinition{ID: pixela.StringValue(input.ID), Name: pixela.StringValue(input.Name)})
+	return &pixela.Result{IsSuccess: true}, nil
+}
+
+func (g *fakeGraph) GetWithContext(ctx context.Context, input *pixela.GraphGetInput) (*pixela.GraphDefinition, error) {
+	def, ok := g.definitions[pixela.StringValue(input.ID)]
+	if !ok {
+		return &pixela.GraphDefinition{Result: pixela.Result{Message: "Specified graphID not exist.", StatusCode: http.StatusNotFound}}, nil
+	}
+	return &def, nil
+}
+
+func (g *fakeGraph) GetPixelDatesWithContext(ctx context.Context, input *pixela.GraphGetPixelDatesInput) (*pixela.Pixels, error) {
+	g.calls++
+	id := pixela.StringValue(input.ID)
+	from := pixela.StringValue(input.From)
+	to := pixela.StringValue(input.To)
+
+	result := []pixela.PixelWithBody{}
+	for _, p := range g.pixels[id] {
+		if p.Date >= from && p.Date <= to {
+			result = append(result, p)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+	return &pixela.Pixels{Pixels: result, Result: pixela.Result{IsSuccess: true}}, nil
+}
+
+type fakePixel struct {
+	Pixel
+	graph *fakeGraph
+}
+
+func (p *fakePixel) CreateWithContext(ctx context.Context, input *pixela.PixelCreateInput) (*pixela.Result, error) {
+	date := pixela.StringValue(input.Date)
+	if date == "20200102" {
+		return &pixela.Result{Message: "rejected"}, nil
+	}
+	if date == "20200104" {
+		return nil, errors.New("connection reset")
+	}
+	p.graph.pixels[pixela.StringValue(input.GraphID)][date] = pixela.PixelWithBody{Date: date, Quantity: pixela.StringValue(input.Quantity)}
+	return &pixela.Result{IsSuccess: true}, nil
+}
+
+func TestFetchPixels(t *testing.T) {
+	c, g := newFakeClient()
+	g.addGraph(
+		pixela.GraphDefinition{ID: "graph-id"},
+		pixela.PixelWithBody{Date: "20191231", Quantity: "1"},
+		pixela.PixelWithBody{Date: "20200101", Quantity: "2"},
+		pixela.PixelWithBody{Date: "20201231", Quantity: "3"},
+		pixela.PixelWithBody{Date: "20220101", Quantity: "4"},
+		pixela.PixelWithBody{Date: "20220102", Quantity: "5"},
+	)
+
+	pixels, err := c.FetchPixels(context.Background(), "graph-id", "20200101", "20220101")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []pixela.PixelWithBody{
+		{Date: "20200101", Quantity: "2"},
+		{Date: "20201231", Quantity: "3"},
+		{Date: "20220101", Quantity: "4"},
+	}, pixels)
+	assert.Equal(t, 3, g.calls)
+
+	_, err = c.FetchPixels(context.Background(), "graph-id", "2020-01-01", "")
+	assert.Error(t, err)
+}
+
+func TestFetchPixelsCanceled(t *testing.T) {
+	c, g := newFakeClient()
+	g.addGraph(pixela.GraphDefinition{ID: "graph-id"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.FetchPixels(ctx, "graph-id", "", "")
+
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 0, g.calls)
+}
+
+func TestExportGraphNotFound(t *testing.T) {
+	c, _ := newFakeClient()
+
+	_, err := c.ExportGraph(context.Background(), "unknown", "", "")
+
+	var re *ResultError
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, "Specified graphID not exist.", err.Error())
+}
+
+func TestCloneGraph(t *testing.T) {
+	src, g := newFakeClient()
+	g.addGraph(
+		pixela.GraphDefinition{ID: "src", Name: "coffee"},
+		pixela.PixelWithBody{Date: "20200101", Quantity: "1"},
+		pixela.PixelWithBody{Date: "20200102", Quantity: "2"},
+		pixela.PixelWithBody{Date: "20200103", Quantity: "3"},
+	)
+	dst, d := newFakeClient()
+	progress := []string{}
+
+	result, err := src.CloneGraph(context.Background(), dst, "src", "dst", CloneOptions{
+		WithPixels: true,
+		From:       "20200101",
+		To:         "20200131",
+		Progress: func(i, n int, date string, result *pixela.Result) {
+			progress = append(progress, date)
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &CloneResult{ID: "dst", Pixels: 3, Copied: 2, Failed: []string{"20200102"}}, result)
+	assert.Equal(t, []string{"20200101", "20200102", "20200103"}, progress)
+	assert.Equal(t, "coffee", d.definitions["dst"].Name)
+	assert.Len(t, d.pixels["dst"], 2)
+}
+
+func TestCloneGraphDefinition(t *testing.T) {
+	src, g := newFakeClient()
+	g.addGraph(pixela.GraphDefinition{
+		ID: "src", Name: "coffee", Unit: "cup", Type: "int", Color: "shibafu",
+		TimeZone: "Asia/Tokyo", SelfSufficient: "increment", IsSecret: true, PublishOptionalData: true,
+	})
+
+	for _, startOnMonday := range []bool{false, true} {
+		dst, d := newFakeClient()
+		_, err := src.CloneGraph(context.Background(), dst, "src", "dst", CloneOptions{StartOnMonday: startOnMonday})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*pixela.GraphCreateInput{{
+			ID:                  pixela.String("dst"),
+			Name:                pixela.String("coffee"),
+			Unit:                pixela.String("cup"),
+			Type:                pixela.String("int"),
+			Color:               pixela.String("shibafu"),
+			TimeZone:            pixela.String("Asia/Tokyo"),
+			SelfSufficient:      pixela.String("increment"),
+			IsSecret:            pixela.Bool(true),
+			PublishOptionalData: pixela.Bool(true),
+			StartOnMonday:       boolPtr(startOnMonday),
+		}}, d.created)
+	}
+}
+
+func TestCloneGraphCanceled(t *testing.T) {
+	src, g := newFakeClient()
+	g.addGraph(
+		pixela.GraphDefinition{ID: "src"},
+		pixela.PixelWithBody{Date: "20200101", Quantity: "1"},
+		pixela.PixelWithBody{Date: "20200103", Quantity: "3"},
+	)
+	dst, _ := newFakeClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, err := src.CloneGraph(ctx, dst, "src", "dst", CloneOptions{
+		WithPixels: true,
+		From:       "20200101",
+		To:         "20200131",
+		Progress: func(i, n int, date string, result *pixela.Result) {
+			cancel()
+		},
+	})
+
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, &CloneResult{ID: "dst", Pixels: 2, Copied: 1, Failed: []string{}}, result)
+}
+
+func TestCloneGraphError(t *testing.T) {
+	src, g := newFakeClient()
+	g.addGraph(
+		pixela.GraphDefinition{ID: "src"},
+		pixela.PixelWithBody{Date: "20200101", Quantity: "1"},
+		pixela.PixelWithBody{Date: "20200102", Quantity: "2"},
+		pixela.PixelWithBody{Date: "20200104", Quantity: "4"},
+		pixela.PixelWithBody{Date: "20200105", Quantity: "5"},
+	)
+	dst, _ := newFakeClient()
+
+	result, err := src.CloneGraph(context.Background(), dst, "src", "dst", CloneOptions{WithPixels: true, From: "20200101", To: "20200131"})
+
+	assert.EqualError(t, err, "pixel create failed: connection reset")
+	// それまでにコピーした Pixel と失敗した日付を返す
+	assert.Equal(t, &CloneResult{ID: "dst", Pixels: 4, Copied: 1, Failed: []string{"20200102", "20200104"}}, result)
+}
+
+func TestCombinePixels(t *testing.T) {
+	c, g := newFakeClient()
+	g.addGraph(
+		pixela.GraphDefinition{ID: "a"},
+		pixela.PixelWithBody{Date: "20200101", Quantity: "1.5"},
+		pixela.PixelWithBody{Date: "20200102", Quantity: "2"},
+	)
+	g.addGraph(
+		pixela.GraphDefinition{ID: "b"},
+		pixela.PixelWithBody{Date: "20200101", Quantity: "2.5"},
+	)
+
+	result, err := c.CombinePixels(context.Background(), []string{"a", "b"}, func(a, b float64) float64 { return a + b }, "20200101", "20200131")
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"20200101": "4", "20200102": "2"}, result)
+}
+
+func TestClearPurgeCacheURLs(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("X-USER-TOKEN")+" "+string(b))
+		// 最初のリクエストは拒否されて再試行される
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, `{"message":"Please retry this request.","isSuccess":false,"isRejected":true}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"message":"Success.","isSuccess":true}`)
+	}))
+	defer server.Close()
+	apiBaseURL = server.URL + "/v1"
+	t.Cleanup(func() { apiBaseURL = pixela.APIBaseURLForV1 })
+	input := &GraphClearPurgeCacheURLsInput{ID: pixela.String("coffee")}
+
+	// 拒否されたリクエストは pixela4go と同じく ErrAPICallRejected を返す
+	_, err := New("alice", "secret").Graph.ClearPurgeCacheURLsWithContext(context.Background(), input)
+	assert.True(t, errors.Is(err, pixela.ErrAPICallRejected))
+
+	// RequestPolicy で他の API と同じように再試行する
+	requests = nil
+	result, err := RequestPolicy{Retry: 1}.Graph(New("alice", "secret").Graph).ClearPurgeCacheURLsWithContext(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &pixela.Result{Message: "Success.", IsSuccess: true, StatusCode: http.StatusOK}, result)
+	assert.Equal(t, []string{
+		`PUT /v1/users/alice/graphs/coffee secret {"purgeCacheURLs":[]}`,
+		`PUT /v1/users/alice/graphs/coffee secret {"purgeCacheURLs":[]}`,
+	}, requests)
+}
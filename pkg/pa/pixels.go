@@ -0,0 +1,69 @@
+package pa
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+)
+
+// DateLayout is the layout of the dates of Pixela.
+const DateLayout = "20060102"
+
+const (
+	// 一度に取得できる期間は 1 年までなので期間を分割して取得する
+	maxPixelDatesDays = 365
+	// 期間の指定がないときはこの日付からすべての Pixel を取得する
+	defaultPixelsFrom = "20000101"
+)
+
+// FetchPixels gets all Pixels of the graph between from and to in the yyyyMMdd format.
+// It gets all Pixels up to today when from and to are empty.
+func (c *Client) FetchPixels(ctx context.Context, id, from, to string) ([]pixela.PixelWithBody, error) {
+	if from == "" {
+		from = defaultPixelsFrom
+	}
+	if to == "" {
+		to = time.Now().Format(DateLayout)
+	}
+	start, err := time.Parse(DateLayout, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date: %w", err)
+	}
+	end, err := time.Parse(DateLayout, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date: %w", err)
+	}
+
+	result := []pixela.PixelWithBody{}
+	for s := start; !s.After(end); s = s.AddDate(0, 0, maxPixelDatesDays) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		e := s.AddDate(0, 0, maxPixelDatesDays-1)
+		if e.After(end) {
+			e = end
+		}
+
+		dates, err := c.Graph.GetPixelDatesWithContext(ctx, &pixela.GraphGetPixelDatesInput{
+			ID:       pixela.String(id),
+			From:     pixela.String(s.Format(DateLayout)),
+			To:       pixela.String(e.Format(DateLayout)),
+			WithBody: pixela.Bool(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("graph get pixel dates failed: %w", err)
+		}
+		if !dates.IsSuccess {
+			return nil, &ResultError{Result: &dates.Result}
+		}
+		p, ok := dates.Pixels.([]pixela.PixelWithBody)
+		if !ok {
+			return nil, fmt.Errorf("type assertion failed: %T", dates.Pixels)
+		}
+		result = append(result, p...)
+	}
+
+	return result, nil
+}
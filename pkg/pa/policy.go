@@ -0,0 +1,210 @@
+package pa
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+)
+
+// maxRetry is the maximum number of the retries of a rejected request, which is the same as pixela4go.
+const maxRetry = 20
+
+// RequestPolicy is how the APIs of a client send their requests.
+// It is applied to each client instead of the global settings of pixela4go and net/http,
+// so the commands with the different policies can run in a process at the same time.
+type RequestPolicy struct {
+	// Limiter limits the rate of the requests including the retries when it is not nil
+	Limiter *Limiter
+	// Retry is the number of the retries when a request is rejected
+	Retry int
+}
+
+// do calls fn after waiting for the Limiter, and calls it again when the request is rejected.
+// The waits between the retries are 200ms, 400ms, 800ms and so on like pixela4go.
+func do[T any](ctx context.Context, p RequestPolicy, fn func() (T, error)) (T, error) {
+	retry := p.Retry
+	if retry > maxRetry {
+		retry = maxRetry
+	}
+	for i := 0; ; i++ {
+		if p.Limiter != nil {
+			if err := p.Limiter.Wait(ctx); err != nil {
+				var zero T
+				return zero, err
+			}
+		}
+		// pixela4go の RetryCount は 0 のままなので、拒否されたリクエストは ErrAPICallRejected で返る
+		v, err := fn()
+		if !errors.Is(err, pixela.ErrAPICallRejected) || i >= retry {
+			return v, err
+		}
+		t := time.NewTimer(time.Duration(100<<(i+1)) * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			var zero T
+			return zero, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// User returns the user API which sends the requests by the policy.
+func (p RequestPolicy) User(u User) User {
+	return &policyUser{User: u, policy: p}
+}
+
+// UserProfile returns the user profile API which sends the requests by the policy.
+func (p RequestPolicy) UserProfile(u UserProfile) UserProfile {
+	return &policyUserProfile{UserProfile: u, policy: p}
+}
+
+// Graph returns the graph API which sends the requests by the policy.
+func (p RequestPolicy) Graph(g Graph) Graph {
+	return &policyGraph{Graph: g, policy: p}
+}
+
+// Pixel returns the pixel API which sends the requests by the policy.
+func (p RequestPolicy) Pixel(px Pixel) Pixel {
+	return &policyPixel{Pixel: px, policy: p}
+}
+
+// Webhook returns the webhook API which sends the requests by the policy.
+func (p RequestPolicy) Webhook(w Webhook) Webhook {
+	return &policyWebhook{Webhook: w, policy: p}
+}
+
+type policyUser struct {
+	User
+	policy RequestPolicy
+}
+
+func (u *policyUser) CreateWithContext(ctx context.Context, input *pixela.UserCreateInput) (*pixela.Result, error) {
+	return do(ctx, u.policy, func() (*pixela.Result, error) { return u.User.CreateWithContext(ctx, input) })
+}
+
+func (u *policyUser) UpdateWithContext(ctx context.Context, input *pixela.UserUpdateInput) (*pixela.Result, error) {
+	return do(ctx, u.policy, func() (*pixela.Result, error) { return u.User.UpdateWithContext(ctx, input) })
+}
+
+func (u *policyUser) DeleteWithContext(ctx context.Context) (*pixela.Result, error) {
+	return do(ctx, u.policy, func() (*pixela.Result, error) { return u.User.DeleteWithContext(ctx) })
+}
+
+type policyUserProfile struct {
+	UserProfile
+	policy RequestPolicy
+}
+
+func (u *policyUserProfile) UpdateWithContext(ctx context.Context, input *pixela.UserProfileUpdateInput) (*pixela.Result, error) {
+	return do(ctx, u.policy, func() (*pixela.Result, error) { return u.UserProfile.UpdateWithContext(ctx, input) })
+}
+
+type policyGraph struct {
+	Graph
+	policy RequestPolicy
+}
+
+func (g *policyGraph) CreateWithContext(ctx context.Context, input *pixela.GraphCreateInput) (*pixela.Result, error) {
+	return do(ctx, g.policy, func() (*pixela.Result, error) { return g.Graph.CreateWithContext(ctx, input) })
+}
+
+func (g *policyGraph) GetAllWithContext(ctx context.Context) (*pixela.GraphDefinitions, error) {
+	return do(ctx, g.policy, func() (*pixela.GraphDefinitions, error) { return g.Graph.GetAllWithContext(ctx) })
+}
+
+func (g *policyGraph) GetWithContext(ctx context.Context, input *pixela.GraphGetInput) (*pixela.GraphDefinition, error) {
+	return do(ctx, g.policy, func() (*pixela.GraphDefinition, error) { return g.Graph.GetWithContext(ctx, input) })
+}
+
+func (g *policyGraph) GetSVGWithContext(ctx context.Context, input *pixela.GraphGetSVGInput) (string, error) {
+	return do(ctx, g.policy, func() (string, error) { return g.Graph.GetSVGWithContext(ctx, input) })
+}
+
+func (g *policyGraph) StatsWithContext(ctx context.Context, input *pixela.GraphStatsInput) (*pixela.Stats, error) {
+	return do(ctx, g.policy, func() (*pixela.Stats, error) { return g.Graph.StatsWithContext(ctx, input) })
+}
+
+func (g *policyGraph) UpdateWithContext(ctx context.Context, input *pixela.GraphUpdateInput) (*pixela.Result, error) {
+	return do(ctx, g.policy, func() (*pixela.Result, error) { return g.Graph.UpdateWithContext(ctx, input) })
+}
+
+func (g *policyGraph) DeleteWithContext(ctx context.Context, input *pixela.GraphDeleteInput) (*pixela.Result, error) {
+	return do(ctx, g.policy, func() (*pixela.Result, error) { return g.Graph.DeleteWithContext(ctx, input) })
+}
+
+func (g *policyGraph) GetPixelDatesWithContext(ctx context.Context, input *pixela.GraphGetPixelDatesInput) (*pixela.Pixels, error) {
+	return do(ctx, g.policy, func() (*pixela.Pixels, error) { return g.Graph.GetPixelDatesWithContext(ctx, input) })
+}
+
+func (g *policyGraph) StopwatchWithContext(ctx context.Context, input *pixela.GraphStopwatchInput) (*pixela.Result, error) {
+	return do(ctx, g.policy, func() (*pixela.Result, error) { return g.Graph.StopwatchWithContext(ctx, input) })
+}
+
+func (g *policyGraph) AddWithContext(ctx context.Context, input *pixela.GraphAddInput) (*pixela.Result, error) {
+	return do(ctx, g.policy, func() (*pixela.Result, error) { return g.Graph.AddWithContext(ctx, input) })
+}
+
+func (g *policyGraph) SubtractWithContext(ctx context.Context, input *pixela.GraphSubtractInput) (*pixela.Result, error) {
+	return do(ctx, g.policy, func() (*pixela.Result, error) { return g.Graph.SubtractWithContext(ctx, input) })
+}
+
+func (g *policyGraph) GetLatestPixelWithContext(ctx context.Context, input *pixela.GraphGetLatestPixelInput) (*pixela.GraphPixel, error) {
+	return do(ctx, g.policy, func() (*pixela.GraphPixel, error) { return g.Graph.GetLatestPixelWithContext(ctx, input) })
+}
+
+func (g *policyGraph) ClearPurgeCacheURLsWithContext(ctx context.Context, input *GraphClearPurgeCacheURLsInput) (*pixela.Result, error) {
+	return do(ctx, g.policy, func() (*pixela.Result, error) { return g.Graph.ClearPurgeCacheURLsWithContext(ctx, input) })
+}
+
+type policyPixel struct {
+	Pixel
+	policy RequestPolicy
+}
+
+func (p *policyPixel) CreateWithContext(ctx context.Context, input *pixela.PixelCreateInput) (*pixela.Result, error) {
+	return do(ctx, p.policy, func() (*pixela.Result, error) { return p.Pixel.CreateWithContext(ctx, input) })
+}
+
+func (p *policyPixel) IncrementWithContext(ctx context.Context, input *pixela.PixelIncrementInput) (*pixela.Result, error) {
+	return do(ctx, p.policy, func() (*pixela.Result, error) { return p.Pixel.IncrementWithContext(ctx, input) })
+}
+
+func (p *policyPixel) DecrementWithContext(ctx context.Context, input *pixela.PixelDecrementInput) (*pixela.Result, error) {
+	return do(ctx, p.policy, func() (*pixela.Result, error) { return p.Pixel.DecrementWithContext(ctx, input) })
+}
+
+func (p *policyPixel) GetWithContext(ctx context.Context, input *pixela.PixelGetInput) (*pixela.Quantity, error) {
+	return do(ctx, p.policy, func() (*pixela.Quantity, error) { return p.Pixel.GetWithContext(ctx, input) })
+}
+
+func (p *policyPixel) UpdateWithContext(ctx context.Context, input *pixela.PixelUpdateInput) (*pixela.Result, error) {
+	return do(ctx, p.policy, func() (*pixela.Result, error) { return p.Pixel.UpdateWithContext(ctx, input) })
+}
+
+func (p *policyPixel) DeleteWithContext(ctx context.Context, input *pixela.PixelDeleteInput) (*pixela.Result, error) {
+	return do(ctx, p.policy, func() (*pixela.Result, error) { return p.Pixel.DeleteWithContext(ctx, input) })
+}
+
+type policyWebhook struct {
+	Webhook
+	policy RequestPolicy
+}
+
+func (w *policyWebhook) CreateWithContext(ctx context.Context, input *pixela.WebhookCreateInput) (*pixela.WebhookCreateResult, error) {
+	return do(ctx, w.policy, func() (*pixela.WebhookCreateResult, error) { return w.Webhook.CreateWithContext(ctx, input) })
+}
+
+func (w *policyWebhook) GetAllWithContext(ctx context.Context) (*pixela.WebhookDefinitions, error) {
+	return do(ctx, w.policy, func() (*pixela.WebhookDefinitions, error) { return w.Webhook.GetAllWithContext(ctx) })
+}
+
+func (w *policyWebhook) InvokeWithContext(ctx context.Context, input *pixela.WebhookInvokeInput) (*pixela.Result, error) {
+	return do(ctx, w.policy, func() (*pixela.Result, error) { return w.Webhook.InvokeWithContext(ctx, input) })
+}
+
+func (w *policyWebhook) DeleteWithContext(ctx context.Context, input *pixela.WebhookDeleteInput) (*pixela.Result, error) {
+	return do(ctx, w.policy, func() (*pixela.Result, error) { return w.Webhook.DeleteWithContext(ctx, input) })
+}
@@ -0,0 +1,150 @@
+package pa
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errNotStarted is the error of the calls which Pool.Do did not start.
+var errNotStarted = errors.New("not started")
+
+// Pool limits the number of the requests of the bulk operations which run at the same time.
+// A Pool is shared by the operations, so the limit applies to all of them together.
+// The calls of Pool.Do must not be nested, because the outer call holds the slots which the inner one waits for.
+type Pool struct {
+	slots chan struct{}
+}
+
+// NewPool returns a Pool which runs up to concurrency requests at the same time.
+func NewPool(concurrency int) *Pool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Pool{slots: make(chan struct{}, concurrency)}
+}
+
+// Do calls fn with the indexes from 0 to n-1 on the pool, and calls report with each index and the error of fn
+// in the order of the indexes, so the results are reported in order while the requests run concurrently.
+// It stops starting the calls when fn returns an error or ctx is done, and waits for the running calls.
+// report is called only for the started calls. Do returns ctx.Err() when ctx is done, or the first error of fn.
+// A nil Pool calls fn one by one.
+func (p *Pool) Do(ctx context.Context, n int, fn func(ctx context.Context, i int) error, report func(i int, err error)) error {
+	if p == nil {
+		for i := 0; i < n; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			err := fn(ctx, i)
+			report(i, err)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, n)
+	finished := make([]chan struct{}, n)
+	for i := range finished {
+		finished[i] = make(chan struct{})
+	}
+	// dispatch は新しい呼び出しを始めるかどうかで、実行中の呼び出しはキャンセルしない
+	dispatch, stop := context.WithCancel(ctx)
+	defer stop()
+
+	go func() {
+		for i := 0; i < n; i++ {
+			if !p.acquire(dispatch) {
+				for j := i; j < n; j++ {
+					errs[j] = errNotStarted
+					close(finished[j])
+				}
+				return
+			}
+			go func(i int) {
+				defer close(finished[i])
+				defer p.release()
+				if errs[i] = fn(ctx, i); errs[i] != nil {
+					stop()
+				}
+			}(i)
+		}
+	}()
+
+	var first error
+	for i := 0; i < n; i++ {
+		<-finished[i]
+		if errs[i] == errNotStarted {
+			continue
+		}
+		report(i, errs[i])
+		if errs[i] != nil && first == nil {
+			first = errs[i]
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return first
+}
+
+func (p *Pool) acquire(ctx context.Context) bool {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	if ctx.Err() != nil {
+		p.release()
+		return false
+	}
+	return true
+}
+
+func (p *Pool) release() {
+	<-p.slots
+}
+
+// Limiter spaces the requests evenly so that they don't exceed the rate.
+type Limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewLimiter returns a Limiter which allows n requests per the duration.
+func NewLimiter(n int, per time.Duration) *Limiter {
+	return &Limiter{interval: per / time.Duration(n)}
+}
+
+// Wait blocks until the next request is allowed, or returns ctx.Err() when ctx is done before it.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+package pa
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pixela "github.com/ebc-2in2crc/pixela4go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolDo(t *testing.T) {
+	for _, p := range []*Pool{nil, NewPool(1), NewPool(4)} {
+		var running, peak int32
+		var reported []int
+
+		err := p.Do(context.Background(), 20, func(ctx context.Context, i int) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&peak)
+				if n <= m || atomic.CompareAndSwapInt32(&peak, m, n) {
+					break
+				}
+			}
+			// 後の呼び出しほど早く終わっても順番に報告される
+			time.Sleep(time.Duration(20-i) * time.Millisecond / 10)
+			return nil
+		}, func(i int, err error) {
+			assert.NoError(t, err)
+			reported = append(reported, i)
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, reported, 20)
+		for i, r := range reported {
+			assert.Equal(t, i, r)
+		}
+		max := int32(1)
+		if p != nil {
+			max = int32(cap(p.slots))
+		}
+		assert.LessOrEqual(t, peak, max)
+	}
+}
+
+func TestPoolDoError(t *testing.T) {
+	failure := errors.New("failure")
+	p := NewPool(2)
+	var mu sync.Mutex
+	started := 0
+
+	err := p.Do(context.Background(), 10, func(ctx context.Context, i int) error {
+		mu.Lock()
+		started++
+		mu.Unlock()
+		if i == 1 {
+			return failure
+		}
+		return nil
+	}, func(i int, err error) {})
+
+	assert.Equal(t, failure, err)
+	assert.Less(t, started, 10)
+	// プールのスロットはすべて返されている
+	assert.Len(t, p.slots, 0)
+}
+
+func TestPoolDoCanceled(t *testing.T) {
+	for _, p := range []*Pool{nil, NewPool(3)} {
+		ctx, cancel := context.WithCancel(context.Background())
+		var reported []int
+
+		err := p.Do(ctx, 10, func(ctx context.Context, i int) error {
+			if i == 2 {
+				cancel()
+				return ctx.Err()
+			}
+			return nil
+		}, func(i int, err error) {
+			reported = append(reported, i)
+		})
+
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, []int{0, 1, 2}, reported[:3])
+		assert.Less(t, len(reported), 10)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(10, time.Second)
+	start := time.Now()
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, l.Wait(context.Background()))
+	}
+
+	// 最初のリクエストはすぐに許可され、後のリクエストは 100ms ずつ間隔を空ける
+	assert.True(t, time.Since(start) >= 300*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.True(t, errors.Is(l.Wait(ctx), context.Canceled))
+}
+
+// rejectingGraph rejects the first requests like Pixela does for the users who aren't supporters.
+type rejectingGraph struct {
+	Graph
+	rejects int
+	calls   int
+}
+
+func (g *rejectingGraph) GetWithContext(ctx context.Context, input *pixela.GraphGetInput) (*pixela.GraphDefinition, error) {
+	g.calls++
+	if g.calls <= g.rejects {
+		return nil, pixela.ErrAPICallRejected
+	}
+	return &pixela.GraphDefinition{ID: pixela.StringValue(input.ID)}, nil
+}
+
+func TestRequestPolicyLimiter(t *testing.T) {
+	graph := &rejectingGraph{}
+	send := func(g Graph) time.Duration {
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := g.GetWithContext(context.Background(), &pixela.GraphGetInput{ID: pixela.String("graph-id")})
+			assert.NoError(t, err)
+		}
+		return time.Since(start)
+	}
+
+	assert.True(t, send(RequestPolicy{Limiter: NewLimiter(5, time.Second)}.Graph(graph)) >= 400*time.Millisecond)
+	// リミッターの無いクライアントのリクエストは待たない
+	assert.True(t, send(RequestPolicy{}.Graph(graph)) < 200*time.Millisecond)
+}
+
+func TestRequestPolicyRetry(t *testing.T) {
+	tests := []struct {
+		retry   int
+		rejects int
+		calls   int
+		wantErr bool
+	}{
+		{retry: 0, rejects: 1, calls: 1, wantErr: true},
+		{retry: 2, rejects: 2, calls: 3},
+		{retry: 2, rejects: 3, calls: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		graph := &rejectingGraph{rejects: tt.rejects}
+		def, err := RequestPolicy{Retry: tt.retry}.Graph(graph).GetWithContext(context.Background(), &pixela.GraphGetInput{ID: pixela.String("graph-id")})
+		assert.Equal(t, tt.calls, graph.calls)
+		if tt.wantErr {
+			assert.True(t, errors.Is(err, pixela.ErrAPICallRejected))
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, "graph-id", def.ID)
+	}
+
+	// リトライを待つ間にキャンセルされたら、それ以上リトライしない
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	graph := &rejectingGraph{rejects: 1}
+	_, err := RequestPolicy{Retry: 1}.Graph(graph).GetWithContext(ctx, &pixela.GraphGetInput{ID: pixela.String("graph-id")})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 1, graph.calls)
+}
@@ -0,0 +1,45 @@
+package pa
+
+import (
+	"context"
+	"fmt"
+)
+
+// WebhookKey identifies a webhook by its graph and type.
+type WebhookKey struct {
+	GraphID string
+	Type    string
+}
+
+// FindWebhookHash returns the hash of the webhook of the graph and the type.
+// It returns "" when there is no such webhook.
+func (c *Client) FindWebhookHash(ctx context.Context, graphID, typ string) (string, error) {
+	hashes, err := c.WebhookHashes(ctx)
+	if err != nil {
+		return "", err
+	}
+	return hashes[WebhookKey{GraphID: graphID, Type: typ}], nil
+}
+
+// WebhookHashes returns the hashes of all the webhooks by their graphs and types.
+func (c *Client) WebhookHashes(ctx context.Context) (map[WebhookKey]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	whs, err := c.Webhook.GetAllWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("webhook get all failed: %w", err)
+	}
+	if !whs.IsSuccess {
+		return nil, &ResultError{Result: &whs.Result}
+	}
+	hashes := map[WebhookKey]string{}
+	for _, wh := range whs.Webhooks {
+		key := WebhookKey{GraphID: wh.GraphID, Type: wh.Type}
+		// 同じグラフと種類の webhook が複数あるときは最初のものを使う
+		if _, ok := hashes[key]; !ok {
+			hashes[key] = wh.WebhookHash
+		}
+	}
+	return hashes, nil
+}